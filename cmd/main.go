@@ -23,6 +23,7 @@ import (
 	"github.com/tuannvm/slack-mcp-client/internal/config"
 	"github.com/tuannvm/slack-mcp-client/internal/mcp"
 	"github.com/tuannvm/slack-mcp-client/internal/monitoring"
+	"github.com/tuannvm/slack-mcp-client/internal/prompt"
 	"github.com/tuannvm/slack-mcp-client/internal/rag"
 
 	slackbot "github.com/tuannvm/slack-mcp-client/internal/slack"
@@ -41,6 +42,10 @@ var (
 	// Configuration migration flag
 	migrateConfig = flag.Bool("migrate-config", false, "Migrate legacy configuration to new format and exit")
 
+	// Local terminal client flags
+	stdioScript = flag.String("stdio-script", "", "Replay a transcript file through the local terminal client and exit (implies useStdIOClient)")
+	stdioOutput = flag.String("stdio-output", "", "File to write the replayed transcript with bot replies to (default: stdout)")
+
 	// RAG-related flags
 	ragIngest          = flag.String("rag-ingest", "", "Ingest PDF files from directory and exit")
 	ragSearch          = flag.String("rag-search", "", "Search RAG database and exit")
@@ -64,7 +69,16 @@ func main() {
 	// Validate configuration and exit if requested
 	if *configValidate {
 		// Load and validate config (runtime validation and strict JSON parsing)
-		if _, err := config.LoadConfig(*configFile, nil); err != nil {
+		cfg, err := config.LoadConfig(*configFile, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration validation failed: %v\n", err)
+			os.Exit(1)
+		}
+		if err := slackbot.ValidateMessages(cfg.Messages); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration validation failed: %v\n", err)
+			os.Exit(1)
+		}
+		if err := prompt.Validate(cfg.LLM.CustomPrompt, cfg.LLM.CustomPromptFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration validation failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -631,8 +645,28 @@ func startSlackClient(ctx context.Context, logger *logging.Logger, mcpClients ma
 
 	var userFrontend slackbot.UserFrontend
 	// Use the structured logger for the Slack client
-	if cfg.UseStdIOClient {
-		userFrontend = slackbot.NewStdioClient(logger)
+	if cfg.UseStdIOClient || *stdioScript != "" {
+		stdioClient := slackbot.NewStdioClient(logger, slackbot.StdioOptions{
+			ThinkingMessage: cfg.Slack.ThinkingMessage,
+			Script:          *stdioScript != "",
+		})
+		if *stdioScript != "" {
+			script, err := os.Open(*stdioScript)
+			if err != nil {
+				logger.Fatal("Failed to open transcript file: %v", err)
+			}
+			defer func() { _ = script.Close() }()
+			stdioClient.Input = script
+		}
+		if *stdioOutput != "" {
+			output, err := os.Create(*stdioOutput)
+			if err != nil {
+				logger.Fatal("Failed to create transcript output file: %v", err)
+			}
+			defer func() { _ = output.Close() }()
+			stdioClient.Output = output
+		}
+		userFrontend = stdioClient
 	} else {
 		userFrontend, err = slackbot.GetSlackClient(
 			cfg.Slack.BotToken,
@@ -0,0 +1,54 @@
+// Package azure provides authentication shared by the Azure OpenAI chat and embedding clients
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// DefaultAPIVersion is the Azure OpenAI API version used when none is configured
+const DefaultAPIVersion = "2024-10-21"
+
+// CognitiveServicesScope is the token scope for Azure OpenAI
+const CognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+// tokenTransport adds a fresh Entra ID bearer token to each request
+type tokenTransport struct {
+	credential azcore.TokenCredential
+	base       http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The credential caches tokens and refreshes them before they expire
+	token, err := t.credential.GetToken(req.Context(), policy.TokenRequestOptions{Scopes: []string{CognitiveServicesScope}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Entra ID token for Azure OpenAI: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del("api-key")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	return t.base.RoundTrip(req)
+}
+
+// NewTokenClient returns an HTTP client that authenticates with the given credential.
+// A nil credential uses the default Azure credential chain: environment, workload identity,
+// managed identity and the Azure CLI.
+func NewTokenClient(credential azcore.TokenCredential, timeout time.Duration) (*http.Client, error) {
+	if credential == nil {
+		var err error
+		credential, err = azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &tokenTransport{credential: credential, base: http.DefaultTransport},
+	}, nil
+}
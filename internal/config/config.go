@@ -3,7 +3,9 @@ package config
 
 import (
 	"os"
+	"sort"
 	"strconv"
+	"time"
 )
 
 // Constants for provider types
@@ -11,6 +13,33 @@ const (
 	ProviderOpenAI    = "openai"
 	ProviderOllama    = "ollama"
 	ProviderAnthropic = "anthropic"
+	ProviderBedrock   = "bedrock"
+	ProviderAzure     = "azure-openai"
+)
+
+// ProviderTypes lists the supported LLM provider types
+var ProviderTypes = []string{ProviderOpenAI, ProviderOllama, ProviderAnthropic, ProviderBedrock, ProviderAzure}
+
+// IsProviderType reports whether name is a supported LLM provider type
+func IsProviderType(name string) bool {
+	for _, providerType := range ProviderTypes {
+		if name == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+// Authentication types for Azure OpenAI providers
+const (
+	AzureAuthAPIKey  = "apiKey"
+	AzureAuthEntraID = "entraId"
+)
+
+// Agent modes: the text ReAct agent or the provider's native tool calling
+const (
+	AgentModeReAct  = "react"
+	AgentModeNative = "native"
 )
 
 // Observability Providers
@@ -28,37 +57,248 @@ type Config struct {
 	MCPServers                 map[string]MCPServerConfig `json:"mcpServers"`
 	QueryEnhancementProvider   string                     `json:"queryEnhancementProvider,omitempty"`   // Optional: LLM provider for query enhancement (applies to all queries)
 	QueryEnhancementPromptFile string                     `json:"queryEnhancementPromptFile,omitempty"` // Optional: Path to custom query enhancement prompt file
+	QueryEnhancementSchemaFile string                     `json:"queryEnhancementSchemaFile,omitempty"` // Optional: Path to the JSON Schema query enhancement results must conform to
 	RAG                        RAGConfig                  `json:"rag,omitempty"`
 	Monitoring                 MonitoringConfig           `json:"monitoring,omitempty"`
 	Timeouts                   TimeoutConfig              `json:"timeouts,omitempty"`
 	Retry                      RetryConfig                `json:"retry,omitempty"`
 	Reload                     ReloadConfig               `json:"reload,omitempty"`
 	Observability              ObservabilityConfig        `json:"observability,omitempty"`
+	Messages                   MessagesConfig             `json:"messages,omitempty"`
+	Redaction                  RedactionConfig            `json:"redaction,omitempty"`
+	Quotas                     QuotaConfig                `json:"quotas,omitempty"`
 	UseStdIOClient             bool                       `json:"useStdIOClient,omitempty"` // Use terminal client instead of a real slack bot, for local development
 }
 
 // SlackConfig contains Slack-specific configuration
 type SlackConfig struct {
-	BotToken        string `json:"botToken"`
-	AppToken        string `json:"appToken"`
-	MessageHistory  int    `json:"messageHistory,omitempty"`  // Max messages to keep in history per channel (default: 50)
-	ThinkingMessage string `json:"thinkingMessage,omitempty"` // Custom "thinking" message (default: "Thinking...")
+	BotToken        string               `json:"botToken"`
+	AppToken        string               `json:"appToken"`
+	MessageHistory  int                  `json:"messageHistory,omitempty"`  // Max messages to keep in history per channel (default: 50)
+	ThinkingMessage string               `json:"thinkingMessage,omitempty"` // Custom "thinking" message (default: "Thinking...")
+	AdminUsers      []string             `json:"adminUsers,omitempty"`      // Slack user IDs allowed to see raw error details
+	Permalinks      PermalinkConfig      `json:"permalinks,omitempty"`      // Expansion of links to Slack messages in prompts
+	ChannelHistory  ChannelHistoryConfig `json:"channelHistory,omitempty"`  // Recent channel messages for top-level mentions
+	AgentTrace      AgentTraceConfig     `json:"agentTrace,omitempty"`      // Tool steps shown while the agent runs
+}
+
+// Agent trace verbosity: off hides the agent's tool steps, summary lists them with their durations,
+// detailed adds arguments and errors
+const (
+	AgentTraceOff      = "off"
+	AgentTraceSummary  = "summary"
+	AgentTraceDetailed = "detailed"
+)
+
+// AgentTraceConfig controls the message that shows the agent's tool steps as it runs
+type AgentTraceConfig struct {
+	Verbosity string            `json:"verbosity,omitempty"` // off, summary or detailed (default: summary)
+	Channels  map[string]string `json:"channels,omitempty"`  // Verbosity by channel ID, overriding verbosity
+}
+
+// ChannelVerbosity returns the trace verbosity of a channel
+func (c AgentTraceConfig) ChannelVerbosity(channelID string) string {
+	if verbosity, ok := c.Channels[channelID]; ok {
+		return verbosity
+	}
+	return c.Verbosity
+}
+
+// ChannelHistoryConfig adds recent channel messages to the context of top-level mentions, so questions
+// like "summarize what happened here today" can be answered. Channels must opt in individually.
+type ChannelHistoryConfig struct {
+	MaxMessages int                             `json:"maxMessages,omitempty"` // Most recent messages to include (default: 50)
+	MaxAgeHours int                             `json:"maxAgeHours,omitempty"` // Only include messages from the last M hours (default: 24)
+	Channels    map[string]ChannelHistoryLimits `json:"channels,omitempty"`    // Opted-in channels by ID, with optional overrides
+}
+
+// ChannelHistoryLimits overrides the channel history limits for one channel; zero values use the defaults
+type ChannelHistoryLimits struct {
+	MaxMessages int `json:"maxMessages,omitempty"`
+	MaxAgeHours int `json:"maxAgeHours,omitempty"`
+}
+
+// PermalinkConfig controls how links to other Slack messages in a prompt are expanded into quoted context
+type PermalinkConfig struct {
+	Disabled          bool `json:"disabled,omitempty"`          // Don't expand message links (default: false)
+	IncludeThread     bool `json:"includeThread,omitempty"`     // Also include the other messages of the linked thread (default: false)
+	MaxLinks          int  `json:"maxLinks,omitempty"`          // Links expanded per prompt (default: 3)
+	MaxThreadMessages int  `json:"maxThreadMessages,omitempty"` // Thread messages included per link (default: 20)
+}
+
+// MessagesConfig customizes the bot's user-facing messages.
+// Templates use Go text/template syntax; see docs/configuration.md for message IDs and fields.
+type MessagesConfig struct {
+	DefaultLocale string                       `json:"defaultLocale,omitempty"` // Locale used when the user's locale has no templates (default: "en-US")
+	Locales       map[string]map[string]string `json:"locales,omitempty"`       // Templates by locale ("ja-JP" or "ja"), then by message ID
 }
 
 // LLMConfig contains LLM provider configuration
 type LLMConfig struct {
-	Provider           string                       `json:"provider"`
-	UseNativeTools     bool                         `json:"useNativeTools,omitempty"`
-	UseAgent           bool                         `json:"useAgent,omitempty"`
-	CustomPrompt       string                       `json:"customPrompt,omitempty"`
-	CustomPromptFile   string                       `json:"customPromptFile,omitempty"`
-	ReplaceToolPrompt  bool                         `json:"replaceToolPrompt,omitempty"`
-	MaxAgentIterations int                          `json:"maxAgentIterations,omitempty"` // Maximum agent iterations (default: 20)
-	Providers          map[string]LLMProviderConfig `json:"providers"`
+	Provider            string                       `json:"provider"`
+	Fallbacks           []string                     `json:"fallbacks,omitempty"`        // Providers tried in order when the provider fails
+	ChannelProviders    map[string][]string          `json:"channelProviders,omitempty"` // Provider chain per channel ID, overriding provider and fallbacks
+	Failover            FailoverConfig               `json:"failover,omitempty"`         // Circuit breaker and timeout settings for fallbacks
+	Routing             RoutingConfig                `json:"routing,omitempty"`          // Route requests to provider and model tiers
+	UseNativeTools      bool                         `json:"useNativeTools,omitempty"`
+	UseAgent            bool                         `json:"useAgent,omitempty"`
+	AgentMode           string                       `json:"agentMode,omitempty"`           // Agent implementation: react or native (default: react)
+	CustomPrompt        string                       `json:"customPrompt,omitempty"`        // System prompt, a Go text/template
+	CustomPromptFile    string                       `json:"customPromptFile,omitempty"`    // File holding the system prompt template, reloaded on changes
+	PromptExtras        map[string]string            `json:"promptExtras,omitempty"`        // Values available to the prompt as {{.Extras.name}}
+	ChannelPromptExtras map[string]map[string]string `json:"channelPromptExtras,omitempty"` // Extras by channel ID, overriding promptExtras
+	ReplaceToolPrompt   bool                         `json:"replaceToolPrompt,omitempty"`
+	MaxAgentIterations  int                          `json:"maxAgentIterations,omitempty"` // Maximum agent iterations (default: 20)
+	ToolLoop            ToolLoopConfig               `json:"toolLoop,omitempty"`           // Limits for tool calling outside agent mode
+	ContextBudget       ContextBudgetConfig          `json:"contextBudget,omitempty"`      // Token budgeting for the assembled prompt
+	Cache               ResponseCacheConfig          `json:"cache,omitempty"`              // Reuse answers to repeated questions
+	Providers           map[string]LLMProviderConfig `json:"providers"`
+}
+
+// maxToolLoopIterations caps ToolLoopConfig.MaxIterations; larger values are lowered to it
+const maxToolLoopIterations = 50
+
+// ToolLoopConfig limits how long the model may keep calling tools before it must answer
+type ToolLoopConfig struct {
+	MaxIterations int `json:"maxIterations,omitempty"` // Maximum rounds of tool calls per question (default: 5, at most 50)
+	MaxTokens     int `json:"maxTokens,omitempty"`     // Total LLM tokens the rounds may use; 0 means no limit (default: 0)
+}
+
+// ResponseCacheConfig caches answers to repeated questions. Turns that use tools are never cached.
+type ResponseCacheConfig struct {
+	Enabled           bool                `json:"enabled,omitempty"`
+	TTLSeconds        int                 `json:"ttlSeconds,omitempty"`        // How long answers are reused (default: 3600)
+	ChannelTTLSeconds map[string]int      `json:"channelTTLSeconds,omitempty"` // TTL per channel ID; 0 disables caching in the channel
+	MaxEntries        int                 `json:"maxEntries,omitempty"`        // Oldest entries are evicted beyond this (default: 1000)
+	Semantic          SemanticCacheConfig `json:"semantic,omitempty"`          // Match reworded questions by embedding similarity
+}
+
+// SemanticCacheConfig matches questions by the similarity of their embeddings from rag.embeddingProvider
+type SemanticCacheConfig struct {
+	Enabled   bool    `json:"enabled,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"` // Minimum cosine similarity of a match (default: 0.95)
+}
+
+// ChannelTTL returns how long answers are cached in a channel; zero disables caching there
+func (c ResponseCacheConfig) ChannelTTL(channelID string) time.Duration {
+	if ttl, ok := c.ChannelTTLSeconds[channelID]; ok {
+		return time.Duration(ttl) * time.Second
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// FailoverConfig controls when a provider is skipped in favour of the next one in its chain
+type FailoverConfig struct {
+	FailureThreshold      int `json:"failureThreshold,omitempty"`      // Consecutive retryable failures that open a provider's circuit (default: 3)
+	CooldownSeconds       int `json:"cooldownSeconds,omitempty"`       // How long an open circuit skips the provider (default: 60)
+	AttemptTimeoutSeconds int `json:"attemptTimeoutSeconds,omitempty"` // Timeout of a call when another provider follows in the chain, except agent runs (default: 60)
+}
+
+// RoutingConfig sends each request to a provider and model tier chosen by rules or a classifier model
+type RoutingConfig struct {
+	Enabled     bool                   `json:"enabled,omitempty"`
+	DefaultTier string                 `json:"defaultTier,omitempty"` // Tier when nothing matches (default: the provider with its model)
+	Tiers       map[string]RoutingTier `json:"tiers,omitempty"`
+	Rules       []RoutingRule          `json:"rules,omitempty"`      // Evaluated in order; the first match wins
+	Classifier  RoutingClassifier      `json:"classifier,omitempty"` // Model asked to pick a tier when no rule matches (optional)
+}
+
+// RoutingTier is a provider and model requests can be routed to
+type RoutingTier struct {
+	Provider    string `json:"provider"`
+	Model       string `json:"model,omitempty"`       // Overrides the provider's model, also in agent mode; fallbacks keep their own
+	Description string `json:"description,omitempty"` // What the tier is for, shown to the classifier
+}
+
+// RoutingRule matches requests to a tier. All conditions that are set must hold.
+type RoutingRule struct {
+	Tier           string   `json:"tier"`
+	MinPromptChars int      `json:"minPromptChars,omitempty"`
+	MaxPromptChars int      `json:"maxPromptChars,omitempty"`
+	Attachments    *bool    `json:"attachments,omitempty"` // Whether the prompt includes linked or shared messages
+	Tools          *bool    `json:"tools,omitempty"`       // Whether the prompt mentions an available tool
+	Keywords       []string `json:"keywords,omitempty"`    // Matches when the prompt contains any of them, ignoring case
+}
+
+// RoutingClassifier asks a cheap model which tier fits a request
+type RoutingClassifier struct {
+	Provider       string `json:"provider,omitempty"` // Enables the classifier
+	Model          string `json:"model,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"` // default: 10
+}
+
+// ProviderChain returns the providers to try for a channel, in order and without duplicates
+func (c *LLMConfig) ProviderChain(channelID string) []string {
+	chain := c.ChannelProviders[channelID]
+	if len(chain) == 0 {
+		chain = append([]string{c.Provider}, c.Fallbacks...)
+	}
+	seen := make(map[string]bool, len(chain))
+	result := make([]string, 0, len(chain))
+	for _, name := range chain {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// ProviderType returns the type of a provider instance. Entries from before provider types
+// existed are named after their type, so an unset type falls back to the name.
+func (c *LLMConfig) ProviderType(name string) string {
+	if providerType := c.Providers[name].Type; providerType != "" {
+		return providerType
+	}
+	return name
+}
+
+// ChannelExtras returns the prompt extras of a channel: the global extras with the channel's overrides
+func (c *LLMConfig) ChannelExtras(channelID string) map[string]string {
+	extras := make(map[string]string, len(c.PromptExtras)+len(c.ChannelPromptExtras[channelID]))
+	for name, value := range c.PromptExtras {
+		extras[name] = value
+	}
+	for name, value := range c.ChannelPromptExtras[channelID] {
+		extras[name] = value
+	}
+	return extras
+}
+
+// ProviderAgentMode returns the agent mode of a provider instance
+func (c *LLMConfig) ProviderAgentMode(name string) string {
+	if mode := c.Providers[name].AgentMode; mode != "" {
+		return mode
+	}
+	return c.AgentMode
+}
+
+// migrateProviderTypes sets the type of untyped entries named after a provider type, as
+// in configs written before provider instances were decoupled from their types.
+// It returns the names of the migrated entries.
+func (c *LLMConfig) migrateProviderTypes() []string {
+	var migrated []string
+	for name, provider := range c.Providers {
+		if provider.Type == "" && IsProviderType(name) {
+			provider.Type = name
+			c.Providers[name] = provider
+			migrated = append(migrated, name)
+		}
+	}
+	sort.Strings(migrated)
+	return migrated
+}
+
+// ContextBudgetConfig controls how prompts are fitted into the model's context window
+type ContextBudgetConfig struct {
+	Disabled             bool `json:"disabled,omitempty"`             // Disable token budgeting entirely (default: false)
+	MaxInputTokens       int  `json:"maxInputTokens,omitempty"`       // Upper bound on prompt tokens (default: context window minus reserved output)
+	ReservedOutputTokens int  `json:"reservedOutputTokens,omitempty"` // Tokens reserved for the response when maxTokens is unset (default: 1024)
 }
 
 // LLMProviderConfig contains provider-specific settings
 type LLMProviderConfig struct {
+	Type                      string  `json:"type,omitempty"` // Provider type: openai, anthropic, ollama, bedrock or azure-openai (default: the entry's name)
 	Model                     string  `json:"model"`
 	APIKey                    string  `json:"apiKey,omitempty"`
 	BaseURL                   string  `json:"baseUrl,omitempty"`
@@ -66,6 +306,16 @@ type LLMProviderConfig struct {
 	MaxTokens                 int     `json:"maxTokens,omitempty"`
 	ThinkingMode              string  `json:"thinkingMode,omitempty"`              // Thinking mode: none, low, medium, high, auto (default: auto)
 	IncludeThinkingInResponse bool    `json:"includeThinkingInResponse,omitempty"` // Include thinking content in response (default: false)
+	ContextWindow             int     `json:"contextWindow,omitempty"`             // Model context window in tokens (default: inferred from model name)
+	Region                    string  `json:"region,omitempty"`                    // AWS region (Bedrock; default: from the AWS environment)
+	Profile                   string  `json:"profile,omitempty"`                   // AWS shared config profile (Bedrock)
+	RoleARN                   string  `json:"roleArn,omitempty"`                   // IAM role to assume for the calls (Bedrock)
+	APIVersion                string  `json:"apiVersion,omitempty"`                // API version (Azure OpenAI; default: 2024-10-21)
+	AuthType                  string  `json:"authType,omitempty"`                  // apiKey or entraId (Azure OpenAI; default: apiKey)
+	DisablePromptCaching      bool    `json:"disablePromptCaching,omitempty"`      // Do not mark stable prompt prefixes as cacheable (Anthropic)
+	AgentMode                 string  `json:"agentMode,omitempty"`                 // Agent implementation for this provider, overriding llm.agentMode
+	// Deployments maps model names to deployment names (Azure OpenAI); unmapped models use the model name
+	Deployments map[string]string `json:"deployments,omitempty"`
 }
 
 // MCPServerConfig contains MCP server configuration
@@ -143,7 +393,11 @@ type RAGProviderConfig struct {
 
 // RAGEmbeddingProviderConfig contains embedding provider-specific settings
 type RAGEmbeddingProviderConfig struct {
-	APIKey string `json:"apiKey,omitempty"` // API key for the embedding provider
+	APIKey     string `json:"apiKey,omitempty"`     // API key for the embedding provider
+	BaseURL    string `json:"baseUrl,omitempty"`    // Azure OpenAI: resource endpoint
+	Deployment string `json:"deployment,omitempty"` // Azure OpenAI: embedding deployment name
+	APIVersion string `json:"apiVersion,omitempty"` // Azure OpenAI: API version (default: 2024-10-21)
+	AuthType   string `json:"authType,omitempty"`   // Azure OpenAI: apiKey or entraId (default: apiKey)
 }
 
 // MonitoringConfig contains monitoring and observability settings
@@ -163,6 +417,25 @@ type TimeoutConfig struct {
 	ResponseProcessing     string `json:"responseProcessing,omitempty"`     // Slack response processing (default: "1m")
 }
 
+// BridgeOperation returns how long one LLM call through the bridge may take, including failover and,
+// in agent mode, the whole agent run (default: 3m)
+func (c TimeoutConfig) BridgeOperation() time.Duration {
+	return parseTimeout(c.BridgeOperationTimeout, 3*time.Minute)
+}
+
+// ToolProcessing returns how long one tool call may take (default: 3m)
+func (c TimeoutConfig) ToolProcessing() time.Duration {
+	return parseTimeout(c.ToolProcessingTimeout, 3*time.Minute)
+}
+
+// parseTimeout parses a duration such as "90s", using fallback when it is empty or invalid
+func parseTimeout(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
 // RetryConfig contains retry and resilience settings
 type RetryConfig struct {
 	MaxAttempts          int    `json:"maxAttempts,omitempty"`          // Max retry attempts (default: 3)
@@ -199,6 +472,8 @@ func (c *Config) ApplyDefaults() {
 	c.applyMonitoringDefaults()
 	c.applyMCPDefaults()
 	c.applyObservabilityDefaults()
+	c.applyMessagesDefaults()
+	c.applyQuotaDefaults()
 }
 
 // applyVersionDefaults sets default version if not specified
@@ -218,6 +493,48 @@ func (c *Config) applyLLMDefaults() {
 		c.LLM.MaxAgentIterations = 20
 	}
 
+	if c.LLM.AgentMode == "" {
+		c.LLM.AgentMode = AgentModeReAct
+	}
+
+	if c.LLM.ToolLoop.MaxIterations <= 0 {
+		c.LLM.ToolLoop.MaxIterations = 5
+	} else if c.LLM.ToolLoop.MaxIterations > maxToolLoopIterations {
+		c.LLM.ToolLoop.MaxIterations = maxToolLoopIterations
+	}
+
+	if c.LLM.Failover.FailureThreshold <= 0 {
+		c.LLM.Failover.FailureThreshold = 3
+	}
+
+	if c.LLM.Failover.CooldownSeconds <= 0 {
+		c.LLM.Failover.CooldownSeconds = 60
+	}
+
+	if c.LLM.Failover.AttemptTimeoutSeconds <= 0 {
+		c.LLM.Failover.AttemptTimeoutSeconds = 60
+	}
+
+	if c.LLM.Routing.Classifier.TimeoutSeconds <= 0 {
+		c.LLM.Routing.Classifier.TimeoutSeconds = 10
+	}
+
+	if c.LLM.ContextBudget.ReservedOutputTokens <= 0 {
+		c.LLM.ContextBudget.ReservedOutputTokens = 1024
+	}
+
+	if c.LLM.Cache.TTLSeconds <= 0 {
+		c.LLM.Cache.TTLSeconds = 3600
+	}
+
+	if c.LLM.Cache.MaxEntries <= 0 {
+		c.LLM.Cache.MaxEntries = 1000
+	}
+
+	if c.LLM.Cache.Semantic.Threshold <= 0 || c.LLM.Cache.Semantic.Threshold > 1 {
+		c.LLM.Cache.Semantic.Threshold = 0.95
+	}
+
 	// Ensure providers map exists
 	if c.LLM.Providers == nil {
 		c.LLM.Providers = make(map[string]LLMProviderConfig)
@@ -226,48 +543,49 @@ func (c *Config) applyLLMDefaults() {
 	// Set default provider configurations if they don't exist
 	if _, exists := c.LLM.Providers[ProviderOpenAI]; !exists {
 		c.LLM.Providers[ProviderOpenAI] = LLMProviderConfig{
-			Model:        "gpt-4o",
-			Temperature:  0.7,
-			ThinkingMode: "auto",
-		}
-	} else {
-		// Apply default thinking mode if not set
-		if providerConfig := c.LLM.Providers[ProviderOpenAI]; providerConfig.ThinkingMode == "" {
-			providerConfig.ThinkingMode = "auto"
-			c.LLM.Providers[ProviderOpenAI] = providerConfig
+			Type:        ProviderOpenAI,
+			Model:       "gpt-4o",
+			Temperature: 0.7,
 		}
 	}
 
 	if _, exists := c.LLM.Providers[ProviderAnthropic]; !exists {
 		c.LLM.Providers[ProviderAnthropic] = LLMProviderConfig{
-			Model:        "claude-3-5-sonnet-20241022",
-			Temperature:  0.7,
-			ThinkingMode: "auto",
-		}
-	} else {
-		// Apply default thinking mode if not set
-		if providerConfig := c.LLM.Providers[ProviderAnthropic]; providerConfig.ThinkingMode == "" {
-			providerConfig.ThinkingMode = "auto"
-			c.LLM.Providers[ProviderAnthropic] = providerConfig
+			Type:        ProviderAnthropic,
+			Model:       "claude-3-5-sonnet-20241022",
+			Temperature: 0.7,
 		}
 	}
 
 	if _, exists := c.LLM.Providers[ProviderOllama]; !exists {
 		c.LLM.Providers[ProviderOllama] = LLMProviderConfig{
-			Model:        "llama3",
-			BaseURL:      "http://localhost:11434",
-			Temperature:  0.7,
-			ThinkingMode: "auto",
+			Type:        ProviderOllama,
+			Model:       "llama3",
+			BaseURL:     "http://localhost:11434",
+			Temperature: 0.7,
 		}
-	} else {
-		// Apply default thinking mode if not set
-		if providerConfig := c.LLM.Providers[ProviderOllama]; providerConfig.ThinkingMode == "" {
-			providerConfig.ThinkingMode = "auto"
-			c.LLM.Providers[ProviderOllama] = providerConfig
+	}
+
+	c.LLM.migrateProviderTypes()
+
+	// Apply default thinking mode to every instance of the built-in types
+	for name, providerConfig := range c.LLM.Providers {
+		switch providerConfig.Type {
+		case ProviderOpenAI, ProviderAnthropic, ProviderOllama:
+			if providerConfig.ThinkingMode == "" {
+				providerConfig.ThinkingMode = "auto"
+				c.LLM.Providers[name] = providerConfig
+			}
 		}
 	}
 }
 
+// envProvider returns the built-in instance of a provider type, which environment variables configure
+func (c *Config) envProvider(providerType string) (LLMProviderConfig, bool) {
+	providerConfig, exists := c.LLM.Providers[providerType]
+	return providerConfig, exists && c.LLM.ProviderType(providerType) == providerType
+}
+
 // applyRAGDefaults sets default RAG provider and configurations
 func (c *Config) applyRAGDefaults() {
 	if c.RAG.Provider == "" {
@@ -300,6 +618,75 @@ func (c *Config) applySlackDefaults() {
 	if c.Slack.ThinkingMessage == "" {
 		c.Slack.ThinkingMessage = "Thinking..."
 	}
+	if c.Slack.Permalinks.MaxLinks == 0 {
+		c.Slack.Permalinks.MaxLinks = 3
+	}
+	if c.Slack.Permalinks.MaxThreadMessages == 0 {
+		c.Slack.Permalinks.MaxThreadMessages = 20
+	}
+	if c.Slack.ChannelHistory.MaxMessages == 0 {
+		c.Slack.ChannelHistory.MaxMessages = 50
+	}
+	if c.Slack.ChannelHistory.MaxAgeHours == 0 {
+		c.Slack.ChannelHistory.MaxAgeHours = 24
+	}
+	if c.Slack.AgentTrace.Verbosity == "" {
+		c.Slack.AgentTrace.Verbosity = AgentTraceSummary
+	}
+}
+
+// RedactionConfig controls masking of PII and secrets before text is sent to LLM and tracing providers
+type RedactionConfig struct {
+	Enabled         bool              `json:"enabled,omitempty"`         // Enable redaction (default: false)
+	Detectors       []string          `json:"detectors,omitempty"`       // Built-in detectors: email, api_key, credit_card (default: all)
+	CustomPatterns  map[string]string `json:"customPatterns,omitempty"`  // Extra detectors by name, as Go regular expressions
+	RedactUserNames bool              `json:"redactUserNames,omitempty"` // Also mask the real names of thread participants
+}
+
+// QuotaConfig limits the LLM tokens users and channels may use per day and per month (UTC)
+type QuotaConfig struct {
+	Enabled   bool                   `json:"enabled,omitempty"`
+	UsageFile string                 `json:"usageFile,omitempty"` // Where usage is persisted (default: ./llm-usage.json)
+	User      QuotaLimits            `json:"user,omitempty"`      // Limits of each user
+	Channel   QuotaLimits            `json:"channel,omitempty"`   // Limits of each channel
+	Users     map[string]QuotaLimits `json:"users,omitempty"`     // Limits by user ID, replacing the user limits
+	Channels  map[string]QuotaLimits `json:"channels,omitempty"`  // Limits by channel ID, replacing the channel limits
+}
+
+// QuotaLimits are token limits; 0 means no limit
+type QuotaLimits struct {
+	DailyTokens   int `json:"dailyTokens,omitempty"`
+	MonthlyTokens int `json:"monthlyTokens,omitempty"`
+}
+
+// UserLimits returns the limits of a user
+func (c QuotaConfig) UserLimits(userID string) QuotaLimits {
+	if limits, ok := c.Users[userID]; ok {
+		return limits
+	}
+	return c.User
+}
+
+// ChannelLimits returns the limits of a channel
+func (c QuotaConfig) ChannelLimits(channelID string) QuotaLimits {
+	if limits, ok := c.Channels[channelID]; ok {
+		return limits
+	}
+	return c.Channel
+}
+
+// applyQuotaDefaults sets the default usage file
+func (c *Config) applyQuotaDefaults() {
+	if c.Quotas.UsageFile == "" {
+		c.Quotas.UsageFile = "./llm-usage.json"
+	}
+}
+
+// applyMessagesDefaults sets the default message locale
+func (c *Config) applyMessagesDefaults() {
+	if c.Messages.DefaultLocale == "" {
+		c.Messages.DefaultLocale = "en-US"
+	}
 }
 
 // applyTimeoutDefaults sets default timeout values
@@ -408,13 +795,14 @@ func (c *Config) ApplyEnvironmentVariables() {
 		}
 	}
 
-	// Apply API keys to provider configurations
+	// Apply API keys to provider configurations. Variables such as OPENAI_API_KEY configure the
+	// instance named after the type; other instances use their own settings or ${VAR} placeholders.
 	if c.LLM.Providers == nil {
 		c.LLM.Providers = make(map[string]LLMProviderConfig)
 	}
 
 	// OpenAI configuration
-	if openaiConfig, exists := c.LLM.Providers[ProviderOpenAI]; exists {
+	if openaiConfig, exists := c.envProvider(ProviderOpenAI); exists {
 		if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
 			openaiConfig.APIKey = apiKey
 		}
@@ -433,7 +821,7 @@ func (c *Config) ApplyEnvironmentVariables() {
 	}
 
 	// Anthropic configuration
-	if anthropicConfig, exists := c.LLM.Providers[ProviderAnthropic]; exists {
+	if anthropicConfig, exists := c.envProvider(ProviderAnthropic); exists {
 		if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
 			anthropicConfig.APIKey = apiKey
 		}
@@ -452,7 +840,7 @@ func (c *Config) ApplyEnvironmentVariables() {
 	}
 
 	// Ollama configuration
-	if ollamaConfig, exists := c.LLM.Providers[ProviderOllama]; exists {
+	if ollamaConfig, exists := c.envProvider(ProviderOllama); exists {
 		if baseURL := os.Getenv("OLLAMA_BASE_URL"); baseURL != "" {
 			ollamaConfig.BaseURL = baseURL
 		}
@@ -469,6 +857,19 @@ func (c *Config) ApplyEnvironmentVariables() {
 		}
 		c.LLM.Providers[ProviderOllama] = ollamaConfig
 	}
+	// Azure OpenAI configuration
+	if azureConfig, exists := c.envProvider(ProviderAzure); exists {
+		if apiKey := os.Getenv("AZURE_OPENAI_API_KEY"); apiKey != "" {
+			azureConfig.APIKey = apiKey
+		}
+		if endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT"); endpoint != "" {
+			azureConfig.BaseURL = endpoint
+		}
+		if apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION"); apiVersion != "" {
+			azureConfig.APIVersion = apiVersion
+		}
+		c.LLM.Providers[ProviderAzure] = azureConfig
+	}
 	// Observability overrides
 	if enabled := os.Getenv("OBSERVABILITY_ENABLED"); enabled != "" {
 		if val, err := strconv.ParseBool(enabled); err == nil {
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -31,11 +33,118 @@ func (c *Config) ValidateAfterDefaults() error {
 		return fmt.Errorf("LLM provider '%s' not configured", c.LLM.Provider)
 	}
 
+	// Validate every provider instance has a supported type
+	names := make([]string, 0, len(c.LLM.Providers))
+	for name := range c.LLM.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		providerType := c.LLM.Providers[name].Type
+		if providerType == "" && !IsProviderType(name) {
+			return fmt.Errorf("LLM provider '%s' needs a \"type\" (supported: %s)", name, strings.Join(ProviderTypes, ", "))
+		}
+		if providerType != "" && !IsProviderType(providerType) {
+			return fmt.Errorf("LLM provider '%s' has unknown type '%s' (supported: %s)", name, providerType, strings.Join(ProviderTypes, ", "))
+		}
+	}
+
+	// Validate agent modes
+	for _, name := range names {
+		if mode := c.LLM.ProviderAgentMode(name); mode != AgentModeReAct && mode != AgentModeNative {
+			return fmt.Errorf("unknown agentMode '%s' for LLM provider '%s' (supported: %s, %s)", mode, name, AgentModeReAct, AgentModeNative)
+		}
+	}
+
+	// Validate agent trace verbosities
+	traceVerbosities := map[string]string{"slack.agentTrace.verbosity": c.Slack.AgentTrace.Verbosity}
+	for channelID, verbosity := range c.Slack.AgentTrace.Channels {
+		traceVerbosities["slack.agentTrace.channels."+channelID] = verbosity
+	}
+	for field, verbosity := range traceVerbosities {
+		switch verbosity {
+		case AgentTraceOff, AgentTraceSummary, AgentTraceDetailed:
+		default:
+			return fmt.Errorf("unknown verbosity '%s' in %s (supported: %s, %s, %s)", verbosity, field, AgentTraceOff, AgentTraceSummary, AgentTraceDetailed)
+		}
+	}
+
+	// Validate the timeouts used by the bridge
+	bridgeTimeouts := map[string]string{
+		"timeouts.bridgeOperationTimeout": c.Timeouts.BridgeOperationTimeout,
+		"timeouts.toolProcessingTimeout":  c.Timeouts.ToolProcessingTimeout,
+	}
+	for field, value := range bridgeTimeouts {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration '%s' in %s (e.g. \"90s\" or \"3m\")", value, field)
+		}
+	}
+
+	// Validate fallback chains only name configured providers
+	for _, name := range c.LLM.Fallbacks {
+		if _, exists := c.LLM.Providers[name]; !exists {
+			return fmt.Errorf("LLM fallback provider '%s' not configured", name)
+		}
+	}
+	for channelID, chain := range c.LLM.ChannelProviders {
+		for _, name := range chain {
+			if _, exists := c.LLM.Providers[name]; !exists {
+				return fmt.Errorf("LLM provider '%s' for channel '%s' not configured", name, channelID)
+			}
+		}
+	}
+
+	// Validate routing tiers and rules
+	if c.LLM.Routing.Enabled {
+		for name, tier := range c.LLM.Routing.Tiers {
+			if _, exists := c.LLM.Providers[tier.Provider]; !exists {
+				return fmt.Errorf("LLM provider '%s' for routing tier '%s' not configured", tier.Provider, name)
+			}
+		}
+		if _, exists := c.LLM.Routing.Tiers[c.LLM.Routing.DefaultTier]; c.LLM.Routing.DefaultTier != "" && !exists {
+			return fmt.Errorf("default routing tier '%s' not configured", c.LLM.Routing.DefaultTier)
+		}
+		for i, rule := range c.LLM.Routing.Rules {
+			if _, exists := c.LLM.Routing.Tiers[rule.Tier]; !exists {
+				return fmt.Errorf("routing rule %d uses tier '%s', which is not configured", i+1, rule.Tier)
+			}
+		}
+		if classifier := c.LLM.Routing.Classifier.Provider; classifier != "" {
+			if _, exists := c.LLM.Providers[classifier]; !exists {
+				return fmt.Errorf("LLM provider '%s' for the routing classifier not configured", classifier)
+			}
+		}
+	}
+
+	if c.LLM.Cache.Enabled && c.LLM.Cache.Semantic.Enabled && c.RAG.EmbeddingProvider == "" {
+		return fmt.Errorf("semantic response caching requires rag.embeddingProvider")
+	}
+
+	// Validate quota limits are not negative
+	if c.Quotas.Enabled {
+		limits := map[string]QuotaLimits{"user": c.Quotas.User, "channel": c.Quotas.Channel}
+		for userID, l := range c.Quotas.Users {
+			limits["user "+userID] = l
+		}
+		for channelID, l := range c.Quotas.Channels {
+			limits["channel "+channelID] = l
+		}
+		for name, l := range limits {
+			if l.DailyTokens < 0 || l.MonthlyTokens < 0 {
+				return fmt.Errorf("quota limits for %s must not be negative", name)
+			}
+		}
+	}
+
 	// Validate provider-specific requirements
 	providerConfig := c.LLM.Providers[c.LLM.Provider]
-	switch c.LLM.Provider {
+	switch c.LLM.ProviderType(c.LLM.Provider) {
 	case ProviderOpenAI:
-		if providerConfig.APIKey == "" || strings.HasPrefix(providerConfig.APIKey, "${") {
+		// OpenAI-compatible endpoints set through baseUrl may not need a key
+		if providerConfig.BaseURL == "" && (providerConfig.APIKey == "" || strings.HasPrefix(providerConfig.APIKey, "${")) {
 			return fmt.Errorf("OPENAI_API_KEY environment variable not set")
 		}
 	case ProviderAnthropic:
@@ -44,6 +153,26 @@ func (c *Config) ValidateAfterDefaults() error {
 		}
 	}
 
+	// Azure OpenAI needs its endpoint, and an API key unless it uses Entra ID
+	for _, name := range names {
+		if c.LLM.ProviderType(name) != ProviderAzure {
+			continue
+		}
+		azure := c.LLM.Providers[name]
+		if azure.BaseURL == "" || strings.HasPrefix(azure.BaseURL, "${") {
+			return fmt.Errorf("endpoint (AZURE_OPENAI_ENDPOINT) not set for Azure OpenAI provider '%s'", name)
+		}
+		switch azure.AuthType {
+		case "", AzureAuthAPIKey:
+			if azure.APIKey == "" || strings.HasPrefix(azure.APIKey, "${") {
+				return fmt.Errorf("API key (AZURE_OPENAI_API_KEY) not set for Azure OpenAI provider '%s'", name)
+			}
+		case AzureAuthEntraID:
+		default:
+			return fmt.Errorf("unknown authType '%s' for Azure OpenAI provider '%s' (supported: %s, %s)", azure.AuthType, name, AzureAuthAPIKey, AzureAuthEntraID)
+		}
+	}
+
 	// Validate observability configuration
 	if c.Observability.Enabled {
 		if c.Observability.Provider == ObservabilityProviderLangfuse {
@@ -197,6 +326,7 @@ func (c *Config) SubstituteEnvironmentVariables() {
 	// Substitute in RAG Embedding Providers configuration
 	for name, provider := range c.RAG.EmbeddingProviders {
 		provider.APIKey = substituteEnvVars(provider.APIKey)
+		provider.BaseURL = substituteEnvVars(provider.BaseURL)
 		c.RAG.EmbeddingProviders[name] = provider
 	}
 
@@ -239,6 +369,11 @@ func LoadConfig(configFile string, logger *logging.Logger) (*Config, error) {
 		}
 	}
 
+	// Entries of older configs are named after their provider type and have no type field
+	if migrated := cfg.LLM.migrateProviderTypes(); len(migrated) > 0 && logger != nil {
+		logger.DebugKV("Set LLM provider types from their names", "providers", migrated)
+	}
+
 	// Perform environment variable substitution (for ${VAR} placeholders only)
 	cfg.SubstituteEnvironmentVariables()
 
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/monitoring"
+)
+
+// Keys of the token usage that Anthropic responses report in GenerationInfo
+const (
+	anthropicCacheReadTokens  = "CacheReadInputTokens"
+	anthropicCacheWriteTokens = "CacheCreationInputTokens"
+)
+
+// stableSystemKey carries the length of the system prompt's stable prefix to the HTTP client
+type stableSystemKey struct{}
+
+// anthropicModel joins system messages into one prompt, passes the length of its stable first part
+// to the prompt caching client and reports token usage under the names the other providers use
+type anthropicModel struct {
+	llms.Model
+	model string
+}
+
+// GenerateContent implements llms.Model
+func (m *anthropicModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	messages, stableSystem := joinSystemMessages(messages)
+	ctx = context.WithValue(ctx, stableSystemKey{}, stableSystem)
+	resp, err := m.Model.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	for i, choice := range resp.Choices {
+		if choice.GenerationInfo == nil {
+			continue
+		}
+		input, _ := choice.GenerationInfo["InputTokens"].(int)
+		output, _ := choice.GenerationInfo["OutputTokens"].(int)
+		cacheRead, _ := choice.GenerationInfo[anthropicCacheReadTokens].(int)
+		cacheWrite, _ := choice.GenerationInfo[anthropicCacheWriteTokens].(int)
+		// Anthropic counts cached prompt tokens separately from the rest of the input
+		choice.GenerationInfo["PromptTokens"] = input + cacheRead + cacheWrite
+		choice.GenerationInfo["CompletionTokens"] = output
+		choice.GenerationInfo["TotalTokens"] = input + cacheRead + cacheWrite + output
+		if i == 0 {
+			recordAnthropicTokens(m.model, choice.GenerationInfo)
+		}
+	}
+	return resp, nil
+}
+
+// Call implements the deprecated single-prompt interface of llms.Model
+func (m *anthropicModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// joinSystemMessages merges the system messages into the first one, separated by blank lines, since the
+// Anthropic client would otherwise concatenate them. It returns the length of the first system text,
+// which holds the instructions that stay the same between requests; later ones such as channel history change.
+func joinSystemMessages(messages []llms.MessageContent) ([]llms.MessageContent, int) {
+	var system []string
+	var joined []llms.MessageContent
+	first := -1
+	for _, message := range messages {
+		if message.Role != llms.ChatMessageTypeSystem {
+			joined = append(joined, message)
+			continue
+		}
+		var text strings.Builder
+		for _, part := range message.Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				text.WriteString(textPart.Text)
+			}
+		}
+		if text.Len() == 0 {
+			continue
+		}
+		if first < 0 {
+			first = len(joined)
+			joined = append(joined, llms.MessageContent{})
+		}
+		system = append(system, text.String())
+	}
+	if first < 0 {
+		return messages, 0
+	}
+	joined[first] = llms.TextParts(llms.ChatMessageTypeSystem, strings.Join(system, "\n\n"))
+	return joined, len(system[0])
+}
+
+// recordAnthropicTokens reports token usage, including prompt cache reads and writes
+func recordAnthropicTokens(model string, info map[string]any) {
+	for _, key := range []string{"PromptTokens", "CompletionTokens", "TotalTokens"} {
+		if tokens, ok := info[key].(int); ok {
+			monitoring.LLMTokensPerRequest.With(prometheus.Labels{
+				monitoring.MetricLabelType:  key,
+				monitoring.MetricLabelModel: model,
+			}).Observe(float64(tokens))
+		}
+	}
+	for key, cacheType := range map[string]string{anthropicCacheReadTokens: "read", anthropicCacheWriteTokens: "write"} {
+		if tokens, ok := info[key].(int); ok && tokens > 0 {
+			monitoring.LLMPromptCacheTokens.With(prometheus.Labels{
+				monitoring.MetricLabelType:  cacheType,
+				monitoring.MetricLabelModel: model,
+			}).Add(float64(tokens))
+		}
+	}
+}
+
+// promptCachingClient marks the stable prefixes of Messages API requests as cacheable: the tool
+// definitions, the stable part of the system prompt and the conversation before the latest turn
+type promptCachingClient struct {
+	client *http.Client
+}
+
+// Do implements the Anthropic client's Doer interface
+func (c *promptCachingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/messages") || req.Body == nil {
+		return c.client.Do(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	stableSystem, _ := req.Context().Value(stableSystemKey{}).(int)
+	// Requests that cannot be marked are sent as they are
+	if marked, err := markCacheBreakpoints(body, stableSystem); err == nil {
+		body = marked
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return c.client.Do(req)
+}
+
+// markCacheBreakpoints adds cache_control breakpoints to a Messages API request body. A stableSystem
+// length inside the system prompt splits it into a cached block and an uncached one.
+func markCacheBreakpoints(body []byte, stableSystem int) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload map[string]any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	if tools, ok := payload["tools"].([]any); ok && len(tools) > 0 {
+		if tool, ok := tools[len(tools)-1].(map[string]any); ok {
+			tool["cache_control"] = ephemeralCacheControl()
+		}
+	}
+
+	if system, ok := payload["system"].(string); ok && system != "" {
+		blocks := []any{map[string]any{"type": "text", "text": system, "cache_control": ephemeralCacheControl()}}
+		if stableSystem > 0 && stableSystem < len(system) {
+			if rest := strings.TrimLeft(system[stableSystem:], "\n"); rest != "" {
+				blocks = []any{
+					map[string]any{"type": "text", "text": system[:stableSystem], "cache_control": ephemeralCacheControl()},
+					map[string]any{"type": "text", "text": rest},
+				}
+			}
+		}
+		payload["system"] = blocks
+	}
+
+	// The conversation up to the latest turn is resent unchanged with the next message
+	if messages, ok := payload["messages"].([]any); ok && len(messages) >= 2 {
+		if message, ok := messages[len(messages)-2].(map[string]any); ok {
+			markLastContentBlock(message)
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// markLastContentBlock marks the last block of a message that can carry a cache breakpoint
+func markLastContentBlock(message map[string]any) {
+	if text, ok := message["content"].(string); ok && text != "" {
+		message["content"] = []any{map[string]any{"type": "text", "text": text, "cache_control": ephemeralCacheControl()}}
+		return
+	}
+	content, _ := message["content"].([]any)
+	for i := len(content) - 1; i >= 0; i-- {
+		block, ok := content[i].(map[string]any)
+		if !ok || block["type"] == "thinking" || block["type"] == "redacted_thinking" {
+			continue
+		}
+		block["cache_control"] = ephemeralCacheControl()
+		return
+	}
+}
+
+// ephemeralCacheControl is Anthropic's default five-minute cache breakpoint
+func ephemeralCacheControl() map[string]any {
+	return map[string]any{"type": "ephemeral"}
+}
@@ -1,6 +1,8 @@
 package llm
 
 import (
+	"net/http"
+
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/anthropic"
 	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
@@ -25,17 +27,22 @@ func (f *AnthropicModelFactory) Create(config map[string]interface{}, logger *lo
 	modelName, _ := config["model"].(string)  // Already validated in parent factory
 	apiKey, _ := config["api_key"].(string)   // Already validated in Validate method
 	baseURL, _ := config["base_url"].(string) // Optional custom base URL
+	disablePromptCaching, _ := config["disable_prompt_caching"].(bool)
 
 	opts := []anthropic.Option{
 		anthropic.WithModel(modelName), // Set model during initialization
 		anthropic.WithToken(apiKey),    // API key is required
 	}
 
+	if !disablePromptCaching {
+		opts = append(opts, anthropic.WithHTTPClient(&promptCachingClient{client: &http.Client{}}))
+	}
+
 	if baseURL != "" {
 		opts = append(opts, anthropic.WithBaseURL(baseURL))
-		logger.InfoKV("Configuring LangChain with Anthropic", "base_url", baseURL, "model", modelName)
+		logger.InfoKV("Configuring LangChain with Anthropic", "base_url", baseURL, "model", modelName, "prompt_caching", !disablePromptCaching)
 	} else {
-		logger.InfoKV("Configuring LangChain with Anthropic (default endpoint)", "model", modelName)
+		logger.InfoKV("Configuring LangChain with Anthropic (default endpoint)", "model", modelName, "prompt_caching", !disablePromptCaching)
 	}
 
 	llmClient, err := anthropic.New(opts...)
@@ -54,5 +61,5 @@ func (f *AnthropicModelFactory) Create(config map[string]interface{}, logger *lo
 		return nil, domainErr
 	}
 
-	return llmClient, nil
+	return &anthropicModel{Model: llmClient, model: modelName}, nil
 }
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload = nil
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022",
+			"content":[{"type":"text","text":"Restart the pod."}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":20,"output_tokens":5,"cache_creation_input_tokens":0,"cache_read_input_tokens":1500}}`)
+	}))
+	defer server.Close()
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are an SRE assistant."),
+		llms.TextParts(llms.ChatMessageTypeSystem, "Recent channel messages: deploy failed"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Why did the deploy fail?"),
+		llms.TextParts(llms.ChatMessageTypeAI, "The pod crashed."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "How do I fix it?"),
+	}
+	tools := []llms.Tool{
+		{Type: "function", Function: &llms.FunctionDefinition{Name: "get_logs", Parameters: map[string]any{"type": "object"}}},
+		{Type: "function", Function: &llms.FunctionDefinition{Name: "restart_pod", Parameters: map[string]any{"type": "object"}}},
+	}
+
+	tests := []struct {
+		name     string
+		disabled bool
+	}{
+		{"enabled", false},
+		{"disabled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"model":                  "claude-3-5-sonnet-20241022",
+				"api_key":                "secret",
+				"base_url":               server.URL,
+				"disable_prompt_caching": tt.disabled,
+			}
+			model, err := (&AnthropicModelFactory{}).Create(config, logging.New("test", logging.LevelError))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			resp, err := model.GenerateContent(context.Background(), messages, llms.WithTools(tools), llms.WithMaxTokens(100))
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+
+			info := resp.Choices[0].GenerationInfo
+			if info["PromptTokens"] != 1520 || info["CompletionTokens"] != 5 || info["TotalTokens"] != 1525 {
+				t.Errorf("token usage = %v", info)
+			}
+
+			if tt.disabled {
+				want := "You are an SRE assistant.\n\nRecent channel messages: deploy failed"
+				if payload["system"] != want {
+					t.Errorf("system = %v, want %q", payload["system"], want)
+				}
+				return
+			}
+
+			system, _ := payload["system"].([]any)
+			if len(system) != 2 || !hasCacheControl(system[0]) || hasCacheControl(system[1]) {
+				t.Errorf("system = %v, want a cached instructions block and an uncached channel history block", payload["system"])
+			}
+			if text := system[0].(map[string]any)["text"]; text != "You are an SRE assistant." {
+				t.Errorf("cached system text = %q", text)
+			}
+			requestTools, _ := payload["tools"].([]any)
+			if len(requestTools) != 2 || hasCacheControl(requestTools[0]) || !hasCacheControl(requestTools[1]) {
+				t.Errorf("tools = %v, want a breakpoint on the last tool", payload["tools"])
+			}
+			requestMessages, _ := payload["messages"].([]any)
+			for i, message := range requestMessages {
+				content := message.(map[string]any)["content"].([]any)
+				if want := i == len(requestMessages)-2; hasCacheControl(content[len(content)-1]) != want {
+					t.Errorf("message %d cached = %v, want %v", i, !want, want)
+				}
+			}
+		})
+	}
+}
+
+func hasCacheControl(block any) bool {
+	m, ok := block.(map[string]any)
+	return ok && m["cache_control"] != nil
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/azure"
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	appconfig "github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+// azureRequestTimeout bounds a single Azure OpenAI request when authenticating with Entra ID
+const azureRequestTimeout = 5 * time.Minute
+
+// AzureOpenAIModelFactory creates Azure OpenAI LangChain model instances
+type AzureOpenAIModelFactory struct{}
+
+// Validate checks if the configuration is valid for Azure OpenAI
+func (f *AzureOpenAIModelFactory) Validate(config map[string]interface{}) error {
+	if baseURL, _ := config["base_url"].(string); baseURL == "" {
+		return customErrors.NewLLMError("missing_config", "Azure OpenAI config requires 'base_url' (the resource endpoint)")
+	}
+	authType, _ := config["auth_type"].(string)
+	apiKey, _ := config["api_key"].(string)
+	if !azureUsesEntraID(authType) && apiKey == "" {
+		return customErrors.NewLLMError("missing_config", "Azure OpenAI config requires 'api_key' unless authType is entraId")
+	}
+	return nil
+}
+
+// Create returns a new Azure OpenAI LangChain model instance
+func (f *AzureOpenAIModelFactory) Create(config map[string]interface{}, logger *logging.Logger) (llms.Model, error) {
+	modelName, _ := config["model"].(string) // Already validated in parent factory
+	apiKey, _ := config["api_key"].(string)
+	baseURL, _ := config["base_url"].(string)
+	authType, _ := config["auth_type"].(string)
+	deployments, _ := config["deployments"].(map[string]string)
+	apiVersion, _ := config["api_version"].(string)
+	if apiVersion == "" {
+		apiVersion = azure.DefaultAPIVersion
+	}
+
+	deployment := azureDeployment(deployments, modelName)
+	opts := []openai.Option{
+		openai.WithModel(deployment), // Azure routes requests by deployment name
+		openai.WithBaseURL(baseURL),
+		openai.WithAPIVersion(apiVersion),
+		openai.WithCallback(newTokenMetricsCallback(modelName, logger)),
+	}
+
+	if azureUsesEntraID(authType) {
+		httpClient, err := azure.NewTokenClient(nil, azureRequestTimeout)
+		if err != nil {
+			logger.ErrorKV("Failed to create Entra ID credential for Azure OpenAI", "error", err)
+			return nil, customErrors.WrapLLMError(err, "initialization_failed", "Failed to create Entra ID credential for Azure OpenAI").
+				WithData("model", modelName)
+		}
+		// The transport replaces this placeholder with a fresh token on every request
+		opts = append(opts, openai.WithAPIType(openai.APITypeAzureAD), openai.WithToken("entra-id"), openai.WithHTTPClient(httpClient))
+	} else {
+		opts = append(opts, openai.WithAPIType(openai.APITypeAzure), openai.WithToken(apiKey))
+	}
+	logger.InfoKV("Configuring LangChain with Azure OpenAI", "base_url", baseURL, "model", modelName,
+		"deployment", deployment, "api_version", apiVersion, "auth_type", authType)
+
+	llmClient, err := openai.New(opts...)
+	if err != nil {
+		logger.ErrorKV("Failed to initialize LangChainGo Azure OpenAI client", "error", err)
+		return nil, customErrors.WrapLLMError(err, "initialization_failed", "Failed to initialize Azure OpenAI client").
+			WithData("model", modelName).
+			WithData("base_url", baseURL)
+	}
+
+	return &azureOpenAIModel{Model: llmClient, deployments: deployments}, nil
+}
+
+// azureUsesEntraID reports whether the provider authenticates with Entra ID instead of an API key
+func azureUsesEntraID(authType string) bool {
+	return authType == appconfig.AzureAuthEntraID
+}
+
+// azureDeployment returns the deployment of a model, which defaults to the model name
+func azureDeployment(deployments map[string]string, model string) string {
+	if deployment, ok := deployments[model]; ok && deployment != "" {
+		return deployment
+	}
+	return model
+}
+
+// azureOpenAIModel maps per-request model overrides, e.g. from routing tiers, to their deployments
+type azureOpenAIModel struct {
+	llms.Model
+	deployments map[string]string
+}
+
+// GenerateContent sends the request to the deployment of the requested model
+func (m *azureOpenAIModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.Model != "" {
+		options = append(options, llms.WithModel(azureDeployment(m.deployments, opts.Model)))
+	}
+	return m.Model.GenerateContent(ctx, messages, options...)
+}
+
+// Call implements the deprecated single-prompt interface of llms.Model
+func (m *azureOpenAIModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
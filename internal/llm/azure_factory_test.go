@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+func TestAzureOpenAIDeployments(t *testing.T) {
+	var gotPaths, gotVersions, gotKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPaths = append(gotPaths, r.URL.Path)
+		gotVersions = append(gotVersions, r.URL.Query().Get("api-version"))
+		gotKeys = append(gotKeys, r.Header.Get("api-key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"model":       "gpt-4o",
+		"api_key":     "secret",
+		"base_url":    server.URL,
+		"deployments": map[string]string{"gpt-4o": "chat-prod", "gpt-4o-mini": "chat-small"},
+	}
+	factory := &AzureOpenAIModelFactory{}
+	if err := factory.Validate(config); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	model, err := factory.Create(config, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	ctx := context.Background()
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hello")}
+	if _, err := model.GenerateContent(ctx, messages); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	// A per-request model, e.g. from a routing tier, goes to its own deployment
+	if _, err := model.GenerateContent(ctx, messages, llms.WithModel("gpt-4o-mini")); err != nil {
+		t.Fatalf("GenerateContent() with model override error = %v", err)
+	}
+	// Unmapped models use the model name as the deployment
+	if _, err := model.GenerateContent(ctx, messages, llms.WithModel("o3-mini")); err != nil {
+		t.Fatalf("GenerateContent() with unmapped model error = %v", err)
+	}
+
+	wantPaths := []string{
+		"/openai/deployments/chat-prod/chat/completions",
+		"/openai/deployments/chat-small/chat/completions",
+		"/openai/deployments/o3-mini/chat/completions",
+	}
+	for i, want := range wantPaths {
+		if i >= len(gotPaths) || gotPaths[i] != want {
+			t.Fatalf("request paths = %v, want %v", gotPaths, wantPaths)
+		}
+		if gotVersions[i] != "2024-10-21" || gotKeys[i] != "secret" {
+			t.Errorf("request %d api-version = %q, api-key = %q", i, gotVersions[i], gotKeys[i])
+		}
+	}
+}
+
+func TestAzureOpenAIValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"api key", map[string]interface{}{"base_url": "https://example.openai.azure.com", "api_key": "secret"}, false},
+		{"entra id without key", map[string]interface{}{"base_url": "https://example.openai.azure.com", "auth_type": "entraId"}, false},
+		{"missing key", map[string]interface{}{"base_url": "https://example.openai.azure.com"}, true},
+		{"missing endpoint", map[string]interface{}{"api_key": "secret"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&AzureOpenAIModelFactory{}).Validate(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmc/langchaingo/llms"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/monitoring"
+)
+
+// converseAPI is the part of the Bedrock runtime client used by bedrockModel
+type converseAPI interface {
+	Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error)
+}
+
+// bedrockModel is a LangChain model backed by the Bedrock Converse API, which gives Claude, Llama,
+// Titan and other Bedrock models one request format, including native tool use where the model supports it.
+type bedrockModel struct {
+	client converseAPI
+	model  string
+}
+
+var _ llms.Model = (*bedrockModel)(nil)
+
+// Call implements the deprecated single-prompt interface of llms.Model
+func (m *bedrockModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// GenerateContent sends the messages through Converse and returns the answer as a single choice
+func (m *bedrockModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	modelID := m.model
+	if opts.Model != "" {
+		modelID = opts.Model
+	}
+
+	system, conversation, err := converseMessages(messages, len(opts.Tools) > 0)
+	if err != nil {
+		return nil, err
+	}
+	input := &bedrockruntime.ConverseInput{
+		ModelId:  aws.String(modelID),
+		Messages: conversation,
+		System:   system,
+	}
+
+	if opts.MaxTokens > 0 || opts.Temperature > 0 || len(opts.StopWords) > 0 {
+		input.InferenceConfig = &types.InferenceConfiguration{StopSequences: opts.StopWords}
+		if opts.MaxTokens > 0 {
+			input.InferenceConfig.MaxTokens = aws.Int32(int32(opts.MaxTokens))
+		}
+		if opts.Temperature > 0 {
+			input.InferenceConfig.Temperature = aws.Float32(float32(opts.Temperature))
+		}
+	}
+	if len(opts.Tools) > 0 {
+		input.ToolConfig = converseTools(opts.Tools)
+	}
+
+	output, err := m.client.Converse(ctx, input)
+	if err != nil {
+		return nil, customErrors.WrapLLMError(err, "bedrock_request_failed", fmt.Sprintf("Bedrock Converse request failed for model '%s'", modelID))
+	}
+	choice, err := converseChoice(output)
+	if err != nil {
+		return nil, err
+	}
+	recordBedrockTokens(modelID, choice.GenerationInfo)
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// converseMessages splits system text from the conversation and converts each turn to Converse blocks.
+// Converse requires alternating user and assistant turns, so consecutive turns of the same role are merged;
+// tool results are sent by the user. It also requires the first turn to be the user's, so assistant turns
+// left at the start of trimmed history are dropped. Without tool definitions Converse rejects tool blocks,
+// so tool calls and results are then sent as text.
+func converseMessages(messages []llms.MessageContent, nativeTools bool) ([]types.SystemContentBlock, []types.Message, error) {
+	var system []types.SystemContentBlock
+	var conversation []types.Message
+
+	for _, message := range messages {
+		if message.Role == llms.ChatMessageTypeSystem {
+			for _, part := range message.Parts {
+				if text, ok := part.(llms.TextContent); ok && text.Text != "" {
+					system = append(system, &types.SystemContentBlockMemberText{Value: text.Text})
+				}
+			}
+			continue
+		}
+
+		role := types.ConversationRoleUser
+		if message.Role == llms.ChatMessageTypeAI {
+			role = types.ConversationRoleAssistant
+		}
+		var blocks []types.ContentBlock
+		for _, part := range message.Parts {
+			block, err := converseBlock(part, nativeTools)
+			if err != nil {
+				return nil, nil, err
+			}
+			if block != nil {
+				blocks = append(blocks, block)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if last := len(conversation) - 1; last >= 0 && conversation[last].Role == role {
+			conversation[last].Content = append(conversation[last].Content, blocks...)
+			continue
+		}
+		conversation = append(conversation, types.Message{Role: role, Content: blocks})
+	}
+
+	for len(conversation) > 0 && conversation[0].Role == types.ConversationRoleAssistant {
+		// Results of the dropped tool calls would refer to unknown tool uses
+		calls := make(map[string]bool)
+		for _, block := range conversation[0].Content {
+			if toolUse, ok := block.(*types.ContentBlockMemberToolUse); ok {
+				calls[aws.ToString(toolUse.Value.ToolUseId)] = true
+			}
+		}
+		conversation = conversation[1:]
+		if len(conversation) == 0 || len(calls) == 0 {
+			continue
+		}
+		var kept []types.ContentBlock
+		for _, block := range conversation[0].Content {
+			if result, ok := block.(*types.ContentBlockMemberToolResult); ok && calls[aws.ToString(result.Value.ToolUseId)] {
+				continue
+			}
+			kept = append(kept, block)
+		}
+		if len(kept) == 0 {
+			conversation = conversation[1:]
+			continue
+		}
+		conversation[0].Content = kept
+	}
+	return system, conversation, nil
+}
+
+// converseBlock converts one message part; empty text is dropped since Converse rejects blank blocks
+func converseBlock(part llms.ContentPart, nativeTools bool) (types.ContentBlock, error) {
+	switch p := part.(type) {
+	case llms.TextContent:
+		if strings.TrimSpace(p.Text) == "" {
+			return nil, nil
+		}
+		return &types.ContentBlockMemberText{Value: p.Text}, nil
+	case llms.ToolCall:
+		if p.FunctionCall == nil {
+			return nil, nil
+		}
+		if !nativeTools {
+			return &types.ContentBlockMemberText{Value: fmt.Sprintf("Calling tool '%s' with %s", p.FunctionCall.Name, p.FunctionCall.Arguments)}, nil
+		}
+		args := map[string]interface{}{}
+		if p.FunctionCall.Arguments != "" {
+			if err := json.Unmarshal([]byte(p.FunctionCall.Arguments), &args); err != nil {
+				return nil, customErrors.WrapLLMError(err, "invalid_tool_arguments", fmt.Sprintf("Tool call '%s' has invalid JSON arguments", p.FunctionCall.Name))
+			}
+		}
+		return &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: aws.String(p.ID),
+			Name:      aws.String(p.FunctionCall.Name),
+			Input:     document.NewLazyDocument(args),
+		}}, nil
+	case llms.ToolCallResponse:
+		if !nativeTools {
+			return &types.ContentBlockMemberText{Value: toolResultText(p.Name, p.Content)}, nil
+		}
+		return &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+			ToolUseId: aws.String(p.ToolCallID),
+			Content:   []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: p.Content}},
+		}}, nil
+	default:
+		return nil, customErrors.NewLLMErrorf("unsupported_content", "Bedrock does not support message parts of type %T", part)
+	}
+}
+
+// converseTools converts tool definitions to a Converse tool configuration
+func converseTools(tools []llms.Tool) *types.ToolConfiguration {
+	config := &types.ToolConfiguration{}
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		config.Tools = append(config.Tools, &types.ToolMemberToolSpec{Value: types.ToolSpecification{
+			Name:        aws.String(tool.Function.Name),
+			Description: aws.String(tool.Function.Description),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(schema)},
+		}})
+	}
+	return config
+}
+
+// converseChoice converts the Converse output into a content choice with tool calls and token usage
+func converseChoice(output *bedrockruntime.ConverseOutput) (*llms.ContentChoice, error) {
+	message, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, customErrors.NewLLMError("empty_response", "Bedrock Converse returned no message")
+	}
+
+	choice := &llms.ContentChoice{StopReason: string(output.StopReason), GenerationInfo: map[string]any{}}
+	var text strings.Builder
+	for _, block := range message.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			text.WriteString(b.Value)
+		case *types.ContentBlockMemberToolUse:
+			args := "{}"
+			if b.Value.Input != nil {
+				raw, err := b.Value.Input.MarshalSmithyDocument()
+				if err != nil {
+					return nil, customErrors.WrapLLMError(err, "invalid_tool_call", "Failed to read Bedrock tool call input")
+				}
+				args = string(raw)
+			}
+			call := llms.ToolCall{
+				ID:           aws.ToString(b.Value.ToolUseId),
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: aws.ToString(b.Value.Name), Arguments: args},
+			}
+			choice.ToolCalls = append(choice.ToolCalls, call)
+			if choice.FuncCall == nil {
+				choice.FuncCall = call.FunctionCall
+			}
+		}
+	}
+	choice.Content = text.String()
+
+	if usage := output.Usage; usage != nil {
+		choice.GenerationInfo["PromptTokens"] = int(aws.ToInt32(usage.InputTokens))
+		choice.GenerationInfo["CompletionTokens"] = int(aws.ToInt32(usage.OutputTokens))
+		choice.GenerationInfo["TotalTokens"] = int(aws.ToInt32(usage.TotalTokens))
+	}
+	return choice, nil
+}
+
+// recordBedrockTokens reports token usage in the same metric as the OpenAI client callback
+func recordBedrockTokens(model string, info map[string]any) {
+	for _, key := range []string{"PromptTokens", "CompletionTokens", "TotalTokens"} {
+		if tokens, ok := info[key].(int); ok {
+			monitoring.LLMTokensPerRequest.With(prometheus.Labels{
+				monitoring.MetricLabelType:  key,
+				monitoring.MetricLabelModel: model,
+			}).Observe(float64(tokens))
+		}
+	}
+}
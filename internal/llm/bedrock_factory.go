@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/tmc/langchaingo/llms"
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+// BedrockModelFactory creates LangChain models for AWS Bedrock, using the Converse API
+type BedrockModelFactory struct{}
+
+// Validate checks if the configuration is valid for Bedrock.
+// Credentials and region come from the AWS environment unless configured.
+func (f *BedrockModelFactory) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+// Create returns a new Bedrock LangChain model instance
+func (f *BedrockModelFactory) Create(config map[string]interface{}, logger *logging.Logger) (llms.Model, error) {
+	modelName, _ := config["model"].(string) // Already validated in parent factory
+	region, _ := config["region"].(string)
+	profile, _ := config["profile"].(string)
+	roleARN, _ := config["role_arn"].(string)
+	baseURL, _ := config["base_url"].(string) // Optional endpoint, e.g. a VPC endpoint or a local stub
+
+	var loadOptions []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		loadOptions = append(loadOptions, awsconfig.WithRegion(region))
+	}
+	if profile != "" {
+		loadOptions = append(loadOptions, awsconfig.WithSharedConfigProfile(profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOptions...)
+	if err != nil {
+		logger.ErrorKV("Failed to load AWS configuration for Bedrock", "profile", profile, "error", err)
+		return nil, customErrors.WrapLLMError(err, "initialization_failed", "Failed to load AWS configuration for Bedrock").
+			WithData("model", modelName)
+	}
+	if awsCfg.Region == "" {
+		return nil, customErrors.NewLLMError("missing_config", "Bedrock config requires 'region' or an AWS region in the environment")
+	}
+
+	if roleARN != "" {
+		awsCfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsCfg), roleARN))
+		logger.InfoKV("Assuming IAM role for Bedrock", "role_arn", roleARN)
+	}
+
+	client := bedrockruntime.NewFromConfig(awsCfg, func(o *bedrockruntime.Options) {
+		if baseURL != "" {
+			o.BaseEndpoint = aws.String(baseURL)
+		}
+	})
+	logger.InfoKV("Configuring LangChain with Bedrock Converse", "region", awsCfg.Region, "model", modelName, "base_url", baseURL)
+
+	return &bedrockModel{client: client, model: modelName}, nil
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+func TestBedrockConverse(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &gotBody); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"output": {"message": {"role": "assistant", "content": [
+				{"text": "Checking hosts."},
+				{"toolUse": {"toolUseId": "t2", "name": "list_hosts", "input": {"state": "down"}}}
+			]}},
+			"stopReason": "tool_use",
+			"usage": {"inputTokens": 10, "outputTokens": 5, "totalTokens": 15}
+		}`)
+	}))
+	defer server.Close()
+
+	// Static credentials from the environment keep the SDK away from local profiles and instance metadata
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	model, err := (&BedrockModelFactory{}).Create(map[string]interface{}{
+		"model":    "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"region":   "us-east-1",
+		"base_url": server.URL,
+	}, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are an SRE assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Which hosts are down?"),
+		{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{
+			ID: "t1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "list_hosts", Arguments: `{"state":"up"}`},
+		}}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "t1", Name: "list_hosts", Content: "web-1"}}},
+		llms.TextParts(llms.ChatMessageTypeHuman, "And the down ones?"),
+	}
+	tools := []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{
+		Name:        "list_hosts",
+		Description: "List hosts",
+		Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"state": map[string]interface{}{"type": "string"}}},
+	}}}
+
+	resp, err := model.GenerateContent(context.Background(), messages, llms.WithTools(tools), llms.WithMaxTokens(256))
+	if err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+
+	if gotPath != "/model/anthropic.claude-3-5-sonnet-20240620-v1:0/converse" {
+		t.Errorf("request path = %q", gotPath)
+	}
+	if system, _ := gotBody["system"].([]interface{}); len(system) != 1 {
+		t.Errorf("system = %v, want one block", gotBody["system"])
+	}
+	// The tool result and the following question are merged into one user turn
+	conversation, _ := gotBody["messages"].([]interface{})
+	var roles []string
+	for _, message := range conversation {
+		roles = append(roles, message.(map[string]interface{})["role"].(string))
+	}
+	if len(roles) != 3 || roles[0] != "user" || roles[1] != "assistant" || roles[2] != "user" {
+		t.Fatalf("roles = %v, want user, assistant, user", roles)
+	}
+	last := conversation[2].(map[string]interface{})["content"].([]interface{})
+	if _, ok := last[0].(map[string]interface{})["toolResult"]; !ok || len(last) != 2 {
+		t.Errorf("last turn = %v, want a tool result and the question", last)
+	}
+	if _, ok := gotBody["toolConfig"]; !ok {
+		t.Error("request has no toolConfig")
+	}
+	if inference, _ := gotBody["inferenceConfig"].(map[string]interface{}); inference["maxTokens"] != float64(256) {
+		t.Errorf("inferenceConfig = %v, want maxTokens 256", gotBody["inferenceConfig"])
+	}
+
+	choice := resp.Choices[0]
+	if choice.Content != "Checking hosts." || choice.StopReason != "tool_use" {
+		t.Errorf("choice = %q, stop reason %q", choice.Content, choice.StopReason)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Name != "list_hosts" || choice.ToolCalls[0].FunctionCall.Arguments != `{"state":"down"}` {
+		t.Errorf("tool calls = %+v", choice.ToolCalls)
+	}
+	if choice.GenerationInfo["PromptTokens"] != 10 || choice.GenerationInfo["CompletionTokens"] != 5 || choice.GenerationInfo["TotalTokens"] != 15 {
+		t.Errorf("generation info = %v", choice.GenerationInfo)
+	}
+}
+
+func TestConverseMessagesWithoutTools(t *testing.T) {
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Which hosts are down?"),
+		{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{
+			ID: "t1", FunctionCall: &llms.FunctionCall{Name: "list_hosts", Arguments: `{}`},
+		}}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "t1", Name: "list_hosts", Content: "db-1"}}},
+		llms.TextParts(llms.ChatMessageTypeHuman, "   "),
+	}
+	system, conversation, err := converseMessages(messages, false)
+	if err != nil {
+		t.Fatalf("converseMessages() error = %v", err)
+	}
+	if len(system) != 0 || len(conversation) != 3 {
+		t.Fatalf("got %d system blocks and %d turns, want 0 and 3", len(system), len(conversation))
+	}
+	if len(conversation[2].Content) != 1 {
+		t.Errorf("tool result turn has %d blocks, want 1 since blank text is dropped", len(conversation[2].Content))
+	}
+}
+
+func TestConverseMessagesLeadingAssistant(t *testing.T) {
+	// Trimmed history can start with the answer to a question that no longer fits
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "Be brief."),
+		llms.TextParts(llms.ChatMessageTypeAI, "All hosts are up."),
+		{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{
+			ID: "t1", FunctionCall: &llms.FunctionCall{Name: "list_hosts", Arguments: `{}`},
+		}}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "t1", Name: "list_hosts", Content: "db-1"}}},
+		llms.TextParts(llms.ChatMessageTypeAI, "db-1 is down."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Since when?"),
+	}
+	system, conversation, err := converseMessages(messages, true)
+	if err != nil {
+		t.Fatalf("converseMessages() error = %v", err)
+	}
+	if len(system) != 1 || len(conversation) != 1 {
+		t.Fatalf("got %d system blocks and %d turns, want 1 and 1", len(system), len(conversation))
+	}
+	if conversation[0].Role != types.ConversationRoleUser {
+		t.Errorf("first turn role = %q, want user", conversation[0].Role)
+	}
+	if text, ok := conversation[0].Content[0].(*types.ContentBlockMemberText); !ok || text.Value != "Since when?" {
+		t.Errorf("first turn = %#v, want the question", conversation[0].Content)
+	}
+
+	// Text sent alongside the results of dropped tool calls is kept
+	messages[3].Parts = append(messages[3].Parts, llms.TextContent{Text: "Which hosts are down?"})
+	_, conversation, err = converseMessages(messages, true)
+	if err != nil {
+		t.Fatalf("converseMessages() error = %v", err)
+	}
+	if len(conversation) != 3 || conversation[0].Role != types.ConversationRoleUser || len(conversation[0].Content) != 1 {
+		t.Fatalf("conversation = %#v, want the text of the first user turn without the tool result", conversation)
+	}
+	if _, ok := conversation[0].Content[0].(*types.ContentBlockMemberText); !ok {
+		t.Errorf("first turn = %#v, want text only", conversation[0].Content)
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bpeDownloadTimeout bounds the download of an encoding's BPE ranks
+const bpeDownloadTimeout = 30 * time.Second
+
+// bpeLoader loads tiktoken BPE ranks like the library's default loader, but with a download timeout.
+// Downloads are cached in TIKTOKEN_CACHE_DIR (default: the system temp dir) under the library's file
+// names, so air-gapped deployments can provide the files there.
+type bpeLoader struct {
+	client *http.Client
+}
+
+// LoadTiktokenBpe returns the ranks of a BPE file, read from the cache or downloaded
+func (l *bpeLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	contents, err := l.read(file)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		encoded, rank, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("invalid line in BPE file %s: %q", file, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token in BPE file %s: %w", file, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank in BPE file %s: %w", file, err)
+		}
+		ranks[string(token)] = n
+	}
+	return ranks, nil
+}
+
+// read returns a local file, or a URL from the cache, downloading it on a miss
+func (l *bpeLoader) read(file string) ([]byte, error) {
+	if !strings.HasPrefix(file, "http://") && !strings.HasPrefix(file, "https://") {
+		return os.ReadFile(file)
+	}
+	cacheDir := os.Getenv("TIKTOKEN_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "data-gym-cache")
+	}
+	cachePath := filepath.Join(cacheDir, fmt.Sprintf("%x", sha1.Sum([]byte(file))))
+	if contents, err := os.ReadFile(cachePath); err == nil {
+		return contents, nil
+	}
+
+	resp, err := l.client.Get(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download BPE file %s: %w", file, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download BPE file %s: %s", file, resp.Status)
+	}
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download BPE file %s: %w", file, err)
+	}
+
+	// The cache is best effort; a rename keeps concurrent readers from seeing partial files
+	if err := os.MkdirAll(cacheDir, 0755); err == nil {
+		if tmp, err := os.CreateTemp(cacheDir, filepath.Base(cachePath)+".*.tmp"); err == nil {
+			_, writeErr := tmp.Write(contents)
+			closeErr := tmp.Close()
+			if writeErr != nil || closeErr != nil || os.Rename(tmp.Name(), cachePath) != nil {
+				_ = os.Remove(tmp.Name())
+			}
+		}
+	}
+	return contents, nil
+}
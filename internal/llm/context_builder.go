@@ -0,0 +1,232 @@
+// Package llm provides implementations for language model providers
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContextSection identifies a logical part of the assembled LLM context
+type ContextSection string
+
+// Known context sections
+const (
+	SectionSystem  ContextSection = "system"
+	SectionTools   ContextSection = "tools"
+	SectionHistory ContextSection = "history"
+	SectionChannel ContextSection = "channel"
+	SectionRAG     ContextSection = "rag"
+	SectionUser    ContextSection = "user"
+)
+
+// TruncateStrategy controls which end of a part is kept when it must be shortened
+type TruncateStrategy int
+
+const (
+	// TruncateNone means the part is kept whole or dropped entirely
+	TruncateNone TruncateStrategy = iota
+	// TruncateKeepHead keeps the beginning of the content (e.g. search results ranked by relevance)
+	TruncateKeepHead
+	// TruncateKeepTail keeps the end of the content (e.g. the most recent conversation turns)
+	TruncateKeepTail
+)
+
+// Action names recorded in truncation decisions
+const (
+	ContextActionTruncated = "truncated"
+	ContextActionDropped   = "dropped"
+)
+
+// truncationMarker is inserted where content was removed
+const truncationMarker = "[... %d tokens truncated ...]"
+
+// ContextPart is a single piece of content competing for the token budget
+type ContextPart struct {
+	Section  ContextSection
+	Content  string
+	Priority int              // Higher priority parts are truncated or dropped last
+	Required bool             // Required parts are never dropped, only truncated as a last resort
+	Strategy TruncateStrategy // How the part may be shortened
+}
+
+// TruncationDecision records how a part was altered to fit the budget
+type TruncationDecision struct {
+	Section        ContextSection
+	Action         string // "truncated" or "dropped"
+	OriginalTokens int
+	KeptTokens     int
+}
+
+// ContextReport summarizes a context assembly and the provider calls made with it
+type ContextReport struct {
+	Budget         int
+	OriginalTokens int
+	FinalTokens    int
+	SectionTokens  map[ContextSection]int // Token count of each section after assembly
+	Decisions      []TruncationDecision
+	Attempts       []ProviderAttempt // Providers tried for the request, in order
+	CacheHit       bool              // The answer came from the response cache
+	Usage          TokenUsage        // Tokens used by the LLM calls of the request
+}
+
+// Truncated reports whether any part was truncated or dropped
+func (r *ContextReport) Truncated() bool {
+	return r != nil && len(r.Decisions) > 0
+}
+
+// ContextBuilder fits context parts into a token budget
+type ContextBuilder struct {
+	counter TokenCounter
+	budget  int
+}
+
+// NewContextBuilder creates a builder with the given token counter and budget.
+// A budget of zero or less disables truncation.
+func NewContextBuilder(counter TokenCounter, budget int) *ContextBuilder {
+	if counter == nil {
+		counter = approximateCounter{}
+	}
+	return &ContextBuilder{
+		counter: counter,
+		budget:  budget,
+	}
+}
+
+// Build fits the parts into the budget, shortening or dropping the lowest-priority parts first.
+// The returned parts keep their original order; dropped parts are omitted.
+func (b *ContextBuilder) Build(parts []ContextPart) ([]ContextPart, *ContextReport) {
+	tokens := make([]int, len(parts))
+	total := 0
+	for i, part := range parts {
+		tokens[i] = b.counter.CountTokens(part.Content)
+		total += tokens[i]
+	}
+
+	report := &ContextReport{
+		Budget:         b.budget,
+		OriginalTokens: total,
+		SectionTokens:  make(map[ContextSection]int),
+	}
+
+	result := make([]ContextPart, len(parts))
+	copy(result, parts)
+	dropped := make([]bool, len(parts))
+
+	if b.budget > 0 && total > b.budget {
+		// Visit parts from lowest to highest priority, optional parts before required ones
+		order := make([]int, len(parts))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(x, y int) bool {
+			px, py := parts[order[x]], parts[order[y]]
+			if px.Required != py.Required {
+				return !px.Required
+			}
+			return px.Priority < py.Priority
+		})
+
+		for _, i := range order {
+			if total <= b.budget {
+				break
+			}
+			if tokens[i] == 0 {
+				continue
+			}
+			excess := total - b.budget
+			part := result[i]
+
+			// Prefer shortening when enough of the part would survive to be useful
+			if part.Strategy != TruncateNone && (tokens[i] > excess*2 || part.Required) {
+				keep := tokens[i] - excess
+				if keep < 0 {
+					keep = 0
+				}
+				truncated := b.truncate(part.Content, keep, part.Strategy)
+				newTokens := b.counter.CountTokens(truncated)
+				report.Decisions = append(report.Decisions, TruncationDecision{
+					Section:        part.Section,
+					Action:         ContextActionTruncated,
+					OriginalTokens: tokens[i],
+					KeptTokens:     newTokens,
+				})
+				total += newTokens - tokens[i]
+				tokens[i] = newTokens
+				result[i].Content = truncated
+				continue
+			}
+
+			if !part.Required {
+				report.Decisions = append(report.Decisions, TruncationDecision{
+					Section:        part.Section,
+					Action:         ContextActionDropped,
+					OriginalTokens: tokens[i],
+				})
+				total -= tokens[i]
+				tokens[i] = 0
+				dropped[i] = true
+			}
+		}
+	}
+
+	kept := make([]ContextPart, 0, len(result))
+	for i, part := range result {
+		if dropped[i] {
+			continue
+		}
+		kept = append(kept, part)
+		report.SectionTokens[part.Section] += tokens[i]
+	}
+	report.FinalTokens = total
+
+	return kept, report
+}
+
+// truncate shortens content to roughly maxTokens using the given strategy,
+// cutting at line boundaries where possible and inserting a marker.
+func (b *ContextBuilder) truncate(content string, maxTokens int, strategy TruncateStrategy) string {
+	originalTokens := b.counter.CountTokens(content)
+	marker := fmt.Sprintf(truncationMarker, originalTokens)
+	target := maxTokens - b.counter.CountTokens(marker)
+	if target <= 0 {
+		return marker
+	}
+
+	runes := []rune(content)
+	// Binary search for the longest rune prefix/suffix that fits in the target
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if b.counter.CountTokens(slice(runes, mid, strategy)) <= target {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	kept := slice(runes, lo, strategy)
+
+	// Avoid leaving a partial line at the cut point
+	switch strategy {
+	case TruncateKeepHead:
+		if idx := strings.LastIndex(kept, "\n"); idx > len(kept)/2 {
+			kept = kept[:idx]
+		}
+		return strings.TrimRight(kept, "\n") + "\n" + marker
+	case TruncateKeepTail:
+		if idx := strings.Index(kept, "\n"); idx >= 0 && idx < len(kept)/2 {
+			kept = kept[idx+1:]
+		}
+		return marker + "\n" + strings.TrimLeft(kept, "\n")
+	default:
+		return kept
+	}
+}
+
+// slice returns the first or last n runes depending on the strategy
+func slice(runes []rune, n int, strategy TruncateStrategy) string {
+	if strategy == TruncateKeepTail {
+		return string(runes[len(runes)-n:])
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+// wordCounter counts whitespace-separated words, giving tests exact control over token counts
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func words(prefix string, n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = prefix
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestContextBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        int
+		parts         []ContextPart
+		wantSections  []ContextSection
+		wantDecisions map[ContextSection]string
+		maxTokens     int
+	}{
+		{
+			name:   "fits within budget",
+			budget: 100,
+			parts: []ContextPart{
+				{Section: SectionSystem, Content: words("sys", 5), Priority: 40, Required: true},
+				{Section: SectionUser, Content: words("user", 5), Priority: 50, Required: true},
+			},
+			wantSections:  []ContextSection{SectionSystem, SectionUser},
+			wantDecisions: map[ContextSection]string{},
+			maxTokens:     10,
+		},
+		{
+			name:   "history truncated before rag",
+			budget: 60,
+			parts: []ContextPart{
+				{Section: SectionSystem, Content: words("sys", 10), Priority: 40, Required: true},
+				{Section: SectionHistory, Content: words("hist", 40), Priority: 10, Strategy: TruncateKeepTail},
+				{Section: SectionRAG, Content: words("rag", 20), Priority: 20, Strategy: TruncateKeepHead},
+				{Section: SectionUser, Content: words("user", 5), Priority: 50, Required: true},
+			},
+			wantSections:  []ContextSection{SectionSystem, SectionHistory, SectionRAG, SectionUser},
+			wantDecisions: map[ContextSection]string{SectionHistory: ContextActionTruncated},
+			maxTokens:     60,
+		},
+		{
+			name:   "low priority part dropped when little would remain",
+			budget: 20,
+			parts: []ContextPart{
+				{Section: SectionSystem, Content: words("sys", 10), Priority: 40, Required: true},
+				{Section: SectionTools, Content: words("tool", 30), Priority: 30},
+				{Section: SectionUser, Content: words("user", 5), Priority: 50, Required: true},
+			},
+			wantSections:  []ContextSection{SectionSystem, SectionUser},
+			wantDecisions: map[ContextSection]string{SectionTools: ContextActionDropped},
+			maxTokens:     20,
+		},
+		{
+			name:   "required part truncated as last resort",
+			budget: 20,
+			parts: []ContextPart{
+				{Section: SectionSystem, Content: words("sys", 5), Priority: 40, Required: true},
+				{Section: SectionUser, Content: words("user", 50), Priority: 50, Required: true, Strategy: TruncateKeepHead},
+			},
+			wantSections:  []ContextSection{SectionSystem, SectionUser},
+			wantDecisions: map[ContextSection]string{SectionUser: ContextActionTruncated},
+			maxTokens:     20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewContextBuilder(wordCounter{}, tt.budget)
+			parts, report := builder.Build(tt.parts)
+
+			if len(parts) != len(tt.wantSections) {
+				t.Fatalf("Build() returned %d parts, want %d", len(parts), len(tt.wantSections))
+			}
+			for i, section := range tt.wantSections {
+				if parts[i].Section != section {
+					t.Errorf("part %d section = %s, want %s", i, parts[i].Section, section)
+				}
+			}
+
+			if len(report.Decisions) != len(tt.wantDecisions) {
+				t.Fatalf("Build() made %d decisions, want %d: %+v", len(report.Decisions), len(tt.wantDecisions), report.Decisions)
+			}
+			for _, decision := range report.Decisions {
+				if want := tt.wantDecisions[decision.Section]; want != decision.Action {
+					t.Errorf("decision for %s = %s, want %s", decision.Section, decision.Action, want)
+				}
+			}
+
+			if report.FinalTokens > tt.maxTokens {
+				t.Errorf("FinalTokens = %d, want <= %d", report.FinalTokens, tt.maxTokens)
+			}
+		})
+	}
+}
+
+func TestContextBuilder_TruncateKeepsMostRecentHistory(t *testing.T) {
+	history := "oldest message\nolder message\nrecent message\nnewest message"
+	builder := NewContextBuilder(wordCounter{}, 7)
+
+	parts, _ := builder.Build([]ContextPart{
+		{Section: SectionHistory, Content: history, Priority: 10, Strategy: TruncateKeepTail},
+	})
+
+	got := parts[0].Content
+	if !strings.HasSuffix(got, "newest message") {
+		t.Errorf("truncated history should keep the newest message, got %q", got)
+	}
+	if strings.Contains(got, "oldest") {
+		t.Errorf("truncated history should drop the oldest message, got %q", got)
+	}
+}
+
+func TestModelContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"claude-3-5-sonnet-20241022", 200000},
+		{"llama3.1:70b", 131072},
+		{"llama3", 8192},
+		{"us.anthropic.claude-3-5-sonnet-20241022-v2:0", 200000},
+		{"meta.llama3-1-70b-instruct-v1:0", 131072},
+		{"amazon.titan-text-premier-v1:0", 32000},
+		{"gpt-4.1", 1047576},
+		{"some-unknown-model", defaultContextWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ModelContextWindow(tt.model); got != tt.want {
+				t.Errorf("ModelContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/monitoring"
+)
+
+// Outcomes of a provider attempt
+const (
+	AttemptSuccess = "success"
+	AttemptFailed  = "failed"
+	AttemptSkipped = "skipped" // Unavailable, or its circuit was open
+)
+
+// errCircuitOpen is recorded for providers skipped during their cool-down
+var errCircuitOpen = errors.New("circuit open after repeated failures")
+
+// ProviderAttempt records one provider of a chain tried for a request
+type ProviderAttempt struct {
+	Provider string
+	Outcome  string
+	Err      error
+	Duration time.Duration
+}
+
+// AnsweredBy returns the provider that answered, or "" if none did
+func AnsweredBy(attempts []ProviderAttempt) string {
+	for _, attempt := range attempts {
+		if attempt.Outcome == AttemptSuccess {
+			return attempt.Provider
+		}
+	}
+	return ""
+}
+
+// statusCodePattern finds the HTTP status in provider errors such as "API returned unexpected status code: 429"
+// or, from AWS, "https response error StatusCode: 429"
+var statusCodePattern = regexp.MustCompile(`(?i)status ?code:? (\d{3})`)
+
+// retryableMessages mark errors of overloaded or unreachable providers that carry no status code
+var retryableMessages = []string{"rate limit", "overloaded", "timeout", "timed out", "connection refused", "connection reset", "unavailable"}
+
+// IsRetryableError reports whether another provider may succeed where this error failed:
+// timeouts, network errors, rate limits and server errors. Canceled requests and other
+// client errors are not retryable, since every provider would reject them too.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var llmErr *llms.Error
+	if errors.As(err, &llmErr) {
+		switch llmErr.Code {
+		case llms.ErrCodeRateLimit, llms.ErrCodeTimeout, llms.ErrCodeProviderUnavailable, llms.ErrCodeQuotaExceeded:
+			return true
+		case llms.ErrCodeAuthentication, llms.ErrCodeInvalidRequest, llms.ErrCodeContentFilter,
+			llms.ErrCodeTokenLimit, llms.ErrCodeCanceled, llms.ErrCodeResourceNotFound:
+			return false
+		}
+	}
+
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return status == 408 || status == 429 || status >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, marker := range retryableMessages {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreaker skips a provider for a cool-down after consecutive failures.
+// Once the cool-down is over, a single probe call decides whether it closes again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+// newCircuitBreaker creates a closed breaker
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether the provider may be called now
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// RecordSuccess closes the breaker
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// RecordFailure counts a failure and reports whether the breaker is now open
+func (b *circuitBreaker) RecordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = b.now().Add(b.cooldown)
+	return true
+}
+
+// Release ends a probe without a verdict, e.g. when the request was canceled
+func (b *circuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// breaker returns the circuit breaker of a provider, creating it on first use
+func (r *ProviderRegistry) breaker(name string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.breakers == nil {
+		r.breakers = make(map[string]*circuitBreaker)
+	}
+	breaker, exists := r.breakers[name]
+	if !exists {
+		breaker = newCircuitBreaker(r.failureThreshold, r.cooldown)
+		r.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// failover calls the providers of the chain in order until one answers. Providers that are unavailable or
+// cooling down are skipped, and a retryable error moves on to the next provider; other errors are returned
+// as they are. When every circuit is open, the first of those providers is tried anyway.
+//
+// sideEffects is set for calls that act beyond the provider, such as agent runs calling tools. Those calls
+// get no per-attempt timeout, and once sideEffects reports true a failure is returned instead of repeated.
+func (r *ProviderRegistry) failover(ctx context.Context, chain []string, sideEffects func() bool,
+	call func(ctx context.Context, name string, provider LLMProvider) error) ([]ProviderAttempt, error) {
+	if len(chain) == 0 {
+		r.mu.RLock()
+		chain = []string{r.primary}
+		r.mu.RUnlock()
+	}
+
+	var attempts []ProviderAttempt
+	var lastErr error
+	var open []string
+	called := false
+	for i, name := range chain {
+		provider, err := r.GetProviderWithAvailabilityCheck(name)
+		if err != nil {
+			attempts = append(attempts, r.skip(name, err))
+			lastErr = err
+			continue
+		}
+		if !r.breaker(name).Allow() {
+			attempts = append(attempts, r.skip(name, errCircuitOpen))
+			open = append(open, name)
+			continue
+		}
+
+		called = true
+		attempt, retryable := r.attempt(ctx, name, provider, i < len(chain)-1 && sideEffects == nil, call)
+		attempts = append(attempts, attempt)
+		if attempt.Err == nil {
+			return attempts, nil
+		}
+		lastErr = attempt.Err
+		if !retryable {
+			return attempts, lastErr
+		}
+		if sideEffects != nil && sideEffects() {
+			r.logger.WarnKV("LLM provider failed after tools were called, not repeating them with another provider", "provider", name, "error", attempt.Err)
+			return attempts, lastErr
+		}
+		if i < len(chain)-1 {
+			r.logger.WarnKV("LLM provider failed, trying the next provider", "provider", name, "error", attempt.Err)
+		}
+	}
+
+	// Every usable provider is cooling down; trying one beats failing without a call
+	if !called && len(open) > 0 {
+		r.logger.WarnKV("All LLM provider circuits are open, trying the first provider anyway", "provider", open[0])
+		provider, err := r.GetProvider(open[0])
+		if err != nil {
+			return attempts, err
+		}
+		attempt, _ := r.attempt(ctx, open[0], provider, false, call)
+		attempts = append(attempts, attempt)
+		return attempts, attempt.Err
+	}
+
+	if lastErr == nil {
+		lastErr = customErrors.NewLLMError("no_provider", "no LLM provider available")
+	}
+	return attempts, lastErr
+}
+
+// attempt makes one call and updates the provider's breaker. With timeout set, the call gets its own
+// deadline, so a hanging provider leaves time for the fallback.
+func (r *ProviderRegistry) attempt(ctx context.Context, name string, provider LLMProvider, timeout bool,
+	call func(ctx context.Context, name string, provider LLMProvider) error) (ProviderAttempt, bool) {
+	attemptCtx := ctx
+	if timeout && r.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, r.attemptTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := call(attemptCtx, name, provider)
+	attempt := ProviderAttempt{Provider: name, Outcome: AttemptSuccess, Err: err, Duration: time.Since(start)}
+	breaker := r.breaker(name)
+
+	retryable := false
+	switch {
+	case err == nil:
+		breaker.RecordSuccess()
+		monitoring.LLMCircuitOpen.With(prometheus.Labels{monitoring.MetricLabelProvider: name}).Set(0)
+	case ctx.Err() != nil:
+		// The request was canceled or ran out of time, which says nothing about the provider
+		attempt.Outcome = AttemptFailed
+		breaker.Release()
+	case IsRetryableError(err):
+		attempt.Outcome = AttemptFailed
+		retryable = true
+		if breaker.RecordFailure() {
+			r.logger.WarnKV("LLM provider circuit opened, skipping it during the cool-down", "provider", name, "cooldown", r.cooldown)
+			monitoring.LLMCircuitOpen.With(prometheus.Labels{monitoring.MetricLabelProvider: name}).Set(1)
+		}
+	default:
+		// The provider answered, it just rejected this request
+		attempt.Outcome = AttemptFailed
+		breaker.RecordSuccess()
+	}
+	r.recordAttempt(attempt)
+	return attempt, retryable
+}
+
+// skip records a provider that was not called
+func (r *ProviderRegistry) skip(name string, err error) ProviderAttempt {
+	attempt := ProviderAttempt{Provider: name, Outcome: AttemptSkipped, Err: err}
+	r.logger.DebugKV("Skipping LLM provider", "provider", name, "reason", err)
+	r.recordAttempt(attempt)
+	return attempt
+}
+
+// recordAttempt counts the attempt in the provider metrics
+func (r *ProviderRegistry) recordAttempt(attempt ProviderAttempt) {
+	monitoring.LLMProviderRequests.With(prometheus.Labels{
+		monitoring.MetricLabelProvider: attempt.Provider,
+		monitoring.MetricLabelOutcome:  attempt.Outcome,
+	}).Inc()
+}
+
+// GenerateContentWithFailover generates a completion with the first provider of the chain that answers.
+// optionsFor returns the options for each provider, since temperature and token limits are per provider.
+func (r *ProviderRegistry) GenerateContentWithFailover(ctx context.Context, chain []string, messages []llms.MessageContent,
+	optionsFor func(provider string) ProviderOptions) (*llms.ContentChoice, []ProviderAttempt, error) {
+	var choice *llms.ContentChoice
+	attempts, err := r.failover(ctx, chain, nil, func(ctx context.Context, name string, provider LLMProvider) error {
+		r.logger.DebugKV("Using provider for content generation", "name", name, "num_messages", len(messages))
+		var err error
+		choice, err = provider.GenerateContent(ctx, messages, optionsFor(name))
+		return err
+	})
+	return choice, attempts, err
+}
+
+// GenerateAgentCompletionWithFailover runs the agent with the first provider of the chain that answers.
+// Agent runs are bounded by ctx only, since they include tool calls. Once the agent has called a tool,
+// a failure is returned rather than retried with the next provider, so tools never run twice.
+// optionsFor returns the options for each provider, as for GenerateContentWithFailover.
+func (r *ProviderRegistry) GenerateAgentCompletionWithFailover(ctx context.Context, chain []string, userDisplayName, systemPrompt string, prompt string,
+	messages []RequestMessage, history []HistoryMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int,
+	optionsFor func(provider string) ProviderOptions) (string, []ProviderAttempt, error) {
+	var toolsRan atomic.Bool
+	llmTools = trackedTools(llmTools, &toolsRan)
+	var completion string
+	attempts, err := r.failover(ctx, chain, toolsRan.Load, func(ctx context.Context, name string, provider LLMProvider) error {
+		r.logger.DebugKV("Using provider for agent completion", "name", name)
+		var err error
+		completion, err = provider.GenerateAgentCompletion(ctx, userDisplayName, systemPrompt, prompt, messages, history, llmTools, callbackHandler, maxAgentIterations, optionsFor(name))
+		return err
+	})
+	return completion, attempts, err
+}
+
+// trackedTool records that the agent called a tool
+type trackedTool struct {
+	tools.Tool
+	called *atomic.Bool
+}
+
+func (t trackedTool) Call(ctx context.Context, input string) (string, error) {
+	t.called.Store(true)
+	return t.Tool.Call(ctx, input)
+}
+
+// Parameters keeps the schema of the wrapped tool for native tool calling
+func (t trackedTool) Parameters() map[string]any {
+	if parameterized, ok := t.Tool.(parameterizedTool); ok {
+		return parameterized.Parameters()
+	}
+	return nil
+}
+
+// trackedTools wraps agent tools so that called is set once any of them runs
+func trackedTools(llmTools []tools.Tool, called *atomic.Bool) []tools.Tool {
+	wrapped := make([]tools.Tool, len(llmTools))
+	for i, tool := range llmTools {
+		wrapped[i] = trackedTool{Tool: tool, called: called}
+	}
+	return wrapped
+}
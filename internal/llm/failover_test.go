@@ -0,0 +1,258 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+// fakeProvider answers with its answer or name, or fails with err. With callTools set, agent runs
+// call every tool before answering.
+type fakeProvider struct {
+	name        string
+	answer      string
+	err         error
+	calls       int
+	callTools   bool
+	hadDeadline bool // Whether the last call's context had a deadline
+}
+
+func (p *fakeProvider) GenerateCompletion(ctx context.Context, prompt string, options ProviderOptions) (*llms.ContentChoice, error) {
+	return p.GenerateContent(ctx, nil, options)
+}
+
+func (p *fakeProvider) GenerateChatCompletion(ctx context.Context, messages []RequestMessage, options ProviderOptions) (*llms.ContentChoice, error) {
+	return p.GenerateContent(ctx, nil, options)
+}
+
+func (p *fakeProvider) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ProviderOptions) (*llms.ContentChoice, error) {
+	p.calls++
+	_, p.hadDeadline = ctx.Deadline()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.answer != "" {
+		return &llms.ContentChoice{Content: p.answer}, nil
+	}
+	return &llms.ContentChoice{Content: p.name}, nil
+}
+
+func (p *fakeProvider) GenerateAgentCompletion(ctx context.Context, userDisplayName, systemPrompt string, prompt string, messages []RequestMessage,
+	history []HistoryMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int, options ProviderOptions) (string, error) {
+	if p.callTools {
+		for _, tool := range llmTools {
+			if _, err := tool.Call(ctx, "{}"); err != nil {
+				return "", err
+			}
+		}
+	}
+	choice, err := p.GenerateContent(ctx, nil, ProviderOptions{})
+	if err != nil {
+		return "", err
+	}
+	return choice.Content, nil
+}
+
+func (p *fakeProvider) GetInfo() ProviderInfo { return ProviderInfo{Name: p.name} }
+
+func (p *fakeProvider) IsAvailable() bool { return true }
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), true},
+		{"canceled", fmt.Errorf("call: %w", context.Canceled), false},
+		{"rate limit status", errors.New("API returned unexpected status code: 429: slow down"), true},
+		{"server error status", errors.New("API returned unexpected status code: 503"), true},
+		{"overloaded status", errors.New("API returned unexpected status code: 529"), true},
+		{"bad request status", errors.New("API returned unexpected status code: 400: invalid tools"), false},
+		{"aws throttling", errors.New("operation error Bedrock Runtime: Converse, https response error StatusCode: 429, RequestID: 1, ThrottlingException: slow down"), true},
+		{"aws validation", errors.New("operation error Bedrock Runtime: Converse, https response error StatusCode: 400, RequestID: 1, ValidationException: bad input"), false},
+		{"auth status", errors.New("API returned unexpected status code: 401"), false},
+		{"langchaingo rate limit", llms.NewError(llms.ErrCodeRateLimit, "openai", "too many requests"), true},
+		{"langchaingo auth", llms.NewError(llms.ErrCodeAuthentication, "openai", "invalid api key"), false},
+		{"overloaded message", errors.New("anthropic: Overloaded"), true},
+		{"connection refused", errors.New("dial tcp 127.0.0.1:11434: connect: connection refused"), true},
+		{"other", errors.New("invalid JSON in response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	if breaker.RecordFailure() {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	if !breaker.Allow() {
+		t.Fatal("breaker rejected a call below the threshold")
+	}
+	if !breaker.RecordFailure() {
+		t.Fatal("breaker did not open at the threshold")
+	}
+	if breaker.Allow() {
+		t.Fatal("open breaker allowed a call during the cool-down")
+	}
+
+	now = now.Add(time.Minute)
+	if !breaker.Allow() {
+		t.Fatal("breaker did not allow a probe after the cool-down")
+	}
+	if breaker.Allow() {
+		t.Fatal("breaker allowed a second call while probing")
+	}
+	if !breaker.RecordFailure() {
+		t.Fatal("failed probe did not reopen the breaker")
+	}
+	if breaker.Allow() {
+		t.Fatal("breaker allowed a call after a failed probe")
+	}
+
+	now = now.Add(time.Minute)
+	if !breaker.Allow() {
+		t.Fatal("breaker did not allow a second probe")
+	}
+	breaker.RecordSuccess()
+	if !breaker.Allow() || !breaker.Allow() {
+		t.Fatal("breaker stayed open after a successful probe")
+	}
+}
+
+func TestGenerateContentWithFailover(t *testing.T) {
+	rateLimited := errors.New("API returned unexpected status code: 429")
+	badRequest := errors.New("API returned unexpected status code: 400")
+
+	tests := []struct {
+		name         string
+		errs         map[string]error
+		chain        []string
+		want         string
+		wantErr      error
+		wantOutcomes []string
+	}{
+		{"primary answers", nil, []string{"openai", "anthropic"}, "openai", nil,
+			[]string{AttemptSuccess}},
+		{"retryable error falls back", map[string]error{"openai": rateLimited}, []string{"openai", "anthropic"}, "anthropic", nil,
+			[]string{AttemptFailed, AttemptSuccess}},
+		{"other errors do not fall back", map[string]error{"openai": badRequest}, []string{"openai", "anthropic"}, "", badRequest,
+			[]string{AttemptFailed}},
+		{"unknown provider is skipped", nil, []string{"bedrock", "anthropic"}, "anthropic", nil,
+			[]string{AttemptSkipped, AttemptSuccess}},
+		{"last error is returned", map[string]error{"openai": rateLimited, "anthropic": rateLimited}, []string{"openai", "anthropic"}, "", rateLimited,
+			[]string{AttemptFailed, AttemptFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry(tt.errs, 3)
+			choice, attempts, err := r.GenerateContentWithFailover(context.Background(), tt.chain, nil, func(string) ProviderOptions { return ProviderOptions{} })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && choice.Content != tt.want {
+				t.Errorf("answered by %q, want %q", choice.Content, tt.want)
+			}
+			if AnsweredBy(attempts) != tt.want {
+				t.Errorf("AnsweredBy = %q, want %q", AnsweredBy(attempts), tt.want)
+			}
+			if len(attempts) != len(tt.wantOutcomes) {
+				t.Fatalf("attempts = %+v, want outcomes %v", attempts, tt.wantOutcomes)
+			}
+			for i, attempt := range attempts {
+				if attempt.Outcome != tt.wantOutcomes[i] {
+					t.Errorf("attempt %d outcome = %q, want %q", i, attempt.Outcome, tt.wantOutcomes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFailoverSkipsOpenCircuit(t *testing.T) {
+	r := newTestRegistry(map[string]error{"openai": errors.New("API returned unexpected status code: 503")}, 1)
+	primary := r.providers["openai"].(*fakeProvider)
+	chain := []string{"openai", "anthropic"}
+	options := func(string) ProviderOptions { return ProviderOptions{} }
+
+	// The first failure opens the circuit, so the next request goes straight to the fallback
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.GenerateContentWithFailover(context.Background(), chain, nil, options); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+	if primary.calls != 1 {
+		t.Errorf("primary called %d times, want 1", primary.calls)
+	}
+
+	// With every circuit open the first provider is still tried
+	r.providers["anthropic"].(*fakeProvider).err = errors.New("API returned unexpected status code: 503")
+	r.breaker("anthropic").RecordFailure()
+	_, attempts, _ := r.GenerateContentWithFailover(context.Background(), chain, nil, options)
+	if primary.calls != 2 || attempts[len(attempts)-1].Provider != "openai" {
+		t.Errorf("primary calls = %d, attempts = %+v", primary.calls, attempts)
+	}
+}
+
+func TestAgentFailoverAfterTools(t *testing.T) {
+	unavailable := errors.New("API returned unexpected status code: 503")
+	options := func(string) ProviderOptions { return ProviderOptions{} }
+	chain := []string{"openai", "anthropic"}
+
+	// Agent runs are bounded by the request only, and fall back while no tool has run
+	r := newTestRegistry(map[string]error{"openai": unavailable}, 3)
+	tool := &fakeAgentTool{name: "deploy"}
+	answer, attempts, err := r.GenerateAgentCompletionWithFailover(context.Background(), chain, "Alice", "", "deploy it", nil, nil,
+		[]tools.Tool{tool}, callbacks.SimpleHandler{}, 5, options)
+	if err != nil || answer != "anthropic" || len(attempts) != 2 {
+		t.Fatalf("answer = %q, attempts = %+v, err = %v, want the fallback's answer", answer, attempts, err)
+	}
+	if r.providers["openai"].(*fakeProvider).hadDeadline {
+		t.Error("agent run got the per-attempt timeout")
+	}
+
+	// Once a tool has run, the failure is returned instead of running the tools again
+	r = newTestRegistry(map[string]error{"openai": unavailable}, 3)
+	r.providers["openai"].(*fakeProvider).callTools = true
+	r.providers["anthropic"].(*fakeProvider).callTools = true
+	_, attempts, err = r.GenerateAgentCompletionWithFailover(context.Background(), chain, "Alice", "", "deploy it", nil, nil,
+		[]tools.Tool{tool}, callbacks.SimpleHandler{}, 5, options)
+	if !errors.Is(err, unavailable) || len(attempts) != 1 {
+		t.Errorf("attempts = %+v, err = %v, want the primary's error without a fallback", attempts, err)
+	}
+	if len(tool.inputs) != 1 || r.providers["anthropic"].(*fakeProvider).calls != 0 {
+		t.Errorf("tool called %d times and fallback %d times, want 1 and 0", len(tool.inputs), r.providers["anthropic"].(*fakeProvider).calls)
+	}
+}
+
+// newTestRegistry creates a registry of fake openai and anthropic providers
+func newTestRegistry(errs map[string]error, threshold int) *ProviderRegistry {
+	return &ProviderRegistry{
+		providers: map[string]LLMProvider{
+			"openai":    &fakeProvider{name: "openai", err: errs["openai"]},
+			"anthropic": &fakeProvider{name: "anthropic", err: errs["anthropic"]},
+		},
+		primary:          "openai",
+		logger:           logging.New("test", logging.LevelError),
+		failureThreshold: threshold,
+		cooldown:         time.Minute,
+		attemptTimeout:   time.Second,
+	}
+}
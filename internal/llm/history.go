@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// HistoryRole is the role of a conversation turn
+type HistoryRole string
+
+// Conversation turn roles
+const (
+	HistoryRoleUser      HistoryRole = "user"
+	HistoryRoleAssistant HistoryRole = "assistant" // An answer, or a tool call when ToolCall is set
+	HistoryRoleTool      HistoryRole = "tool"      // The result of a tool call
+)
+
+// HistoryMessage is one turn of a conversation, shared by the agent and non-agent paths.
+// A tool call and its result are linked by the call's ID.
+type HistoryMessage struct {
+	ID         string // Unique within the conversation, e.g. the Slack message timestamp
+	Role       HistoryRole
+	Author     string // Who wrote a user turn, shown to the model (optional)
+	Content    string
+	ToolCall   *HistoryToolCall // Tool called by an assistant turn
+	ToolCallID string           // ID of the call a tool turn is the result of
+	ToolName   string           // Tool that produced a tool turn
+}
+
+// HistoryToolCall is a tool call made by the model
+type HistoryToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON-encoded arguments
+}
+
+// MessageContents converts history into chat turns. With nativeTools, each tool call whose result is in the
+// history becomes a tool-call turn followed by a tool-result turn, one call per turn, which every provider
+// accepts. Other tool calls and results, such as text-based calls or a call whose result was cut from the
+// history, are sent as text so the request stays valid.
+func MessageContents(history []HistoryMessage, nativeTools bool) []llms.MessageContent {
+	calls := make(map[string]bool)
+	answered := make(map[string]bool)
+	for _, msg := range history {
+		if msg.ToolCall != nil && msg.ToolCall.ID != "" {
+			calls[msg.ToolCall.ID] = true
+		}
+	}
+	for _, msg := range history {
+		if msg.Role == HistoryRoleTool && calls[msg.ToolCallID] {
+			answered[msg.ToolCallID] = true
+		}
+	}
+
+	contents := make([]llms.MessageContent, 0, len(history))
+	for _, msg := range history {
+		switch {
+		case msg.Role == HistoryRoleAssistant && msg.ToolCall != nil:
+			call := msg.ToolCall
+			if !nativeTools || !answered[call.ID] {
+				text := msg.Content
+				if text == "" {
+					text = fmt.Sprintf("Calling tool '%s' with %s", call.Name, call.Arguments)
+				}
+				contents = append(contents, llms.TextParts(llms.ChatMessageTypeAI, text))
+				continue
+			}
+			if msg.Content != "" {
+				contents = append(contents, llms.TextParts(llms.ChatMessageTypeAI, msg.Content))
+			}
+			contents = append(contents, llms.MessageContent{
+				Role: llms.ChatMessageTypeAI,
+				Parts: []llms.ContentPart{llms.ToolCall{
+					ID:           call.ID,
+					Type:         "function",
+					FunctionCall: &llms.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+				}},
+			})
+		case msg.Role == HistoryRoleAssistant:
+			contents = append(contents, llms.TextParts(llms.ChatMessageTypeAI, msg.Content))
+		case msg.Role == HistoryRoleTool:
+			if !nativeTools || !answered[msg.ToolCallID] {
+				contents = append(contents, llms.TextParts(llms.ChatMessageTypeHuman, toolResultText(msg.ToolName, msg.Content)))
+				continue
+			}
+			contents = append(contents, llms.MessageContent{
+				Role:  llms.ChatMessageTypeTool,
+				Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: msg.ToolCallID, Name: msg.ToolName, Content: msg.Content}},
+			})
+		default:
+			contents = append(contents, llms.TextParts(llms.ChatMessageTypeHuman, userText(msg)))
+		}
+	}
+	return contents
+}
+
+// ChatMessages converts history into langchaingo chat messages, e.g. for agent memory.
+// Tool calls and results are kept as text, since agents replay them in their own format.
+func ChatMessages(history []HistoryMessage) []llms.ChatMessage {
+	messages := make([]llms.ChatMessage, 0, len(history))
+	for _, msg := range history {
+		switch msg.Role {
+		case HistoryRoleAssistant:
+			text := msg.Content
+			if msg.ToolCall != nil && text == "" {
+				text = fmt.Sprintf("Calling tool '%s' with %s", msg.ToolCall.Name, msg.ToolCall.Arguments)
+			}
+			messages = append(messages, llms.AIChatMessage{Content: text})
+		case HistoryRoleTool:
+			messages = append(messages, llms.ToolChatMessage{ID: msg.ToolCallID, Content: toolResultText(msg.ToolName, msg.Content)})
+		default:
+			messages = append(messages, llms.HumanChatMessage{Content: userText(msg)})
+		}
+	}
+	return messages
+}
+
+// userText prefixes a user turn with its author, so the model can tell the people in a thread apart
+func userText(msg HistoryMessage) string {
+	if msg.Author == "" {
+		return msg.Content
+	}
+	return msg.Author + ": " + msg.Content
+}
+
+// toolResultText renders a tool result sent as text
+func toolResultText(toolName, content string) string {
+	return fmt.Sprintf("Result of tool '%s':\n```\n%s\n```", toolName, content)
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestMessageContents(t *testing.T) {
+	history := []HistoryMessage{
+		{ID: "1.0001", Role: HistoryRoleUser, Author: "Alice", Content: "which hosts are down?"},
+		{ID: "msg-1", Role: HistoryRoleAssistant, Content: "Checking.", ToolCall: &HistoryToolCall{ID: "call_1", Name: "list_hosts", Arguments: `{"state":"down"}`}},
+		{ID: "msg-2", Role: HistoryRoleTool, ToolCallID: "call_1", ToolName: "list_hosts", Content: "db-1"},
+		{ID: "msg-3", Role: HistoryRoleTool, ToolCallID: "call_0", ToolName: "ping", Content: "timeout"}, // Its call was cut from the history
+		{ID: "msg-4", Role: HistoryRoleAssistant, Content: "db-1 is down."},
+	}
+
+	roles := func(contents []llms.MessageContent) []llms.ChatMessageType {
+		var got []llms.ChatMessageType
+		for _, content := range contents {
+			got = append(got, content.Role)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name        string
+		nativeTools bool
+		wantRoles   []llms.ChatMessageType
+	}{
+		{"native tools", true, []llms.ChatMessageType{
+			llms.ChatMessageTypeHuman, llms.ChatMessageTypeAI, llms.ChatMessageTypeAI, llms.ChatMessageTypeTool,
+			llms.ChatMessageTypeHuman, llms.ChatMessageTypeAI,
+		}},
+		{"text tools", false, []llms.ChatMessageType{
+			llms.ChatMessageTypeHuman, llms.ChatMessageTypeAI, llms.ChatMessageTypeHuman,
+			llms.ChatMessageTypeHuman, llms.ChatMessageTypeAI,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := MessageContents(history, tt.nativeTools)
+			got := roles(contents)
+			if len(got) != len(tt.wantRoles) {
+				t.Fatalf("roles = %v, want %v", got, tt.wantRoles)
+			}
+			for i := range got {
+				if got[i] != tt.wantRoles[i] {
+					t.Fatalf("roles = %v, want %v", got, tt.wantRoles)
+				}
+			}
+			if text := contents[0].Parts[0].(llms.TextContent).Text; text != "Alice: which hosts are down?" {
+				t.Errorf("user turn = %q", text)
+			}
+		})
+	}
+
+	contents := MessageContents(history, true)
+	call, ok := contents[2].Parts[0].(llms.ToolCall)
+	if !ok || call.ID != "call_1" || call.FunctionCall.Name != "list_hosts" || call.FunctionCall.Arguments != `{"state":"down"}` {
+		t.Errorf("tool call turn = %+v", contents[2].Parts[0])
+	}
+	if result, ok := contents[3].Parts[0].(llms.ToolCallResponse); !ok || result.ToolCallID != "call_1" || result.Content != "db-1" {
+		t.Errorf("tool result turn = %+v", contents[3].Parts[0])
+	}
+	// A result without its call must not reference an unknown call ID
+	if text, ok := contents[4].Parts[0].(llms.TextContent); !ok || !strings.Contains(text.Text, "Result of tool 'ping'") {
+		t.Errorf("orphaned tool result = %+v", contents[4].Parts[0])
+	}
+}
@@ -26,6 +26,7 @@ type LangChainProvider struct {
 	llm          llms.Model
 	providerType string // The underlying provider type (e.g., "openai", "ollama")
 	modelName    string // The specific model configured (e.g., "gpt-4o", "llama3")
+	agentMode    string // AgentModeReAct or AgentModeNative
 	logger       *logging.Logger
 }
 
@@ -52,6 +53,8 @@ func init() {
 	RegisterLangChainModelFactory(ProviderTypeOpenAI, &OpenAIModelFactory{})
 	RegisterLangChainModelFactory(ProviderTypeOllama, &OllamaModelFactory{})
 	RegisterLangChainModelFactory(ProviderTypeAnthropic, &AnthropicModelFactory{})
+	RegisterLangChainModelFactory(ProviderTypeBedrock, &BedrockModelFactory{})
+	RegisterLangChainModelFactory(ProviderTypeAzureOpenAI, &AzureOpenAIModelFactory{})
 }
 
 // RegisterLangChainModelFactory registers a new model factory for the given provider type
@@ -104,29 +107,40 @@ func NewLangChainProviderFactory(config map[string]interface{}, logger *logging.
 		return nil, fmt.Errorf("failed to initialize langchain %s client: %w", underlyingProviderType, err)
 	}
 
+	agentMode, _ := config["agent_mode"].(string)
+	if agentMode == "" {
+		agentMode = AgentModeReAct
+	}
+
 	return &LangChainProvider{
 		llm:          llmClient,
 		providerType: underlyingProviderType,
 		modelName:    modelName,
+		agentMode:    agentMode,
 		logger:       providerLogger, // Assign the named logger
 	}, nil
 }
 
 // GenerateCompletion generates a completion using LangChainGo
 func (p *LangChainProvider) GenerateCompletion(ctx context.Context, prompt string, options ProviderOptions) (*llms.ContentChoice, error) {
-	if p.llm == nil {
-		return nil, errors.NewLLMError("client_not_initialized", "LangChainGo client not initialized")
-	}
-
 	p.logger.DebugKV("Calling LangChainGo GenerateCompletion", "prompt_length", len(prompt))
-	callOptions := p.buildOptions(options)
 
 	msg := llms.MessageContent{
 		Role:  llms.ChatMessageTypeHuman,
 		Parts: []llms.ContentPart{llms.TextContent{Text: prompt}},
 	}
+	return p.GenerateContent(ctx, []llms.MessageContent{msg}, options)
+}
 
-	resp, err := p.llm.GenerateContent(ctx, []llms.MessageContent{msg}, callOptions...)
+// GenerateContent generates a completion for typed messages using LangChainGo
+func (p *LangChainProvider) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ProviderOptions) (*llms.ContentChoice, error) {
+	if p.llm == nil {
+		return nil, errors.NewLLMError("client_not_initialized", "LangChainGo client not initialized")
+	}
+
+	callOptions := p.buildOptions(options)
+
+	resp, err := countUsage(ctx, p.llm).GenerateContent(ctx, messages, callOptions...)
 	if err != nil {
 		p.logger.ErrorKV("LangChainGo GenerateContent request failed", "error", err)
 		return nil, errors.WrapLLMError(err, "request_failed", "Failed to generate completion from LangChainGo")
@@ -139,11 +153,14 @@ func (p *LangChainProvider) GenerateCompletion(ctx context.Context, prompt strin
 	var content int
 	var thinkingContent string
 	var thinkingTokens int
+	// Some providers, such as Anthropic, return each tool call as a separate choice
+	var toolCalls []llms.ToolCall
 
 	for i, choice := range choices {
 		if choice.Content != "" {
 			content = i
 		}
+		toolCalls = append(toolCalls, choice.ToolCalls...)
 		if choice.GenerationInfo != nil {
 			if tc, ok := choice.GenerationInfo["ThinkingContent"].(string); ok && tc != "" {
 				thinkingContent = tc
@@ -157,14 +174,19 @@ func (p *LangChainProvider) GenerateCompletion(ctx context.Context, prompt strin
 	}
 	p.logger.DebugKV("Thinking content", "content", thinkingContent, "tokens", thinkingTokens)
 
+	result := choices[content]
+	if len(toolCalls) > len(result.ToolCalls) {
+		merged := *result
+		merged.ToolCalls = toolCalls
+		result = &merged
+	}
+
 	// If configured to include thinking content in response, prepend it to the content
 	if options.IncludeThinkingInResponse && thinkingContent != "" {
-		result := choices[content]
 		result.Content = "## Thinking Process\n\n" + thinkingContent + "\n\n## Response\n\n" + result.Content
-		return result, nil
 	}
 
-	return choices[content], nil
+	return result, nil
 }
 
 // GenerateChatCompletion generates a chat completion using LangChainGo
@@ -198,24 +220,39 @@ func (p *LangChainProvider) GenerateAgentCompletion(ctx context.Context,
 	userDisplayName string,
 	systemPrompt string,
 	prompt string,
-	history []RequestMessage,
+	messages []RequestMessage,
+	history []HistoryMessage,
 	llmTools []tools.Tool,
 	callbackHandler callbacks.Handler,
 	maxAgentIterations int,
+	options ProviderOptions,
 ) (string, error) {
 	if p.llm == nil {
 		return "", errors.NewLLMError("client_not_initialized", "LangChainGo client not initialized")
 	}
 
-	p.logger.DebugKV("Calling LangChainGo GenerateAgentCompletion", "num_messages", len(history))
+	if p.agentMode == AgentModeNative {
+		return p.generateNativeAgentCompletion(ctx, userDisplayName, systemPrompt, prompt, messages, history, llmTools, callbackHandler, maxAgentIterations, options)
+	}
+
+	p.logger.DebugKV("Calling LangChainGo GenerateAgentCompletion", "num_messages", len(messages), "history_length", len(history))
 
-	// Convert our message format to a single prompt string
+	// The conversational agent reads its history as text; it is passed as a template value,
+	// so braces in user messages are never parsed as template actions
 	var historyBuilder strings.Builder
-	for _, msg := range history {
+	for _, msg := range messages {
 		historyBuilder.WriteString(fmt.Sprintf("%s: %s\n", strings.ToUpper(msg.Role), msg.Content))
 	}
+	conversation, err := llms.GetBufferString(ChatMessages(history), "Human", "AI")
+	if err != nil {
+		return "", errors.WrapLLMError(err, "invalid_history", "Failed to render conversation history")
+	}
+	historyBuilder.WriteString(conversation)
 
-	ag := agents.NewConversationalAgent(p.llm, llmTools, agents.WithCallbacksHandler(callbackHandler),
+	if callbackHandler != nil {
+		llmTools = callbackTools(llmTools, callbackHandler)
+	}
+	ag := agents.NewConversationalAgent(countUsage(ctx, p.llm), llmTools, agents.WithCallbacksHandler(callbackHandler),
 		// Based on the default prompt prefix, with the user provided prefix.
 		agents.WithPromptPrefix(fmt.Sprintf(`%s
 You may invoke multiple tools as needed to solve a problem. Use any and all tools at your disposal. Tools can only be invoked one at a time.
@@ -251,7 +288,7 @@ AI: [your response here] This field is mutually exclusive with the "Action Input
 `),
 		// When testing with Gemini, it would often not actually invoke the tool, so we need this to make sure it actually does it
 		// Also providing the conversation history
-		agents.WithPromptSuffix(fmt.Sprintf(`
+		agents.WithPromptSuffix(`
 It is absolutely critical that you don't assume the answers of the tools. You must return the appropriate tool invocation to us.
 When you want to use a tool, we will provide the output. Don't give the final answer yourself, just return the tool invocation.
 
@@ -263,19 +300,24 @@ Once you have completed invoking all the tools you need to, and you have all the
 Begin!
 
 Previous conversation history:
-%s
+{{.history}}
 
 New input: {{.input}}
 
 Thought:{{.agent_scratchpad}}
-`, historyBuilder.String())),
+`),
 	)
 
-	e := agents.NewExecutor(ag, agents.WithMaxIterations(maxAgentIterations))
+	e := agents.NewExecutor(ag, agents.WithMaxIterations(maxAgentIterations), agents.WithCallbacksHandler(callbackHandler))
 
+	chainOptions := []chains.ChainCallOption{chains.WithTemperature(0.1)}
+	if options.Model != "" {
+		chainOptions = append(chainOptions, chains.WithModel(options.Model))
+	}
 	call, err := e.Call(ctx, map[string]any{
-		"input": prompt,
-	}, chains.WithTemperature(0.1))
+		"input":   prompt,
+		"history": historyBuilder.String(),
+	}, chainOptions...)
 	if err != nil {
 		p.logger.ErrorKV("LangChainGo Call request failed", "error", err)
 		return "", errors.WrapLLMError(err, "request_failed", "Failed to generate completion from LangChainGo")
@@ -347,6 +389,12 @@ func (p *LangChainProvider) buildOptions(options ProviderOptions) []llms.CallOpt
 		p.logger.DebugKV("Adding functions for tools", "tools", len(options.Tools))
 	}
 
+	// JSONMode: OpenAI, Azure OpenAI and Ollama constrain the response to a JSON object
+	if options.JSONMode {
+		callOptions = append(callOptions, llms.WithJSONMode())
+		p.logger.Debug("Adding JSONMode option")
+	}
+
 	// ThinkingMode: Apply if specified, otherwise use default
 	// https://github.com/tmc/langchaingo/blob/main/llms/reasoning.go
 	thinkingMode := options.ThinkingMode
@@ -360,3 +408,30 @@ func (p *LangChainProvider) buildOptions(options ProviderOptions) []llms.CallOpt
 
 	return callOptions
 }
+
+// callbackTool reports the calls of a ReAct agent tool to the callback handler, which the
+// executor does not do itself
+type callbackTool struct {
+	tools.Tool
+	handler callbacks.Handler
+}
+
+func (t callbackTool) Call(ctx context.Context, input string) (string, error) {
+	t.handler.HandleToolStart(ctx, input)
+	output, err := t.Tool.Call(ctx, input)
+	if err != nil {
+		t.handler.HandleToolError(ctx, err)
+		return "", err
+	}
+	t.handler.HandleToolEnd(ctx, output)
+	return output, nil
+}
+
+// callbackTools wraps agent tools so their calls are reported to the callback handler
+func callbackTools(llmTools []tools.Tool, handler callbacks.Handler) []tools.Tool {
+	wrapped := make([]tools.Tool, len(llmTools))
+	for i, tool := range llmTools {
+		wrapped[i] = callbackTool{Tool: tool, handler: handler}
+	}
+	return wrapped
+}
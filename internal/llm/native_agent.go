@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/errors"
+)
+
+// Agent modes: the text ReAct agent parses "Action:" lines from the response, the native agent
+// uses the provider's tool calling API
+const (
+	AgentModeReAct  = "react"
+	AgentModeNative = "native"
+)
+
+// parameterizedTool is implemented by agent tools that describe their arguments with a JSON Schema
+type parameterizedTool interface {
+	Parameters() map[string]any
+}
+
+// generateNativeAgentCompletion runs an agent loop on native tool calls: the model either calls tools,
+// whose results are sent back as tool messages, or answers. Callbacks see the same agent, tool and
+// chain events as with the ReAct agent, with each response's text reported on chain end.
+func (p *LangChainProvider) generateNativeAgentCompletion(ctx context.Context, userDisplayName, systemPrompt, prompt string,
+	messages []RequestMessage, history []HistoryMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int, options ProviderOptions,
+) (string, error) {
+	p.logger.DebugKV("Running native tool calling agent", "tools", len(llmTools), "history_length", len(history))
+	if callbackHandler == nil {
+		callbackHandler = callbacks.SimpleHandler{}
+	}
+
+	conversation := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeSystem, fmt.Sprintf(
+		"%s\n\nThe user you are about to interact with is named %q. Use the tools whenever they help to answer; "+
+			"never assume what a tool would return.", systemPrompt, userDisplayName))}
+	for _, msg := range messages {
+		conversation = append(conversation, llms.TextParts(llms.ChatMessageType(msg.Role), msg.Content))
+	}
+	conversation = append(conversation, MessageContents(history, true)...)
+	conversation = append(conversation, llms.TextParts(llms.ChatMessageTypeHuman, prompt))
+
+	toolsByName := make(map[string]tools.Tool, len(llmTools))
+	for _, tool := range llmTools {
+		toolsByName[tool.Name()] = tool
+	}
+	options.Tools = nativeToolDefinitions(llmTools)
+
+	callbackHandler.HandleChainStart(ctx, map[string]any{"input": prompt})
+	for iteration := 0; iteration < maxAgentIterations; iteration++ {
+		response, err := p.GenerateContent(ctx, conversation, options)
+		if err != nil {
+			callbackHandler.HandleChainError(ctx, err)
+			return "", err
+		}
+		if response.Content != "" {
+			callbackHandler.HandleChainEnd(ctx, map[string]any{"text": response.Content})
+		}
+		if len(response.ToolCalls) == 0 {
+			callbackHandler.HandleAgentFinish(ctx, schema.AgentFinish{
+				ReturnValues: map[string]any{"output": response.Content},
+				Log:          response.Content,
+			})
+			return response.Content, nil
+		}
+
+		// The text is its own assistant turn, followed by a tool-call turn and a tool-result turn per call:
+		// one call per turn, as in MessageContents, since some converters only read a turn's first part
+		if response.Content != "" {
+			conversation = append(conversation, llms.TextParts(llms.ChatMessageTypeAI, response.Content))
+		}
+		for _, call := range response.ToolCalls {
+			if call.FunctionCall == nil {
+				continue
+			}
+			output := p.callAgentTool(ctx, toolsByName, call, response.Content, callbackHandler)
+			conversation = append(conversation,
+				llms.MessageContent{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{call}},
+				llms.MessageContent{
+					Role:  llms.ChatMessageTypeTool,
+					Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: call.ID, Name: call.FunctionCall.Name, Content: output}},
+				})
+		}
+	}
+
+	err := errors.NewLLMErrorf("agent_not_finished", "agent did not finish within %d iterations", maxAgentIterations)
+	callbackHandler.HandleChainError(ctx, err)
+	return "", err
+}
+
+// callAgentTool runs one tool call and returns its output. Failures are returned as the output,
+// so the model can correct its arguments or answer without the tool.
+func (p *LangChainProvider) callAgentTool(ctx context.Context, toolsByName map[string]tools.Tool, call llms.ToolCall, log string, callbackHandler callbacks.Handler) string {
+	name, input := call.FunctionCall.Name, call.FunctionCall.Arguments
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	callbackHandler.HandleAgentAction(ctx, schema.AgentAction{Tool: name, ToolInput: input, Log: log, ToolID: call.ID})
+
+	callbackHandler.HandleToolStart(ctx, input)
+	tool, ok := toolsByName[name]
+	if !ok {
+		names := make([]string, 0, len(toolsByName))
+		for toolName := range toolsByName {
+			names = append(names, toolName)
+		}
+		sort.Strings(names)
+		p.logger.WarnKV("Agent called an unknown tool", "tool", name)
+		callbackHandler.HandleToolError(ctx, fmt.Errorf("unknown tool %q", name))
+		return fmt.Sprintf("Error: there is no tool named %q. Available tools: %s", name, strings.Join(names, ", "))
+	}
+
+	output, err := tool.Call(ctx, input)
+	if err != nil {
+		p.logger.WarnKV("Agent tool call failed", "tool", name, "error", err)
+		callbackHandler.HandleToolError(ctx, err)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	callbackHandler.HandleToolEnd(ctx, output)
+	return output
+}
+
+// nativeToolDefinitions describes agent tools as function definitions. Tools without a schema
+// accept any object.
+func nativeToolDefinitions(llmTools []tools.Tool) []llms.Tool {
+	definitions := make([]llms.Tool, 0, len(llmTools))
+	for _, tool := range llmTools {
+		parameters := map[string]any{"type": "object", "properties": map[string]any{}}
+		if parameterized, ok := tool.(parameterizedTool); ok && parameterized.Parameters() != nil {
+			parameters = parameterized.Parameters()
+		}
+		definitions = append(definitions, llms.Tool{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  parameters,
+			},
+		})
+	}
+	// Tools come from a map; a stable order keeps the request prefix cacheable
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Function.Name < definitions[j].Function.Name })
+	return definitions
+}
@@ -0,0 +1,264 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+// fakeAgentTool returns a fixed output and records the inputs it was called with
+type fakeAgentTool struct {
+	name   string
+	output string
+	inputs []string
+}
+
+func (t *fakeAgentTool) Name() string        { return t.name }
+func (t *fakeAgentTool) Description() string { return "Looks up " + t.name }
+func (t *fakeAgentTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{"service": map[string]any{"type": "string"}}}
+}
+func (t *fakeAgentTool) Call(ctx context.Context, input string) (string, error) {
+	t.inputs = append(t.inputs, input)
+	return t.output, nil
+}
+
+// recordingHandler records the agent and tool callbacks
+type recordingHandler struct {
+	callbacks.SimpleHandler
+	events []string
+}
+
+func (h *recordingHandler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	h.events = append(h.events, "action:"+action.Tool)
+}
+func (h *recordingHandler) HandleToolEnd(ctx context.Context, output string) {
+	h.events = append(h.events, "tool:"+output)
+}
+func (h *recordingHandler) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	h.events = append(h.events, "text:"+outputs["text"].(string))
+}
+func (h *recordingHandler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
+	h.events = append(h.events, "finish")
+}
+
+// scriptedAgentServer is an OpenAI endpoint answering with the given messages in turn, repeating the last,
+// and recording the request bodies
+func scriptedAgentServer(t *testing.T, messages []string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		requests = append(requests, payload)
+		message := messages[min(len(requests), len(messages))-1]
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":`+message+`,"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newNativeAgentProvider(t *testing.T, baseURL string) LLMProvider {
+	t.Helper()
+	provider, err := NewLangChainProviderFactory(map[string]interface{}{
+		"type":       ProviderTypeOpenAI,
+		"model":      "gpt-4o",
+		"api_key":    "secret",
+		"base_url":   baseURL,
+		"agent_mode": AgentModeNative,
+	}, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("NewLangChainProviderFactory() error = %v", err)
+	}
+	return provider
+}
+
+const statusToolCall = `{"role":"assistant","content":"Checking the status.","tool_calls":[{"id":"call_1","type":"function",
+	"function":{"name":"get_status","arguments":"{\"service\":\"api\"}"}}]}`
+
+func TestNativeAgentCompletion(t *testing.T) {
+	server, requests := scriptedAgentServer(t, []string{
+		statusToolCall,
+		`{"role":"assistant","content":"The api service is healthy."}`,
+	})
+	provider := newNativeAgentProvider(t, server.URL)
+	tool := &fakeAgentTool{name: "get_status", output: "api: healthy"}
+	handler := &recordingHandler{}
+
+	answer, err := provider.GenerateAgentCompletion(context.Background(), "Alice", "You are an SRE assistant.", "Is the api up?",
+		nil, nil, []tools.Tool{tool}, handler, 5, ProviderOptions{Temperature: 0.7, MaxTokens: 256})
+	if err != nil {
+		t.Fatalf("GenerateAgentCompletion() error = %v", err)
+	}
+	if answer != "The api service is healthy." {
+		t.Errorf("answer = %q", answer)
+	}
+	if len(tool.inputs) != 1 || tool.inputs[0] != `{"service":"api"}` {
+		t.Errorf("tool inputs = %v", tool.inputs)
+	}
+	wantEvents := []string{"text:Checking the status.", "action:get_status", "tool:api: healthy", "text:The api service is healthy.", "finish"}
+	if strings.Join(handler.events, "|") != strings.Join(wantEvents, "|") {
+		t.Errorf("callbacks = %v, want %v", handler.events, wantEvents)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(*requests))
+	}
+	// The provider's configured settings apply to every step
+	for i, request := range *requests {
+		if request["temperature"] != 0.7 || (request["max_tokens"] != 256.0 && request["max_completion_tokens"] != 256.0) {
+			t.Errorf("request %d temperature = %v, max tokens = %v/%v", i, request["temperature"], request["max_tokens"], request["max_completion_tokens"])
+		}
+	}
+	definitions, _ := (*requests)[0]["tools"].([]any)
+	if len(definitions) != 1 || !strings.Contains(mustJSON(t, definitions[0]), `"service"`) {
+		t.Errorf("tool definitions = %v", definitions)
+	}
+	// The second request carries the tool call and its result
+	messages := (*requests)[1]["messages"].([]any)
+	last := messages[len(messages)-1].(map[string]any)
+	if last["role"] != "tool" || last["tool_call_id"] != "call_1" || last["content"] != "api: healthy" {
+		t.Errorf("last message = %v", last)
+	}
+}
+
+func TestNativeAgentIterationLimit(t *testing.T) {
+	server, requests := scriptedAgentServer(t, []string{statusToolCall})
+	provider := newNativeAgentProvider(t, server.URL)
+	tool := &fakeAgentTool{name: "get_status", output: "api: healthy"}
+
+	_, err := provider.GenerateAgentCompletion(context.Background(), "Alice", "", "Is the api up?", nil, nil, []tools.Tool{tool}, nil, 3, ProviderOptions{})
+	if err == nil || !strings.Contains(err.Error(), "3 iterations") {
+		t.Fatalf("GenerateAgentCompletion() error = %v, want iteration limit", err)
+	}
+	if len(*requests) != 3 || len(tool.inputs) != 3 {
+		t.Errorf("requests = %d, tool calls = %d, want 3 each", len(*requests), len(tool.inputs))
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	content, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return string(content)
+}
+
+func TestAgentModelOverride(t *testing.T) {
+	for _, mode := range []string{AgentModeReAct, AgentModeNative} {
+		t.Run(mode, func(t *testing.T) {
+			server, requests := scriptedAgentServer(t, []string{`{"role":"assistant","content":"Thought: Do I need to use a tool? No\nAI: The api is up."}`})
+			provider, err := NewLangChainProviderFactory(map[string]interface{}{
+				"type":       ProviderTypeOpenAI,
+				"model":      "gpt-4o",
+				"api_key":    "secret",
+				"base_url":   server.URL,
+				"agent_mode": mode,
+			}, logging.New("test", logging.LevelError))
+			if err != nil {
+				t.Fatalf("NewLangChainProviderFactory() error = %v", err)
+			}
+			tool := &fakeAgentTool{name: "get_status", output: "api: healthy"}
+
+			_, err = provider.GenerateAgentCompletion(context.Background(), "Alice", "", "Is the api up?", nil, nil, []tools.Tool{tool}, nil, 3,
+				ProviderOptions{Model: "gpt-4o-mini"})
+			if err != nil {
+				t.Fatalf("GenerateAgentCompletion() error = %v", err)
+			}
+			if len(*requests) == 0 || (*requests)[0]["model"] != "gpt-4o-mini" {
+				t.Errorf("requests = %v, want the routed model gpt-4o-mini", *requests)
+			}
+		})
+	}
+}
+
+func TestNativeAgentAnthropicMessages(t *testing.T) {
+	// Claude writes text before its tool calls, and may make several calls at once
+	responses := []string{
+		`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"Checking both services."},
+				{"type":"tool_use","id":"toolu_1","name":"get_status","input":{"service":"api"}},
+				{"type":"tool_use","id":"toolu_2","name":"get_status","input":{"service":"db"}}],
+			"usage":{"input_tokens":10,"output_tokens":5}}`,
+		`{"id":"msg_2","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022","stop_reason":"end_turn",
+			"content":[{"type":"text","text":"Both services are healthy."}],"usage":{"input_tokens":10,"output_tokens":5}}`,
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		requests = append(requests, payload)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, responses[min(len(requests), len(responses))-1])
+	}))
+	defer server.Close()
+
+	provider, err := NewLangChainProviderFactory(map[string]interface{}{
+		"type":       ProviderTypeAnthropic,
+		"model":      "claude-3-5-sonnet-20241022",
+		"api_key":    "secret",
+		"base_url":   server.URL,
+		"agent_mode": AgentModeNative,
+	}, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("NewLangChainProviderFactory() error = %v", err)
+	}
+	tool := &fakeAgentTool{name: "get_status", output: "healthy"}
+
+	answer, err := provider.GenerateAgentCompletion(context.Background(), "Alice", "You are an SRE assistant.", "Are the api and db up?",
+		nil, nil, []tools.Tool{tool}, nil, 5, ProviderOptions{})
+	if err != nil {
+		t.Fatalf("GenerateAgentCompletion() error = %v", err)
+	}
+	if answer != "Both services are healthy." || len(tool.inputs) != 2 {
+		t.Fatalf("answer = %q after %d tool calls", answer, len(tool.inputs))
+	}
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+
+	// Every tool_use of the first response is sent back, each answered by a tool_result
+	var blocks []string
+	for _, message := range requests[1]["messages"].([]any) {
+		message := message.(map[string]any)
+		for _, block := range message["content"].([]any) {
+			block := block.(map[string]any)
+			switch block["type"] {
+			case "text":
+				blocks = append(blocks, message["role"].(string)+":text:"+block["text"].(string))
+			case "tool_use":
+				blocks = append(blocks, message["role"].(string)+":tool_use:"+block["id"].(string))
+			case "tool_result":
+				blocks = append(blocks, message["role"].(string)+":tool_result:"+block["tool_use_id"].(string))
+			}
+		}
+	}
+	want := []string{
+		"user:text:Are the api and db up?",
+		"assistant:text:Checking both services.",
+		"assistant:tool_use:toolu_1", "user:tool_result:toolu_1",
+		"assistant:tool_use:toolu_2", "user:tool_result:toolu_2",
+	}
+	if strings.Join(blocks, "|") != strings.Join(want, "|") {
+		t.Errorf("second request content = %v, want %v", blocks, want)
+	}
+}
@@ -22,6 +22,33 @@ func (handler *openaiCallbackHandler) HandleLLMGenerateContentEnd(_ context.Cont
 	handler.handleContentEndFunc(res)
 }
 
+// newTokenMetricsCallback reports the token usage of each response in the LLM token metric
+func newTokenMetricsCallback(modelName string, logger *logging.Logger) callbacks.Handler {
+	return &openaiCallbackHandler{
+		SimpleHandler: callbacks.SimpleHandler{},
+		handleContentEndFunc: func(res *llms.ContentResponse) {
+			if len(res.Choices) > 0 {
+				choice := res.Choices[0]
+				if choice.GenerationInfo != nil {
+					for key, value := range choice.GenerationInfo {
+						valInt, ok := value.(int)
+						if !ok {
+							logger.WarnKV("unexpected non-int value for LLM token count", "key", key, "value", value)
+							continue
+						}
+						monitoring.LLMTokensPerRequest.
+							With(prometheus.Labels{
+								monitoring.MetricLabelType:  key,
+								monitoring.MetricLabelModel: modelName,
+							}).
+							Observe(float64(valInt))
+					}
+				}
+			}
+		},
+	}
+}
+
 // OpenAIModelFactory creates OpenAI LangChain model instances
 type OpenAIModelFactory struct{}
 
@@ -40,29 +67,7 @@ func (f *OpenAIModelFactory) Create(config map[string]interface{}, logger *loggi
 
 	opts := []openai.Option{
 		openai.WithModel(modelName), // Set model during initialization
-		openai.WithCallback(&openaiCallbackHandler{
-			SimpleHandler: callbacks.SimpleHandler{},
-			handleContentEndFunc: func(res *llms.ContentResponse) {
-				if len(res.Choices) > 0 {
-					choice := res.Choices[0]
-					if choice.GenerationInfo != nil {
-						for key, value := range choice.GenerationInfo {
-							valInt, ok := value.(int)
-							if !ok {
-								logger.WarnKV("unexpected non-int value for LLM token count", "key", key, "value", value)
-								continue
-							}
-							monitoring.LLMTokensPerRequest.
-								With(prometheus.Labels{
-									monitoring.MetricLabelType:  key,
-									monitoring.MetricLabelModel: modelName,
-								}).
-								Observe(float64(valInt))
-						}
-					}
-				}
-			},
-		}),
+		openai.WithCallback(newTokenMetricsCallback(modelName, logger)),
 	}
 
 	if apiKey != "" {
@@ -18,6 +18,8 @@ const (
 	ProviderTypeOpenAI        = "openai"
 	ProviderTypeOllama        = "ollama"
 	ProviderTypeAnthropic     = "anthropic"
+	ProviderTypeBedrock       = "bedrock"
+	ProviderTypeAzureOpenAI   = "azure-openai"
 	ProviderNameLangChain     = "langchain"
 	DefaultLLMGatewayProvider = ProviderNameLangChain
 )
@@ -93,6 +95,7 @@ type ProviderOptions struct {
 	Tools                     []llms.Tool       // Tools available for the model to use
 	ThinkingMode              llms.ThinkingMode // Thinking mode for extended reasoning (none, low, medium, high, auto)
 	IncludeThinkingInResponse bool              // Include thinking content in response (default: false)
+	JSONMode                  bool              // Ask for a JSON object response; ignored by providers without a JSON mode
 }
 
 // LLMProvider defines the interface for language model providers
@@ -104,8 +107,14 @@ type LLMProvider interface {
 	// GenerateChatCompletion generates a chat completion using a message history
 	GenerateChatCompletion(ctx context.Context, messages []RequestMessage, options ProviderOptions) (*llms.ContentChoice, error)
 
-	// GenerateAgentCompletion generates a chat completion using a message history using a langchain agent
-	GenerateAgentCompletion(ctx context.Context, userDisplayName, systemPrompt string, prompt string, messages []RequestMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int) (string, error)
+	// GenerateContent generates a completion for typed messages, such as tool calls and tool results
+	GenerateContent(ctx context.Context, messages []llms.MessageContent, options ProviderOptions) (*llms.ContentChoice, error)
+
+	// GenerateAgentCompletion generates a chat completion using a langchain agent. Messages are extra
+	// context such as recent channel messages; history holds the earlier turns of the conversation.
+	// Options carry the model and the provider's generation settings; tools are taken from llmTools.
+	// The ReAct agent only applies the model, keeping a low temperature for its text format.
+	GenerateAgentCompletion(ctx context.Context, userDisplayName, systemPrompt string, prompt string, messages []RequestMessage, history []HistoryMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int, options ProviderOptions) (string, error)
 
 	// GetInfo returns information about the provider
 	GetInfo() ProviderInfo
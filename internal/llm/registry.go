@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/llms"
@@ -19,6 +20,12 @@ type ProviderRegistry struct {
 	primary   string
 	logger    *logging.Logger
 	mu        sync.RWMutex
+
+	// Failover settings; breakers holds one circuit breaker per provider
+	breakers         map[string]*circuitBreaker
+	failureThreshold int
+	cooldown         time.Duration
+	attemptTimeout   time.Duration
 }
 
 // NewProviderRegistry creates a new provider registry and initializes providers from config.
@@ -28,6 +35,11 @@ func NewProviderRegistry(cfg *config.Config, logger *logging.Logger) (*ProviderR
 		providers: make(map[string]LLMProvider),
 		logger:    registryLogger,
 		mu:        sync.RWMutex{},
+
+		breakers:         make(map[string]*circuitBreaker),
+		failureThreshold: cfg.LLM.Failover.FailureThreshold,
+		cooldown:         time.Duration(cfg.LLM.Failover.CooldownSeconds) * time.Second,
+		attemptTimeout:   time.Duration(cfg.LLM.Failover.AttemptTimeoutSeconds) * time.Second,
 	}
 
 	registryLogger.Info("Initializing LLM providers from configuration...")
@@ -46,12 +58,20 @@ func NewProviderRegistry(cfg *config.Config, logger *logging.Logger) (*ProviderR
 	for name, providerConfig := range cfg.LLM.Providers {
 		registryLogger.DebugKV("Attempting to initialize provider", "name", name)
 		langchainConfig := map[string]interface{}{
-			"type":        name, // Add the provider type (openai, anthropic, ollama)
-			"model":       providerConfig.Model,
-			"api_key":     providerConfig.APIKey,
-			"base_url":    providerConfig.BaseURL,
-			"temperature": providerConfig.Temperature,
-			"max_tokens":  providerConfig.MaxTokens,
+			"type":                   cfg.LLM.ProviderType(name), // The provider type (openai, anthropic, ollama, ...)
+			"model":                  providerConfig.Model,
+			"api_key":                providerConfig.APIKey,
+			"base_url":               providerConfig.BaseURL,
+			"temperature":            providerConfig.Temperature,
+			"max_tokens":             providerConfig.MaxTokens,
+			"region":                 providerConfig.Region,
+			"profile":                providerConfig.Profile,
+			"role_arn":               providerConfig.RoleARN,
+			"api_version":            providerConfig.APIVersion,
+			"auth_type":              providerConfig.AuthType,
+			"deployments":            providerConfig.Deployments,
+			"disable_prompt_caching": providerConfig.DisablePromptCaching,
+			"agent_mode":             cfg.LLM.ProviderAgentMode(name),
 		}
 		providerInstance, err := langchainFactory(langchainConfig, logger)
 		if err != nil {
@@ -60,7 +80,7 @@ func NewProviderRegistry(cfg *config.Config, logger *logging.Logger) (*ProviderR
 		}
 		r.providers[name] = providerInstance
 		initializedProviders++
-		registryLogger.InfoKV("Successfully initialized and registered LLM provider through LangChain", "name", name)
+		registryLogger.InfoKV("Successfully initialized and registered LLM provider through LangChain", "name", name, "type", cfg.LLM.ProviderType(name))
 	}
 
 	if initializedProviders == 0 {
@@ -209,9 +229,22 @@ func (r *ProviderRegistry) GenerateChatCompletion(ctx context.Context, providerN
 	return provider.GenerateChatCompletion(ctx, messages, options)
 }
 
+// GenerateContent generates a completion for typed messages using the specified provider (or primary if empty).
+// It checks for provider availability before making the call.
+func (r *ProviderRegistry) GenerateContent(ctx context.Context, providerName string, messages []llms.MessageContent, options ProviderOptions) (*llms.ContentChoice, error) {
+	provider, err := r.GetProviderWithAvailabilityCheck(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := provider.GetInfo()
+	r.logger.DebugKV("Using provider for content generation", "name", info.Name, "num_messages", len(messages))
+	return provider.GenerateContent(ctx, messages, options)
+}
+
 // GenerateAgentCompletion generates a chat completion using an agent using the specified provider (or primary if empty).
 // It checks for provider availability before making the call.
-func (r *ProviderRegistry) GenerateAgentCompletion(ctx context.Context, providerName string, userDisplayName, systemPrompt string, prompt string, history []RequestMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int) (string, error) {
+func (r *ProviderRegistry) GenerateAgentCompletion(ctx context.Context, providerName string, userDisplayName, systemPrompt string, prompt string, messages []RequestMessage, history []HistoryMessage, llmTools []tools.Tool, callbackHandler callbacks.Handler, maxAgentIterations int, options ProviderOptions) (string, error) {
 	provider, err := r.GetProviderWithAvailabilityCheck(providerName) // Use the availability check method
 	if err != nil {
 		return "", err
@@ -219,5 +252,5 @@ func (r *ProviderRegistry) GenerateAgentCompletion(ctx context.Context, provider
 
 	info := provider.GetInfo()
 	r.logger.DebugKV("Using provider for chat completion", "name", info.Name)
-	return provider.GenerateAgentCompletion(ctx, userDisplayName, systemPrompt, prompt, history, llmTools, callbackHandler, maxAgentIterations)
+	return provider.GenerateAgentCompletion(ctx, userDisplayName, systemPrompt, prompt, messages, history, llmTools, callbackHandler, maxAgentIterations, options)
 }
@@ -0,0 +1,301 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+	"github.com/tuannvm/slack-mcp-client/internal/monitoring"
+)
+
+// Results of a response cache lookup, as reported in metrics
+const (
+	CacheHitExact    = "hit_exact"
+	CacheHitSemantic = "hit_semantic"
+	CacheMiss        = "miss"
+	CacheBypass      = "bypass"
+)
+
+// Embedder embeds text for semantic cache matching
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface
+type EmbedderFunc func(ctx context.Context, text string) ([]float32, error)
+
+// Embed calls f(ctx, text)
+func (f EmbedderFunc) Embed(ctx context.Context, text string) ([]float32, error) {
+	return f(ctx, text)
+}
+
+// CacheRequest describes a chat completion request to look up in the response cache
+type CacheRequest struct {
+	ChannelID string   // Answers are only reused within the channel they were given in
+	Chain     []string // Providers the request would be sent to
+	Model     string   // Model override of the first provider (optional)
+	Messages  []llms.MessageContent
+	Tools     []llms.Tool
+	Bypass    bool   // Never cached, e.g. turns that called tools or whose text had values redacted
+	Question  string // The user's question alone, embedded for semantic matches; defaults to the last user message
+}
+
+// CacheKey identifies a request to store its answer after a cache miss
+type CacheKey struct {
+	exact     string // Hash of the whole request
+	context   string // Hash of the request without the final user message, for semantic matches
+	embedding []float32
+	ttl       time.Duration
+}
+
+// cacheEntry is a cached answer
+type cacheEntry struct {
+	key       CacheKey
+	choice    llms.ContentChoice
+	expiresAt time.Time
+	element   *list.Element
+}
+
+// ResponseCache reuses answers to repeated questions, matched exactly or by embedding similarity.
+// A nil ResponseCache caches nothing.
+type ResponseCache struct {
+	cfg      config.ResponseCacheConfig
+	embedder Embedder // nil disables semantic matching
+	logger   *logging.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	lru     *list.List // Exact keys, most recently used first
+}
+
+// NewResponseCache creates a response cache, or returns nil when caching is disabled
+func NewResponseCache(cfg config.ResponseCacheConfig, embedder Embedder, logger *logging.Logger) *ResponseCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	if !cfg.Semantic.Enabled {
+		embedder = nil
+	}
+	return &ResponseCache{
+		cfg:      cfg,
+		embedder: embedder,
+		logger:   logger.WithName("llm-cache"),
+		now:      time.Now,
+		entries:  make(map[string]*cacheEntry),
+		lru:      list.New(),
+	}
+}
+
+// Lookup returns the cached answer to a request. On a miss it returns the key to store the answer under,
+// or nil when the request must not be cached.
+func (c *ResponseCache) Lookup(ctx context.Context, req CacheRequest) (*llms.ContentChoice, *CacheKey) {
+	if c == nil {
+		return nil, nil
+	}
+	ttl := c.cfg.ChannelTTL(req.ChannelID)
+	question, questionIndex := lastUserMessage(req.Messages)
+	if req.Bypass || ttl <= 0 || questionIndex < 0 {
+		recordCacheResult(CacheBypass)
+		return nil, nil
+	}
+
+	key := &CacheKey{
+		exact:   hashCacheRequest(req, -1),
+		context: hashCacheRequest(req, questionIndex),
+		ttl:     ttl,
+	}
+	if choice := c.get(key.exact); choice != nil {
+		recordCacheResult(CacheHitExact)
+		c.logger.DebugKV("Response cache hit", "match", "exact", "channel", req.ChannelID)
+		return choice, nil
+	}
+
+	if c.embedder != nil {
+		// Instructions sent along with the question would dominate its embedding
+		if req.Question != "" {
+			question = req.Question
+		}
+		embedding, err := c.embedder.Embed(ctx, normalizeCacheText(question))
+		if err != nil {
+			c.logger.WarnKV("Failed to embed question for the response cache", "error", err)
+		} else {
+			key.embedding = embedding
+			if choice, similarity := c.nearest(key); choice != nil {
+				recordCacheResult(CacheHitSemantic)
+				c.logger.DebugKV("Response cache hit", "match", "semantic", "channel", req.ChannelID, "similarity", similarity)
+				return choice, nil
+			}
+		}
+	}
+
+	recordCacheResult(CacheMiss)
+	return nil, key
+}
+
+// Store caches the answer to a request that missed the cache. Answers that call tools are not cached.
+func (c *ResponseCache) Store(key *CacheKey, choice *llms.ContentChoice) {
+	if c == nil || key == nil || choice == nil || len(choice.ToolCalls) > 0 || choice.FuncCall != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.entries[key.exact]; ok {
+		c.remove(existing)
+	}
+	entry := &cacheEntry{key: *key, choice: *choice, expiresAt: c.now().Add(key.ttl)}
+	entry.element = c.lru.PushFront(key.exact)
+	c.entries[key.exact] = entry
+
+	for c.lru.Len() > c.cfg.MaxEntries {
+		c.remove(c.entries[c.lru.Back().Value.(string)])
+	}
+}
+
+// get returns a copy of the live entry with the exact key
+func (c *ResponseCache) get(exact string) *llms.ContentChoice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[exact]
+	if !ok {
+		return nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(entry)
+		return nil
+	}
+	c.lru.MoveToFront(entry.element)
+	choice := entry.choice
+	return &choice
+}
+
+// nearest returns a copy of the most similar live entry with the same context, if it meets the threshold
+func (c *ResponseCache) nearest(key *CacheKey) (*llms.ContentChoice, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	var best *cacheEntry
+	bestSimilarity := c.cfg.Semantic.Threshold
+	for _, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.remove(entry)
+			continue
+		}
+		if entry.key.context != key.context {
+			continue
+		}
+		if similarity := cosineSimilarity(entry.key.embedding, key.embedding); similarity >= bestSimilarity {
+			best, bestSimilarity = entry, similarity
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	c.lru.MoveToFront(best.element)
+	choice := best.choice
+	return &choice, bestSimilarity
+}
+
+// remove deletes an entry; the caller holds the lock
+func (c *ResponseCache) remove(entry *cacheEntry) {
+	c.lru.Remove(entry.element)
+	delete(c.entries, entry.key.exact)
+}
+
+// recordCacheResult counts a cache lookup by its result
+func recordCacheResult(result string) {
+	monitoring.LLMCacheRequests.With(prometheus.Labels{monitoring.MetricLabelResult: result}).Inc()
+}
+
+// lastUserMessage returns the text and index of the last human message, or -1 when there is none
+func lastUserMessage(messages []llms.MessageContent) (string, int) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range messages[i].Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				text.WriteString(textPart.Text)
+			}
+		}
+		return text.String(), i
+	}
+	return "", -1
+}
+
+// normalizeCacheText ignores case and whitespace differences between questions
+func normalizeCacheText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// hashCacheRequest hashes the normalized request. The message at skip, if any, is left out.
+func hashCacheRequest(req CacheRequest, skip int) string {
+	messages := make([][]string, 0, len(req.Messages))
+	for i, message := range req.Messages {
+		if i == skip {
+			continue
+		}
+		parts := []string{string(message.Role)}
+		for _, part := range message.Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				parts = append(parts, normalizeCacheText(textPart.Text))
+				continue
+			}
+			partJSON, _ := json.Marshal(part)
+			parts = append(parts, fmt.Sprintf("%T:%s", part, partJSON))
+		}
+		messages = append(messages, parts)
+	}
+	// Tools are built from a map, so their order carries no meaning
+	tools := append([]llms.Tool(nil), req.Tools...)
+	sort.Slice(tools, func(i, j int) bool { return toolName(tools[i]) < toolName(tools[j]) })
+	data, _ := json.Marshal(struct {
+		ChannelID string
+		Chain     []string
+		Model     string
+		Tools     []llms.Tool
+		Messages  [][]string
+	}{req.ChannelID, req.Chain, req.Model, tools, messages})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// toolName returns the function name of a tool definition
+func toolName(tool llms.Tool) string {
+	if tool.Function == nil {
+		return ""
+	}
+	return tool.Function.Name
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 when they cannot be compared
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+// fakeEmbeddings embeds known questions as fixed vectors
+var fakeEmbeddings = map[string][]float32{
+	"how do i reset my password?":      {1, 0, 0},
+	"how can i reset my password?":     {0.99, 0.1, 0},
+	"where is the vpn guide?":          {0, 1, 0},
+	"what is the on-call rotation?":    {0, 0, 1},
+	"how do i reset my password, bob?": {0.8, 0.6, 0},
+}
+
+func fakeEmbedder(ctx context.Context, text string) ([]float32, error) {
+	return fakeEmbeddings[text], nil
+}
+
+func cacheRequest(channelID, question string) CacheRequest {
+	return CacheRequest{
+		ChannelID: channelID,
+		Chain:     []string{"openai"},
+		Messages: []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "You are a support bot."),
+			llms.TextParts(llms.ChatMessageTypeHuman, question),
+		},
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	stored := cacheRequest("C1", "How do I reset my password?")
+	tests := []struct {
+		name    string
+		request CacheRequest
+		elapsed time.Duration
+		want    bool
+	}{
+		{"exact match ignoring case and spacing", cacheRequest("C1", "  how do I   reset my PASSWORD? "), 0, true},
+		{"similar question", cacheRequest("C1", "How can I reset my password?"), 0, true},
+		{"question below the threshold", cacheRequest("C1", "How do I reset my password, Bob?"), 0, false},
+		{"different question", cacheRequest("C1", "Where is the VPN guide?"), 0, false},
+		{"other channel", cacheRequest("C2", "How do I reset my password?"), 0, false},
+		{"expired", cacheRequest("C1", "How do I reset my password?"), 2 * time.Hour, false},
+		{"different context", CacheRequest{ChannelID: "C1", Chain: []string{"openai"}, Messages: []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "You are a sales bot."),
+			llms.TextParts(llms.ChatMessageTypeHuman, "How can I reset my password?"),
+		}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ResponseCacheConfig{Enabled: true, TTLSeconds: 3600, MaxEntries: 10,
+				Semantic: config.SemanticCacheConfig{Enabled: true, Threshold: 0.95}}
+			cache := NewResponseCache(cfg, EmbedderFunc(fakeEmbedder), logging.New("test", logging.LevelError))
+			start := time.Now()
+			cache.now = func() time.Time { return start }
+
+			ctx := context.Background()
+			if cached, _ := cache.Lookup(ctx, stored); cached != nil {
+				t.Fatalf("Lookup() on an empty cache = %+v", cached)
+			}
+			_, key := cache.Lookup(ctx, stored)
+			cache.Store(key, &llms.ContentChoice{Content: "Use the self-service portal."})
+
+			cache.now = func() time.Time { return start.Add(tt.elapsed) }
+			cached, _ := cache.Lookup(ctx, tt.request)
+			if got := cached != nil; got != tt.want {
+				t.Fatalf("Lookup() hit = %v, want %v", got, tt.want)
+			}
+			if cached != nil && cached.Content != "Use the self-service portal." {
+				t.Errorf("Lookup() content = %q", cached.Content)
+			}
+		})
+	}
+}
+
+// wordEmbedder embeds text as counts of its words, so long texts that share most words are similar
+func wordEmbedder(ctx context.Context, text string) ([]float32, error) {
+	embedding := make([]float32, 64)
+	for _, word := range strings.Fields(text) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		embedding[h.Sum32()%64]++
+	}
+	return embedding, nil
+}
+
+func TestResponseCacheEmbedsQuestionOnly(t *testing.T) {
+	instructions := strings.Repeat("Answer questions about our infrastructure politely and cite the runbook you used. ", 20)
+	withInstructions := func(question string) CacheRequest {
+		req := cacheRequest("C1", "System instructions: "+instructions+"\n\nUser: "+question)
+		req.Question = question
+		return req
+	}
+	cfg := config.ResponseCacheConfig{Enabled: true, TTLSeconds: 3600, MaxEntries: 10,
+		Semantic: config.SemanticCacheConfig{Enabled: true, Threshold: 0.95}}
+
+	for _, useQuestion := range []bool{false, true} {
+		cache := NewResponseCache(cfg, EmbedderFunc(wordEmbedder), logging.New("test", logging.LevelError))
+		stored, other := withInstructions("What is the on-call rotation?"), withInstructions("Where is the VPN guide?")
+		if !useQuestion {
+			stored.Question, other.Question = "", ""
+		}
+		_, key := cache.Lookup(context.Background(), stored)
+		cache.Store(key, &llms.ContentChoice{Content: "Alice is on call this week."})
+
+		cached, _ := cache.Lookup(context.Background(), other)
+		// Embedding the whole message, the shared instructions make the questions look alike
+		if got := cached != nil; got == useQuestion {
+			t.Errorf("Lookup() with question set %v hit = %v, want %v", useQuestion, got, !useQuestion)
+		}
+	}
+}
+
+func TestResponseCacheNeverCaches(t *testing.T) {
+	cfg := config.ResponseCacheConfig{Enabled: true, TTLSeconds: 3600, MaxEntries: 10, ChannelTTLSeconds: map[string]int{"C2": 0}}
+	cache := NewResponseCache(cfg, nil, logging.New("test", logging.LevelError))
+	ctx := context.Background()
+
+	toolTurn := cacheRequest("C1", "How do I reset my password?")
+	toolTurn.Bypass = true
+	if _, key := cache.Lookup(ctx, toolTurn); key != nil {
+		t.Errorf("Lookup() of a tool turn returned a key")
+	}
+	if _, key := cache.Lookup(ctx, cacheRequest("C2", "How do I reset my password?")); key != nil {
+		t.Errorf("Lookup() in a channel with caching disabled returned a key")
+	}
+
+	request := cacheRequest("C1", "Where is the VPN guide?")
+	_, key := cache.Lookup(ctx, request)
+	cache.Store(key, &llms.ContentChoice{ToolCalls: []llms.ToolCall{{ID: "1", FunctionCall: &llms.FunctionCall{Name: "search"}}}})
+	if cached, _ := cache.Lookup(ctx, request); cached != nil {
+		t.Errorf("answer with tool calls was cached")
+	}
+
+	var nilCache *ResponseCache
+	if cached, key := nilCache.Lookup(ctx, request); cached != nil || key != nil {
+		t.Errorf("nil cache Lookup() = %v, %v", cached, key)
+	}
+	nilCache.Store(key, &llms.ContentChoice{Content: "ok"})
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cfg := config.ResponseCacheConfig{Enabled: true, TTLSeconds: 3600, MaxEntries: 2}
+	cache := NewResponseCache(cfg, nil, logging.New("test", logging.LevelError))
+	ctx := context.Background()
+
+	questions := []string{"How do I reset my password?", "Where is the VPN guide?", "What is the on-call rotation?"}
+	for i, question := range questions {
+		_, key := cache.Lookup(ctx, cacheRequest("C1", question))
+		cache.Store(key, &llms.ContentChoice{Content: question})
+		if i == 1 {
+			// Using the first answer makes the second the least recently used
+			cache.Lookup(ctx, cacheRequest("C1", questions[0]))
+		}
+	}
+
+	for i, want := range []bool{true, false, true} {
+		if cached, _ := cache.Lookup(ctx, cacheRequest("C1", questions[i])); (cached != nil) != want {
+			t.Errorf("Lookup(%q) hit = %v, want %v", questions[i], cached != nil, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+// forcedTierPrefix lets users pick a tier, e.g. "tier:large why did the deploy fail?"
+const forcedTierPrefix = "tier:"
+
+// classifierPrompt asks the classifier model to pick one of the tiers
+const classifierPrompt = `Pick the model tier that should answer the request below. Prefer the cheapest tier that can answer it well.
+
+Tiers:
+%s
+Reply with the tier name only.
+
+Request:
+"""
+%s
+"""`
+
+// RouteRequest describes a request to route
+type RouteRequest struct {
+	Prompt         string
+	HasAttachments bool     // The prompt includes linked or shared messages
+	ToolNames      []string // Tools available for the request
+	ForcedTier     string   // Tier picked by the user (optional)
+}
+
+// RouteDecision is the tier chosen for a request. A zero decision keeps the configured provider.
+type RouteDecision struct {
+	Tier     string
+	Provider string
+	Model    string // Model override; empty keeps the provider's model
+	Reason   string // Why the tier was chosen, e.g. "rule 2" or "classifier"
+}
+
+// Router chooses a provider and model tier for each request. A nil Router routes nothing.
+type Router struct {
+	cfg      config.RoutingConfig
+	registry *ProviderRegistry
+	logger   *logging.Logger
+}
+
+// NewRouter creates a router, or returns nil when routing is disabled
+func NewRouter(cfg config.RoutingConfig, registry *ProviderRegistry, logger *logging.Logger) *Router {
+	if !cfg.Enabled || len(cfg.Tiers) == 0 {
+		return nil
+	}
+	return &Router{cfg: cfg, registry: registry, logger: logger.WithName("llm-router")}
+}
+
+// ParseForcedTier splits a leading "tier:<name>" off the prompt. Unknown tiers are left in the prompt.
+func (r *Router) ParseForcedTier(prompt string) (tier, rest string) {
+	if r == nil {
+		return "", prompt
+	}
+	trimmed := strings.TrimSpace(prompt)
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 || !strings.HasPrefix(strings.ToLower(fields[0]), forcedTierPrefix) {
+		return "", prompt
+	}
+	requested := fields[0][len(forcedTierPrefix):]
+	for name := range r.cfg.Tiers {
+		if strings.EqualFold(name, requested) {
+			return name, strings.TrimSpace(strings.TrimPrefix(trimmed, fields[0]))
+		}
+	}
+	r.logger.WarnKV("Ignoring unknown forced tier", "tier", requested)
+	return "", prompt
+}
+
+// Route chooses the tier for a request: the user's forced tier, then the first matching rule,
+// then the classifier model, then the default tier.
+func (r *Router) Route(ctx context.Context, req RouteRequest) RouteDecision {
+	if r == nil {
+		return RouteDecision{}
+	}
+	if req.ForcedTier != "" {
+		return r.decision(req.ForcedTier, "forced by user")
+	}
+	for i, rule := range r.cfg.Rules {
+		if ruleMatches(rule, req) {
+			return r.decision(rule.Tier, fmt.Sprintf("rule %d", i+1))
+		}
+	}
+	if r.cfg.Classifier.Provider != "" {
+		tier, err := r.classify(ctx, req.Prompt)
+		if err == nil {
+			return r.decision(tier, "classifier")
+		}
+		r.logger.WarnKV("Routing classifier failed, using the default tier", "error", err)
+	}
+	if r.cfg.DefaultTier != "" {
+		return r.decision(r.cfg.DefaultTier, "default tier")
+	}
+	return RouteDecision{Reason: "no match"}
+}
+
+// decision builds the decision for a configured tier
+func (r *Router) decision(name, reason string) RouteDecision {
+	tier := r.cfg.Tiers[name]
+	return RouteDecision{Tier: name, Provider: tier.Provider, Model: tier.Model, Reason: reason}
+}
+
+// ruleMatches reports whether every condition set on the rule holds for the request
+func ruleMatches(rule config.RoutingRule, req RouteRequest) bool {
+	length := len([]rune(req.Prompt))
+	if rule.MinPromptChars > 0 && length < rule.MinPromptChars {
+		return false
+	}
+	if rule.MaxPromptChars > 0 && length > rule.MaxPromptChars {
+		return false
+	}
+	if rule.Attachments != nil && *rule.Attachments != req.HasAttachments {
+		return false
+	}
+	if rule.Tools != nil && *rule.Tools != mentionsTool(req.Prompt, req.ToolNames) {
+		return false
+	}
+	if len(rule.Keywords) > 0 && !containsAny(req.Prompt, rule.Keywords) {
+		return false
+	}
+	return true
+}
+
+// mentionsTool guesses whether a prompt needs tools: it names one, e.g. "list_hosts" or "list hosts"
+func mentionsTool(prompt string, toolNames []string) bool {
+	for _, name := range toolNames {
+		spaced := strings.NewReplacer("_", " ", "-", " ").Replace(name)
+		if containsAny(prompt, []string{name, spaced}) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether text contains any of the words, ignoring case
+func containsAny(text string, words []string) bool {
+	lower := strings.ToLower(text)
+	for _, word := range words {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// classify asks the classifier model which tier fits the prompt
+func (r *Router) classify(ctx context.Context, prompt string) (string, error) {
+	names := make([]string, 0, len(r.cfg.Tiers))
+	for name := range r.cfg.Tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tiers strings.Builder
+	for _, name := range names {
+		if description := r.cfg.Tiers[name].Description; description != "" {
+			fmt.Fprintf(&tiers, "- %s: %s\n", name, description)
+		} else {
+			fmt.Fprintf(&tiers, "- %s\n", name)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.cfg.Classifier.TimeoutSeconds)*time.Second)
+	defer cancel()
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(classifierPrompt, tiers.String(), prompt))}
+	choice, err := r.registry.GenerateContent(ctx, r.cfg.Classifier.Provider, messages, ProviderOptions{Model: r.cfg.Classifier.Model, MaxTokens: 20})
+	if err != nil {
+		return "", err
+	}
+	return parseTier(choice.Content, names)
+}
+
+// parseTier finds the tier named in the classifier's answer, preferring an exact answer
+func parseTier(answer string, names []string) (string, error) {
+	cleaned := strings.ToLower(strings.Trim(strings.TrimSpace(answer), "`'\".:"))
+	for _, name := range names {
+		if cleaned == strings.ToLower(name) {
+			return name, nil
+		}
+	}
+	for _, word := range strings.FieldsFunc(cleaned, func(r rune) bool {
+		return !(r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9'))
+	}) {
+		for _, name := range names {
+			if word == strings.ToLower(name) {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("classifier answered %q, which names no tier", answer)
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+func TestRouterRoute(t *testing.T) {
+	yes, no := true, false
+	cfg := config.RoutingConfig{
+		Enabled:     true,
+		DefaultTier: "standard",
+		Tiers: map[string]config.RoutingTier{
+			"small":    {Provider: "openai", Model: "gpt-4o-mini"},
+			"standard": {Provider: "openai"},
+			"large":    {Provider: "anthropic", Model: "claude-3-5-sonnet-20241022"},
+		},
+		Rules: []config.RoutingRule{
+			{Tier: "large", Keywords: []string{"root cause", "postmortem"}},
+			{Tier: "large", Attachments: &yes},
+			{Tier: "standard", Tools: &yes},
+			{Tier: "small", MaxPromptChars: 40, Tools: &no},
+		},
+	}
+	router := NewRouter(cfg, newTestRegistry(nil, 3), logging.New("test", logging.LevelError))
+	tools := []string{"list_hosts", "get-deployment"}
+
+	tests := []struct {
+		name       string
+		req        RouteRequest
+		wantTier   string
+		wantModel  string
+		wantReason string
+	}{
+		{"short chat", RouteRequest{Prompt: "thanks!"}, "small", "gpt-4o-mini", "rule 4"},
+		{"keyword", RouteRequest{Prompt: "Write the Postmortem for yesterday"}, "large", "claude-3-5-sonnet-20241022", "rule 1"},
+		{"attachments", RouteRequest{Prompt: "what is this?", HasAttachments: true}, "large", "claude-3-5-sonnet-20241022", "rule 2"},
+		{"tool named", RouteRequest{Prompt: "list hosts", ToolNames: tools}, "standard", "", "rule 3"},
+		{"long prompt", RouteRequest{Prompt: "Can you explain how our release process works from branch cut to production?"}, "standard", "", "default tier"},
+		{"forced", RouteRequest{Prompt: "thanks!", ForcedTier: "large"}, "large", "claude-3-5-sonnet-20241022", "forced by user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := router.Route(context.Background(), tt.req)
+			if got.Tier != tt.wantTier || got.Model != tt.wantModel || got.Reason != tt.wantReason {
+				t.Errorf("Route() = %+v, want tier %q, model %q, reason %q", got, tt.wantTier, tt.wantModel, tt.wantReason)
+			}
+		})
+	}
+
+	var disabled *Router
+	if got := disabled.Route(context.Background(), RouteRequest{Prompt: "hi"}); got != (RouteDecision{}) {
+		t.Errorf("nil router routed to %+v", got)
+	}
+}
+
+func TestRouterClassifier(t *testing.T) {
+	registry := newTestRegistry(nil, 3)
+	cfg := config.RoutingConfig{
+		Enabled:     true,
+		DefaultTier: "small",
+		Tiers: map[string]config.RoutingTier{
+			"small": {Provider: "openai", Description: "greetings and short questions"},
+			"large": {Provider: "anthropic", Description: "analysis and debugging"},
+		},
+		Classifier: config.RoutingClassifier{Provider: "openai", TimeoutSeconds: 5},
+	}
+	router := NewRouter(cfg, registry, logging.New("test", logging.LevelError))
+	classifier := registry.providers["openai"].(*fakeProvider)
+
+	classifier.answer = "Tier: large."
+	if got := router.Route(context.Background(), RouteRequest{Prompt: "why is db-1 slow?"}); got.Tier != "large" || got.Reason != "classifier" {
+		t.Errorf("Route() = %+v, want the classifier's tier", got)
+	}
+
+	classifier.err = errors.New("API returned unexpected status code: 503")
+	if got := router.Route(context.Background(), RouteRequest{Prompt: "why is db-1 slow?"}); got.Tier != "small" || got.Reason != "default tier" {
+		t.Errorf("Route() = %+v, want the default tier after a classifier error", got)
+	}
+}
+
+func TestParseForcedTier(t *testing.T) {
+	router := NewRouter(config.RoutingConfig{
+		Enabled: true,
+		Tiers:   map[string]config.RoutingTier{"large": {Provider: "anthropic"}},
+	}, nil, logging.New("test", logging.LevelError))
+
+	tests := []struct {
+		prompt   string
+		wantTier string
+		wantRest string
+	}{
+		{"tier:large why did the deploy fail?", "large", "why did the deploy fail?"},
+		{"  TIER:Large  summarize", "large", "summarize"},
+		{"tier:huge hello", "", "tier:huge hello"},
+		{"what tier:large means", "", "what tier:large means"},
+	}
+	for _, tt := range tests {
+		tier, rest := router.ParseForcedTier(tt.prompt)
+		if tier != tt.wantTier || rest != tt.wantRest {
+			t.Errorf("ParseForcedTier(%q) = %q, %q, want %q, %q", tt.prompt, tier, rest, tt.wantTier, tt.wantRest)
+		}
+	}
+}
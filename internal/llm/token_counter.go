@@ -0,0 +1,204 @@
+// Package llm provides implementations for language model providers
+package llm
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// approxCharsPerToken is the rough character-to-token ratio used when no tokenizer is available
+	approxCharsPerToken = 4
+
+	// defaultContextWindow is used for models we don't recognize
+	defaultContextWindow = 8192
+)
+
+// TokenCounter counts tokens in text for a specific provider/model
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// approximateCounter estimates tokens from the rune count
+type approximateCounter struct{}
+
+// CountTokens returns an approximate token count (1 token ≈ 4 characters)
+func (approximateCounter) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len([]rune(text)) + approxCharsPerToken - 1) / approxCharsPerToken
+}
+
+// tiktokenCounter counts tokens with a tiktoken encoding, falling back to approximation
+// while the encoding is loading or when it cannot be loaded (e.g. no network access to fetch the BPE ranks)
+type tiktokenCounter struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// CountTokens returns the exact number of tokens for the configured encoding
+func (c *tiktokenCounter) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	if c.encoding == nil {
+		return approximateCounter{}.CountTokens(text)
+	}
+	return len(c.encoding.EncodeOrdinary(text))
+}
+
+// encodingRetryInterval is how long to wait before loading an encoding again after a failure
+const encodingRetryInterval = 5 * time.Minute
+
+func init() {
+	tiktoken.SetBpeLoader(&bpeLoader{client: &http.Client{Timeout: bpeDownloadTimeout}})
+}
+
+// encodingEntry is the loading state of an encoding
+type encodingEntry struct {
+	encoding *tiktoken.Tiktoken
+	loading  bool
+	retryAt  time.Time // After a failure, the next load is not started before this time
+}
+
+// encodingCache holds the encodings by name; getEncoding is replaced in tests
+var (
+	encodingCache   = make(map[string]*encodingEntry)
+	encodingCacheMu sync.Mutex
+	getEncoding     = tiktoken.GetEncoding
+)
+
+// loadEncoding returns a tiktoken encoding, or nil until it is loaded. Encodings load in the background,
+// so requests never wait for the BPE download; failed loads are retried after encodingRetryInterval.
+func loadEncoding(name string) *tiktoken.Tiktoken {
+	encodingCacheMu.Lock()
+	defer encodingCacheMu.Unlock()
+
+	entry := encodingCache[name]
+	if entry == nil {
+		entry = &encodingEntry{}
+		encodingCache[name] = entry
+	}
+	if entry.encoding != nil || entry.loading || time.Now().Before(entry.retryAt) {
+		return entry.encoding
+	}
+	entry.loading = true
+	go func() {
+		enc, err := getEncoding(name)
+		encodingCacheMu.Lock()
+		defer encodingCacheMu.Unlock()
+		entry.loading = false
+		if err != nil {
+			entry.retryAt = time.Now().Add(encodingRetryInterval)
+			return
+		}
+		entry.encoding = enc
+	}()
+	return nil
+}
+
+// NewTokenCounter returns a token counter suited to the given provider type and model.
+// OpenAI models use their exact tiktoken encoding. Anthropic does not publish its tokenizer,
+// so cl100k_base is used as a close approximation. Other providers fall back to a character estimate.
+func NewTokenCounter(providerType, model string) TokenCounter {
+	switch providerType {
+	case ProviderTypeOpenAI, ProviderTypeAzureOpenAI:
+		return &tiktokenCounter{encoding: loadEncoding(openAIEncodingForModel(model))}
+	case ProviderTypeAnthropic:
+		return &tiktokenCounter{encoding: loadEncoding(tiktoken.MODEL_CL100K_BASE)}
+	case ProviderTypeBedrock:
+		if strings.HasPrefix(bedrockBaseModel(model), "claude") {
+			return &tiktokenCounter{encoding: loadEncoding(tiktoken.MODEL_CL100K_BASE)}
+		}
+		return approximateCounter{}
+	default:
+		return approximateCounter{}
+	}
+}
+
+// PreloadTokenCounter starts loading the tokenizer of a provider type and model in the background,
+// so token counts are exact from the first requests
+func PreloadTokenCounter(providerType, model string) {
+	NewTokenCounter(providerType, model)
+}
+
+// openAIEncodingForModel maps an OpenAI model name to its tiktoken encoding name
+func openAIEncodingForModel(model string) string {
+	lower := strings.ToLower(model)
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(lower, prefix) {
+			return tiktoken.MODEL_O200K_BASE
+		}
+	}
+	return tiktoken.MODEL_CL100K_BASE
+}
+
+// bedrockVendorPrefixes are the region and vendor prefixes of Bedrock model IDs
+var bedrockVendorPrefixes = map[string]bool{
+	"us": true, "eu": true, "apac": true, "global": true,
+	"anthropic": true, "meta": true, "amazon": true, "mistral": true, "cohere": true, "ai21": true, "deepseek": true,
+}
+
+// bedrockBaseModel lowercases a model name and strips Bedrock prefixes, so
+// "us.anthropic.claude-3-5-sonnet-20241022-v2:0" becomes "claude-3-5-sonnet-20241022-v2:0".
+// Other names, such as "gpt-4.1", are only lowercased.
+func bedrockBaseModel(model string) string {
+	lower := strings.ToLower(model)
+	for {
+		prefix, rest, found := strings.Cut(lower, ".")
+		if !found || !bedrockVendorPrefixes[prefix] {
+			return lower
+		}
+		lower = rest
+	}
+}
+
+// modelContextWindows lists known context window sizes by model name prefix.
+// More specific prefixes must come before more general ones.
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-5", 400000},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3.3", 131072},
+	{"llama3-1", 131072}, // Bedrock model IDs, e.g. meta.llama3-1-70b-instruct-v1:0
+	{"llama3-2", 131072},
+	{"llama3-3", 131072},
+	{"titan-text-premier", 32000},
+	{"titan-text", 8192},
+	{"nova-micro", 128000},
+	{"nova", 300000},
+	{"llama3", 8192},
+	{"mistral", 32768},
+	{"mixtral", 32768},
+	{"qwen", 32768},
+	{"gemma", 8192},
+}
+
+// ModelContextWindow returns the context window size in tokens for a model,
+// or a conservative default if the model is not recognized.
+func ModelContextWindow(model string) int {
+	lower := bedrockBaseModel(model)
+	for _, entry := range modelContextWindows {
+		if strings.HasPrefix(lower, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return defaultContextWindow
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+func TestLoadEncodingInBackground(t *testing.T) {
+	release := make(chan struct{})
+	calls := make(chan struct{}, 10)
+	var fail bool
+	getEncoding = func(name string) (*tiktoken.Tiktoken, error) {
+		calls <- struct{}{}
+		<-release
+		if fail {
+			return nil, errors.New("download failed")
+		}
+		return &tiktoken.Tiktoken{}, nil
+	}
+	t.Cleanup(func() {
+		getEncoding = tiktoken.GetEncoding
+		encodingCacheMu.Lock()
+		delete(encodingCache, "test_base")
+		encodingCacheMu.Unlock()
+	})
+	waitForLoad := func() {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			encodingCacheMu.Lock()
+			loading := encodingCache["test_base"].loading
+			encodingCacheMu.Unlock()
+			if !loading {
+				return
+			}
+		}
+		t.Fatal("encoding did not finish loading")
+	}
+
+	// Requests do not wait for the download and use the estimate meanwhile
+	fail = true
+	if enc := loadEncoding("test_base"); enc != nil {
+		t.Fatalf("loadEncoding() = %v while loading, want nil", enc)
+	}
+	<-calls
+	if enc := loadEncoding("test_base"); enc != nil || len(calls) != 0 {
+		t.Fatalf("loadEncoding() started a second load while the first runs")
+	}
+	release <- struct{}{}
+	waitForLoad()
+
+	// A failure is not retried before the retry interval, but it is retried after it
+	if enc := loadEncoding("test_base"); enc != nil || len(calls) != 0 {
+		t.Fatalf("loadEncoding() retried right after a failure")
+	}
+	encodingCacheMu.Lock()
+	encodingCache["test_base"].retryAt = time.Now()
+	encodingCacheMu.Unlock()
+	fail = false
+	loadEncoding("test_base")
+	<-calls
+	release <- struct{}{}
+	waitForLoad()
+	if enc := loadEncoding("test_base"); enc == nil {
+		t.Fatal("loadEncoding() = nil after a successful retry")
+	}
+}
+
+func TestBPELoader(t *testing.T) {
+	// "a" and "b" in base64
+	const ranks = "YQ== 0\nYg== 1\n"
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if r.URL.Path == "/slow.tiktoken" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, ranks)
+	}))
+	defer server.Close()
+	t.Setenv("TIKTOKEN_CACHE_DIR", t.TempDir())
+	loader := &bpeLoader{client: &http.Client{Timeout: 50 * time.Millisecond}}
+
+	for i := 0; i < 2; i++ {
+		got, err := loader.LoadTiktokenBpe(server.URL + "/test.tiktoken")
+		if err != nil {
+			t.Fatalf("LoadTiktokenBpe() error = %v", err)
+		}
+		if len(got) != 2 || got["a"] != 0 || got["b"] != 1 {
+			t.Errorf("LoadTiktokenBpe() = %v", got)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloads = %d, want 1 with the second load from the cache", downloads)
+	}
+
+	if _, err := loader.LoadTiktokenBpe(server.URL + "/slow.tiktoken"); err == nil {
+		t.Error("LoadTiktokenBpe() of a slow download succeeded, want a timeout")
+	}
+
+	local := filepath.Join(t.TempDir(), "local.tiktoken")
+	if err := os.WriteFile(local, []byte("YQ== x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.LoadTiktokenBpe(local); err == nil {
+		t.Error("LoadTiktokenBpe() of an invalid file succeeded")
+	}
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// TokenUsage counts the tokens used by one or more LLM calls
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt"`
+	CompletionTokens int `json:"completion"`
+	ReasoningTokens  int `json:"reasoning,omitempty"` // Part of the completion tokens
+}
+
+// Total returns the prompt and completion tokens
+func (u TokenUsage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add adds other to u
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.ReasoningTokens += other.ReasoningTokens
+}
+
+// UsageFromGenerationInfo reads the token usage a provider reported for a response
+func UsageFromGenerationInfo(info map[string]any) TokenUsage {
+	prompt, _ := info["PromptTokens"].(int)
+	completion, _ := info["CompletionTokens"].(int)
+	reasoning, _ := info["ReasoningTokens"].(int)
+	return TokenUsage{PromptTokens: prompt, CompletionTokens: completion, ReasoningTokens: reasoning}
+}
+
+// UsageCounter sums the token usage of the calls an agent makes; it is safe for concurrent use
+type UsageCounter struct {
+	mu    sync.Mutex
+	usage TokenUsage
+}
+
+// Usage returns the tokens counted so far
+func (c *UsageCounter) Usage() TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+func (c *UsageCounter) add(usage TokenUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.Add(usage)
+}
+
+// usageCounterKey is the context key of the UsageCounter
+type usageCounterKey struct{}
+
+// WithUsageCounter returns a context whose agent runs count their token usage in the returned counter
+func WithUsageCounter(ctx context.Context) (context.Context, *UsageCounter) {
+	counter := &UsageCounter{}
+	return context.WithValue(ctx, usageCounterKey{}, counter), counter
+}
+
+// usageCountingModel adds the usage of every call to the counter, since agents only see the response text
+type usageCountingModel struct {
+	llms.Model
+	counter *UsageCounter
+}
+
+// GenerateContent implements llms.Model
+func (m *usageCountingModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	resp, err := m.Model.GenerateContent(ctx, messages, options...)
+	if err == nil && len(resp.Choices) > 0 {
+		m.counter.add(UsageFromGenerationInfo(resp.Choices[0].GenerationInfo))
+	}
+	return resp, err
+}
+
+// Call implements the deprecated single-prompt interface of llms.Model
+func (m *usageCountingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// countUsage wraps the model to count its usage when the context carries a UsageCounter
+func countUsage(ctx context.Context, model llms.Model) llms.Model {
+	if counter, ok := ctx.Value(usageCounterKey{}).(*UsageCounter); ok {
+		return &usageCountingModel{Model: model, counter: counter}
+	}
+	return model
+}
@@ -28,6 +28,11 @@ func (t *ToolInfo) Description() string {
 	return t.ToolDescription + "\n The input schema is: " + string(t.InputSchemaBytes)
 }
 
+// Parameters returns the JSON Schema of the tool's arguments, for native tool calling
+func (t *ToolInfo) Parameters() map[string]interface{} {
+	return t.InputSchema
+}
+
 func (t *ToolInfo) Call(ctx context.Context, input string) (string, error) {
 	var args map[string]interface{}
 	err := json.Unmarshal([]byte(input), &args)
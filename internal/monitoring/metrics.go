@@ -14,6 +14,11 @@ const (
 
 	MetricLabelType  = "type"
 	MetricLabelModel = "model"
+
+	MetricLabelProvider = "provider"
+	MetricLabelOutcome  = "outcome"
+
+	MetricLabelResult = "result"
 )
 
 var (
@@ -32,11 +37,43 @@ var (
 		},
 		[]string{MetricLabelType, MetricLabelModel},
 	)
+	LLMProviderRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%sllm_provider_requests_total", prefix),
+			Help: "Total number of LLM provider calls by outcome (success, failed, skipped)",
+		},
+		[]string{MetricLabelProvider, MetricLabelOutcome},
+	)
+	LLMCircuitOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%sllm_circuit_open", prefix),
+			Help: "Whether an LLM provider is skipped after repeated failures (1) or in use (0)",
+		},
+		[]string{MetricLabelProvider},
+	)
+	LLMCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%sllm_cache_requests_total", prefix),
+			Help: "Total number of LLM response cache lookups by result (hit_exact, hit_semantic, miss, bypass)",
+		},
+		[]string{MetricLabelResult},
+	)
+	LLMPromptCacheTokens = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%sllm_prompt_cache_tokens_total", prefix),
+			Help: "Total number of prompt tokens read from or written to the provider's prompt cache",
+		},
+		[]string{MetricLabelType, MetricLabelModel},
+	)
 )
 
 func RegisterMetrics() {
 	prometheus.MustRegister(
 		ToolInvocations,
 		LLMTokensPerRequest,
+		LLMProviderRequests,
+		LLMCircuitOpen,
+		LLMCacheRequests,
+		LLMPromptCacheTokens,
 	)
 }
@@ -27,6 +27,7 @@ type TracingHandler interface {
 	// Span attribute setters
 	SetOutput(span trace.Span, output string)
 	SetTokenUsage(span trace.Span, promptTokens, completionTokens, reasoningTokens, totalTokens int)
+	SetCacheUsage(span trace.Span, cacheReadTokens, cacheWriteTokens int)
 	SetDuration(span trace.Span, duration time.Duration)
 
 	// Status and error handling
@@ -62,6 +63,8 @@ func (n noOpHandler) SetOutput(span trace.Span, output string) {}
 func (n noOpHandler) SetTokenUsage(span trace.Span, promptTokens, completionTokens, reasoningTokens, totalTokens int) {
 }
 
+func (n noOpHandler) SetCacheUsage(span trace.Span, cacheReadTokens, cacheWriteTokens int) {}
+
 func (n noOpHandler) SetDuration(span trace.Span, duration time.Duration) {}
 
 func (n noOpHandler) RecordError(span trace.Span, err error, level string) {}
@@ -222,6 +222,16 @@ func (p *LangfuseProvider) SetTokenUsage(span OtelTrace.Span, promptTokens, comp
 	}
 }
 
+// SetCacheUsage records prompt cache reads and writes. They are part of the prompt tokens set by SetTokenUsage.
+func (p *LangfuseProvider) SetCacheUsage(span OtelTrace.Span, cacheReadTokens, cacheWriteTokens int) {
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.cache_read_input_tokens", cacheReadTokens),
+		attribute.Int("gen_ai.usage.cache_creation_input_tokens", cacheWriteTokens),
+		attribute.Int("llm.token_count.prompt_details.cache_read", cacheReadTokens),
+		attribute.Int("llm.token_count.prompt_details.cache_write", cacheWriteTokens),
+	)
+}
+
 func (p *LangfuseProvider) SetDuration(span OtelTrace.Span, duration time.Duration) {
 	span.SetAttributes(
 		attribute.Float64("duration.seconds", duration.Seconds()),
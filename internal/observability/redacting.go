@@ -0,0 +1,76 @@
+package observability
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redactingHandler masks sensitive values in span inputs, outputs and metadata before they
+// reach the tracing backend
+type redactingHandler struct {
+	TracingHandler
+	redact func(string) string
+}
+
+// NewRedactingHandler wraps a tracing handler so every recorded payload passes through redact
+func NewRedactingHandler(handler TracingHandler, redact func(string) string) TracingHandler {
+	if redact == nil {
+		return handler
+	}
+	return &redactingHandler{TracingHandler: handler, redact: redact}
+}
+
+func (h *redactingHandler) redactMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		redacted[key] = h.redact(value)
+	}
+	return redacted
+}
+
+func (h *redactingHandler) StartTrace(ctx context.Context, name string, input string, metadata map[string]string) (context.Context, trace.Span) {
+	return h.TracingHandler.StartTrace(ctx, name, h.redact(input), h.redactMetadata(metadata))
+}
+
+func (h *redactingHandler) StartSpan(ctx context.Context, name string, spanType string, input string, metadata map[string]string) (context.Context, trace.Span) {
+	return h.TracingHandler.StartSpan(ctx, name, spanType, h.redact(input), h.redactMetadata(metadata))
+}
+
+func (h *redactingHandler) StartLLMSpan(ctx context.Context, name string, model string, input string, parameters map[string]interface{}) (context.Context, trace.Span) {
+	redacted := make(map[string]interface{}, len(parameters))
+	for key, value := range parameters {
+		if s, ok := value.(string); ok {
+			value = h.redact(s)
+		}
+		redacted[key] = value
+	}
+	return h.TracingHandler.StartLLMSpan(ctx, name, model, h.redact(input), redacted)
+}
+
+func (h *redactingHandler) SetOutput(span trace.Span, output string) {
+	h.TracingHandler.SetOutput(span, h.redact(output))
+}
+
+func (h *redactingHandler) RecordError(span trace.Span, err error, level string) {
+	if err != nil {
+		err = redactedError{message: h.redact(err.Error()), cause: err}
+	}
+	h.TracingHandler.RecordError(span, err, level)
+}
+
+func (h *redactingHandler) RecordSuccess(span trace.Span, message string) {
+	h.TracingHandler.RecordSuccess(span, h.redact(message))
+}
+
+// redactedError carries a redacted message while keeping the original error for errors.Is/As
+type redactedError struct {
+	message string
+	cause   error
+}
+
+func (e redactedError) Error() string { return e.message }
+func (e redactedError) Unwrap() error { return e.cause }
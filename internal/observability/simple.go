@@ -200,6 +200,13 @@ func (p *SimpleProvider) SetTokenUsage(span trace.Span, promptTokens, completion
 	)
 }
 
+func (p *SimpleProvider) SetCacheUsage(span trace.Span, cacheReadTokens, cacheWriteTokens int) {
+	span.SetAttributes(
+		attribute.Int("llm.usage.cache_read_tokens", cacheReadTokens),
+		attribute.Int("llm.usage.cache_write_tokens", cacheWriteTokens),
+	)
+}
+
 func (p *SimpleProvider) SetDuration(span trace.Span, duration time.Duration) {
 	span.SetAttributes(
 		attribute.Float64("duration.seconds", duration.Seconds()),
@@ -0,0 +1,220 @@
+// Package prompt renders the system prompt, a Go text/template filled in per request with the user,
+// channel, date, tools and model. Prompts loaded from a file are reloaded when the file changes.
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+const (
+	watchInterval   = 2 * time.Second       // How often the prompt file is checked for changes
+	maxReadAttempts = 3                     // Reads of a prompt file that is being written before giving up
+	readRetryDelay  = 50 * time.Millisecond // Wait before reading a file that changed during the read again
+)
+
+// Data holds the variables available to system prompt templates
+type Data struct {
+	UserName    string            // Name of the user asking
+	UserTitle   string            // Title from the user's Slack profile
+	ChannelID   string            // Channel the request comes from
+	ChannelName string            // Channel name without "#"; empty for direct messages
+	Now         time.Time         // Current time in the user's time zone, for custom formats
+	Date        string            // Today in the user's time zone, e.g. "2026-03-14"
+	Time        string            // e.g. "15:04"
+	Weekday     string            // e.g. "Saturday"
+	TimeZone    string            // The user's time zone, e.g. "Europe/Berlin"; "UTC" if unknown
+	Tools       []string          // Names of the available tools, sorted
+	Provider    string            // LLM provider answering the request
+	Model       string            // Model answering the request
+	Extras      map[string]string // Configured extras, with the channel's extras overriding the global ones
+}
+
+// NewData fills in the time variables for the current time in a time zone; unknown zones use UTC
+func NewData(now time.Time, timeZone string) Data {
+	location, err := time.LoadLocation(timeZone)
+	if err != nil || timeZone == "" {
+		location, timeZone = time.UTC, "UTC"
+	}
+	now = now.In(location)
+	return Data{
+		Now:      now,
+		Date:     now.Format("2006-01-02"),
+		Time:     now.Format("15:04"),
+		Weekday:  now.Weekday().String(),
+		TimeZone: timeZone,
+	}
+}
+
+// sampleData is used to check templates when they are loaded
+var sampleData = Data{
+	UserName:    "Alice Example",
+	UserTitle:   "SRE",
+	ChannelID:   "C0123",
+	ChannelName: "general",
+	Now:         time.Date(2026, 3, 14, 15, 4, 0, 0, time.UTC),
+	Date:        "2026-03-14",
+	Time:        "15:04",
+	Weekday:     "Saturday",
+	TimeZone:    "UTC",
+	Tools:       []string{"search"},
+	Provider:    "openai",
+	Model:       "gpt-4o",
+	Extras:      map[string]string{},
+}
+
+// Template is the system prompt template; it is safe for concurrent use.
+// A nil Template renders an empty prompt.
+type Template struct {
+	file     string        // Prompt file, reloaded on changes; empty for inline prompts
+	interval time.Duration // How often the file is checked
+	logger   *logging.Logger
+
+	mu      sync.RWMutex
+	tmpl    *template.Template
+	source  string // Text of tmpl
+	modTime time.Time
+	size    int64
+}
+
+// New parses the inline prompt, or the prompt file when text is empty. Returns nil when neither is set.
+func New(text, file string, logger *logging.Logger) (*Template, error) {
+	if text == "" && file == "" {
+		return nil, nil
+	}
+	t := &Template{interval: watchInterval, logger: logger.WithName("prompt")}
+	if text != "" {
+		tmpl, err := parse(text, "customPrompt")
+		if err != nil {
+			return nil, err
+		}
+		t.tmpl, t.source = tmpl, text
+		return t, nil
+	}
+	t.file = file
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Validate checks that the inline prompt, or the prompt file when text is empty, is a valid template
+func Validate(text, file string) error {
+	_, err := New(text, file, logging.New("prompt", logging.LevelError))
+	return err
+}
+
+// Render executes the template. A template that fails at runtime is logged and its source is returned,
+// so the model still gets the instructions.
+func (t *Template) Render(data Data) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.RLock()
+	tmpl, source := t.tmpl, t.source
+	t.mu.RUnlock()
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		t.logger.WarnKV("Failed to render system prompt", "error", err)
+		return source
+	}
+	return sb.String()
+}
+
+// Watch reloads the prompt file whenever it changes, until ctx is done. A file that fails to load
+// is logged and the previous prompt is kept. Inline prompts are not watched.
+func (t *Template) Watch(ctx context.Context) {
+	if t == nil || t.file == "" {
+		return
+	}
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(t.file)
+			if err != nil {
+				t.logger.WarnKV("Failed to check system prompt file", "file", t.file, "error", err)
+				continue
+			}
+			t.mu.RLock()
+			changed := !info.ModTime().Equal(t.modTime) || info.Size() != t.size
+			t.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := t.load(); err != nil {
+				t.logger.ErrorKV("Keeping the previous system prompt", "file", t.file, "error", err)
+				continue
+			}
+			t.logger.InfoKV("Reloaded system prompt", "file", t.file)
+		}
+	}
+}
+
+// load reads and parses the prompt file. A file that changes while it is read is read again, and an
+// empty file is rejected, so a writer caught between truncating and rewriting never clears the prompt.
+func (t *Template) load() error {
+	readErr := func(err error) error {
+		return customErrors.WrapConfigError(err, "custom_prompt_file_read_failed", fmt.Sprintf("failed to read custom prompt file %s", t.file))
+	}
+	var content []byte
+	var info os.FileInfo
+	for attempt := 1; ; attempt++ {
+		before, err := os.Stat(t.file)
+		if err != nil {
+			return readErr(err)
+		}
+		if content, err = os.ReadFile(t.file); err != nil {
+			return readErr(err)
+		}
+		if info, err = os.Stat(t.file); err != nil {
+			return readErr(err)
+		}
+		if info.ModTime().Equal(before.ModTime()) && info.Size() == before.Size() && int64(len(content)) == info.Size() {
+			break
+		}
+		if attempt == maxReadAttempts {
+			return customErrors.NewConfigErrorf("custom_prompt_file_read_failed", "custom prompt file %s kept changing while it was read", t.file)
+		}
+		time.Sleep(readRetryDelay)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return customErrors.NewConfigErrorf("custom_prompt_file_empty", "custom prompt file %s is empty", t.file)
+	}
+
+	tmpl, err := parse(string(content), t.file)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A broken file is not read again until it changes
+	t.modTime, t.size = info.ModTime(), info.Size()
+	if err != nil {
+		return err
+	}
+	t.tmpl, t.source = tmpl, string(content)
+	return nil
+}
+
+// parse parses a prompt template and executes it once with sample data, so references to unknown
+// variables fail when the prompt is loaded rather than on the first request
+func parse(text, name string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, customErrors.WrapConfigError(err, "invalid_prompt_template", fmt.Sprintf("invalid system prompt template in %s", name))
+	}
+	if err := tmpl.Execute(io.Discard, sampleData); err != nil {
+		return nil, customErrors.WrapConfigError(err, "invalid_prompt_template", fmt.Sprintf("invalid system prompt template in %s", name))
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,134 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := New("You help {{.UserName}} ({{.UserTitle}}) in #{{.ChannelName}} on {{.Weekday}}, {{.Date}} {{.Time}} {{.TimeZone}}. "+
+		"Team: {{or .Extras.team \"none\"}}. Tools: {{range $i, $t := .Tools}}{{if $i}}, {{end}}{{$t}}{{end}}. Model: {{.Model}}.", "", logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// 23:30 UTC is already the next day in Tokyo
+	data := NewData(time.Date(2026, 3, 14, 23, 30, 0, 0, time.UTC), "Asia/Tokyo")
+	data.UserName, data.UserTitle, data.ChannelName = "Alice", "SRE", "ops"
+	data.Tools, data.Model = []string{"get_logs", "restart_pod"}, "gpt-4o"
+	want := "You help Alice (SRE) in #ops on Sunday, 2026-03-15 08:30 Asia/Tokyo. Team: none. Tools: get_logs, restart_pod. Model: gpt-4o."
+	if got := tmpl.Render(data); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	data.Extras = map[string]string{"team": "platform"}
+	if got := tmpl.Render(data); !strings.Contains(got, "Team: platform.") {
+		t.Errorf("Render() with extras = %q", got)
+	}
+
+	if data := NewData(time.Now(), "Not/AZone"); data.TimeZone != "UTC" {
+		t.Errorf("NewData() with an unknown zone uses %q, want UTC", data.TimeZone)
+	}
+	var disabled *Template
+	if got := disabled.Render(data); got != "" {
+		t.Errorf("nil Template rendered %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.txt")
+	if err := os.WriteFile(valid, []byte("Hello {{.UserName}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte(" \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		file    string
+		wantErr string
+	}{
+		{"no prompt", "", "", ""},
+		{"plain text", "You are a helpful assistant.", "", ""},
+		{"inline template", "Today is {{.Date}}", "", ""},
+		{"prompt file", "", valid, ""},
+		{"syntax error", "Hello {{.UserName", "", "invalid system prompt template"},
+		{"unknown variable", "Hello {{.User}}", "", "invalid system prompt template"},
+		{"unknown function", "Tools: {{join .Tools \", \"}}", "", "invalid system prompt template"},
+		{"missing file", "", filepath.Join(dir, "missing.txt"), "failed to read custom prompt file"},
+		{"empty file", "", empty, "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.text, tt.file)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prompt.txt")
+	// Each version is written to a temporary file and renamed, so the watcher never sees a partial write
+	writeFile := func(content string) {
+		t.Helper()
+		tmp := filepath.Join(dir, "prompt.txt.tmp")
+		if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("Version 1 for {{.UserName}}")
+	tmpl, err := New("", file, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tmpl.interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tmpl.Watch(ctx)
+
+	data := Data{UserName: "Alice"}
+	waitForRender := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if tmpl.Render(data) == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Render() = %q, want %q", tmpl.Render(data), want)
+	}
+
+	writeFile("Version 2, updated for {{.UserName}}")
+	waitForRender("Version 2, updated for Alice")
+
+	// An empty file, as seen between a writer truncating and rewriting it, and a broken template
+	// are reported and the previous prompt stays in use
+	for _, content := range []string{"", "Version 3 for {{.UserName"} {
+		writeFile(content)
+		time.Sleep(100 * time.Millisecond)
+		waitForRender("Version 2, updated for Alice")
+	}
+
+	writeFile("Version 4 for {{.UserName}}")
+	waitForRender("Version 4 for Alice")
+}
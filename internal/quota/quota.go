@@ -0,0 +1,310 @@
+// Package quota accounts the LLM tokens used per user, channel and provider and enforces the
+// daily and monthly token quotas of users and channels.
+//
+// Usage is kept in day and month buckets (UTC) and persisted to a JSON file every few seconds
+// and on shutdown, so it survives restarts and configuration reloads.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+	"github.com/tuannvm/slack-mcp-client/internal/llm"
+)
+
+// Scopes usage is accounted in; quotas apply to users and channels
+const (
+	ScopeUser     = "user"
+	ScopeChannel  = "channel"
+	ScopeProvider = "provider"
+)
+
+// Quota periods
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Bucket name formats
+const (
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// monthsKept is how many monthly buckets are kept for reporting
+const monthsKept = 12
+
+// flushInterval is how often recorded usage is written to the usage file
+const flushInterval = 5 * time.Second
+
+// Exceeded describes an exhausted quota
+type Exceeded struct {
+	Scope    string // ScopeUser or ScopeChannel
+	Period   string // PeriodDaily or PeriodMonthly
+	Used     int
+	Limit    int
+	ResetsAt time.Time
+}
+
+// usageData is the persisted usage, by bucket and then by key such as "user:U123"
+type usageData struct {
+	Days      map[string]map[string]llm.TokenUsage `json:"days"`
+	Months    map[string]map[string]llm.TokenUsage `json:"months"`
+	Overrides map[string]string                    `json:"overrides,omitempty"` // Key to the month its quota is lifted for
+}
+
+// Tracker records token usage and checks quotas; it is safe for concurrent use.
+// A nil Tracker records nothing and never reports an exhausted quota.
+type Tracker struct {
+	cfg    config.QuotaConfig
+	logger *logging.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	data  usageData
+	dirty bool // Usage changed since the file was last written
+
+	saveMu sync.Mutex // Keeps writes of the usage file in order
+}
+
+// New creates a tracker with the usage persisted in the configured file, or returns nil when quotas are disabled
+func New(cfg config.QuotaConfig, logger *logging.Logger) (*Tracker, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	t := &Tracker{
+		cfg:    cfg,
+		logger: logger.WithName("quota"),
+		now:    time.Now,
+		data:   usageData{Days: map[string]map[string]llm.TokenUsage{}, Months: map[string]map[string]llm.TokenUsage{}},
+	}
+	content, err := os.ReadFile(cfg.UsageFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, customErrors.WrapConfigError(err, "quota_usage_unreadable", fmt.Sprintf("failed to read usage file %s", cfg.UsageFile))
+	}
+	if err == nil {
+		// A corrupt file is an error rather than a reset, which would silently grant fresh quotas
+		if err := json.Unmarshal(content, &t.data); err != nil {
+			return nil, customErrors.WrapConfigError(err, "quota_usage_invalid", fmt.Sprintf("failed to parse usage file %s", cfg.UsageFile))
+		}
+		if t.data.Days == nil {
+			t.data.Days = map[string]map[string]llm.TokenUsage{}
+		}
+		if t.data.Months == nil {
+			t.data.Months = map[string]map[string]llm.TokenUsage{}
+		}
+	}
+	return t, nil
+}
+
+// Check returns the first exhausted quota of the user or channel, or nil when both may still use the LLM
+func (t *Tracker) Check(userID, channelID string) *Exceeded {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now().UTC()
+	if exceeded := t.check(now, ScopeUser, userID, t.cfg.UserLimits(userID)); exceeded != nil {
+		return exceeded
+	}
+	return t.check(now, ScopeChannel, channelID, t.cfg.ChannelLimits(channelID))
+}
+
+// check compares one scope's usage with its limits; the caller holds the lock
+func (t *Tracker) check(now time.Time, scope, id string, limits config.QuotaLimits) *Exceeded {
+	if id == "" {
+		return nil
+	}
+	k := key(scope, id)
+	if t.data.Overrides[k] == now.Format(monthFormat) {
+		return nil
+	}
+	if used := t.data.Days[now.Format(dayFormat)][k].Total(); limits.DailyTokens > 0 && used >= limits.DailyTokens {
+		return &Exceeded{Scope: scope, Period: PeriodDaily, Used: used, Limit: limits.DailyTokens,
+			ResetsAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)}
+	}
+	if used := t.data.Months[now.Format(monthFormat)][k].Total(); limits.MonthlyTokens > 0 && used >= limits.MonthlyTokens {
+		return &Exceeded{Scope: scope, Period: PeriodMonthly, Used: used, Limit: limits.MonthlyTokens,
+			ResetsAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return nil
+}
+
+// Record adds the usage of a request to the user, channel and provider that made and served it
+func (t *Tracker) Record(userID, channelID, provider string, usage llm.TokenUsage) {
+	if t == nil || usage.Total() == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now().UTC()
+	day, month := now.Format(dayFormat), now.Format(monthFormat)
+	for scope, id := range map[string]string{ScopeUser: userID, ScopeChannel: channelID, ScopeProvider: provider} {
+		if id == "" {
+			continue
+		}
+		k := key(scope, id)
+		addUsage(t.data.Days, day, k, usage)
+		addUsage(t.data.Months, month, k, usage)
+	}
+	t.prune(now)
+	t.dirty = true
+}
+
+// Override lifts the quotas of a user or channel until the end of the current month
+func (t *Tracker) Override(scope, id string) (time.Time, error) {
+	if t == nil {
+		return time.Time{}, customErrors.NewConfigError("quota_disabled", "quotas are not enabled")
+	}
+	t.mu.Lock()
+	now := t.now().UTC()
+	if t.data.Overrides == nil {
+		t.data.Overrides = map[string]string{}
+	}
+	t.data.Overrides[key(scope, id)] = now.Format(monthFormat)
+	t.dirty = true
+	t.mu.Unlock()
+	t.logger.InfoKV("Lifted LLM quota", "scope", scope, "id", id, "month", now.Format(monthFormat))
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC), t.Flush()
+}
+
+// Run writes recorded usage to the usage file every flushInterval until ctx is done.
+// Call Flush after it returns to write the last changes.
+func (t *Tracker) Run(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				t.logger.WarnKV("Failed to persist LLM usage", "file", t.cfg.UsageFile, "error", err)
+			}
+		}
+	}
+}
+
+// Flush writes usage recorded since the last write to the usage file. The file is written
+// outside the lock, so quota checks do not wait for the disk.
+func (t *Tracker) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return nil
+	}
+	content, err := json.MarshalIndent(t.data, "", "  ")
+	t.dirty = false
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	if err := t.save(content); err != nil {
+		// Try again on the next flush
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Usage returns the tokens a user, channel or provider used today and this month
+func (t *Tracker) Usage(scope, id string) (daily, monthly llm.TokenUsage) {
+	if t == nil {
+		return daily, monthly
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now().UTC()
+	k := key(scope, id)
+	return t.data.Days[now.Format(dayFormat)][k], t.data.Months[now.Format(monthFormat)][k]
+}
+
+// Limits returns the configured limits of a user or channel
+func (t *Tracker) Limits(scope, id string) config.QuotaLimits {
+	if t == nil {
+		return config.QuotaLimits{}
+	}
+	if scope == ScopeChannel {
+		return t.cfg.ChannelLimits(id)
+	}
+	return t.cfg.UserLimits(id)
+}
+
+// prune drops day buckets before the previous month, month buckets older than monthsKept
+// and expired overrides; the caller holds the lock
+func (t *Tracker) prune(now time.Time) {
+	firstDay := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC).Format(dayFormat)
+	for day := range t.data.Days {
+		if day < firstDay {
+			delete(t.data.Days, day)
+		}
+	}
+	firstMonth := time.Date(now.Year(), now.Month()-monthsKept+1, 1, 0, 0, 0, 0, time.UTC).Format(monthFormat)
+	for month := range t.data.Months {
+		if month < firstMonth {
+			delete(t.data.Months, month)
+		}
+	}
+	for k, month := range t.data.Overrides {
+		if month != now.Format(monthFormat) {
+			delete(t.data.Overrides, k)
+		}
+	}
+}
+
+// save writes the usage file atomically, so a crash never leaves it half written; the caller holds saveMu
+func (t *Tracker) save(content []byte) error {
+	dir := filepath.Dir(t.cfg.UsageFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(t.cfg.UsageFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // Already gone after a successful rename
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	return os.Rename(tmp.Name(), t.cfg.UsageFile)
+}
+
+// key identifies a user, channel or provider in the usage buckets
+func key(scope, id string) string {
+	return scope + ":" + id
+}
+
+// addUsage adds usage to a key of a bucket
+func addUsage(buckets map[string]map[string]llm.TokenUsage, bucket, k string, usage llm.TokenUsage) {
+	if buckets[bucket] == nil {
+		buckets[bucket] = map[string]llm.TokenUsage{}
+	}
+	total := buckets[bucket][k]
+	total.Add(usage)
+	buckets[bucket][k] = total
+}
@@ -0,0 +1,114 @@
+package quota
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+	"github.com/tuannvm/slack-mcp-client/internal/llm"
+)
+
+func newTestTracker(t *testing.T, file string, now time.Time) *Tracker {
+	t.Helper()
+	cfg := config.QuotaConfig{
+		Enabled:   true,
+		UsageFile: file,
+		User:      config.QuotaLimits{DailyTokens: 1000, MonthlyTokens: 5000},
+		Channel:   config.QuotaLimits{MonthlyTokens: 8000},
+		Users:     map[string]config.QuotaLimits{"UVIP": {}},
+	}
+	tracker, err := New(cfg, logging.New("test", logging.LevelError))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tracker.now = func() time.Time { return now }
+	return tracker
+}
+
+func TestTrackerCheck(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		userID     string
+		usage      []llm.TokenUsage
+		override   bool
+		wantScope  string
+		wantPeriod string
+		wantReset  time.Time
+	}{
+		{"under quota", "U1", []llm.TokenUsage{{PromptTokens: 400, CompletionTokens: 100}}, false, "", "", time.Time{}},
+		{"daily quota", "U1", []llm.TokenUsage{{PromptTokens: 800, CompletionTokens: 200}}, false, ScopeUser, PeriodDaily, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"user without limits", "UVIP", []llm.TokenUsage{{PromptTokens: 6000}}, false, "", "", time.Time{}},
+		{"channel quota", "UVIP", []llm.TokenUsage{{PromptTokens: 9000}}, false, ScopeChannel, PeriodMonthly, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"overridden", "U1", []llm.TokenUsage{{PromptTokens: 2000}}, true, "", "", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker(t, filepath.Join(t.TempDir(), "usage.json"), now)
+			for _, usage := range tt.usage {
+				tracker.Record(tt.userID, "C1", "openai", usage)
+			}
+			if tt.override {
+				if _, err := tracker.Override(ScopeUser, tt.userID); err != nil {
+					t.Fatalf("Override() error = %v", err)
+				}
+			}
+			exceeded := tracker.Check(tt.userID, "C1")
+			if tt.wantScope == "" {
+				if exceeded != nil {
+					t.Fatalf("Check() = %+v, want nil", exceeded)
+				}
+				return
+			}
+			if exceeded == nil || exceeded.Scope != tt.wantScope || exceeded.Period != tt.wantPeriod || !exceeded.ResetsAt.Equal(tt.wantReset) {
+				t.Fatalf("Check() = %+v, want %s %s quota resetting at %v", exceeded, tt.wantScope, tt.wantPeriod, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestTrackerPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "usage.json")
+	day := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+
+	tracker := newTestTracker(t, file, day)
+	tracker.Record("U1", "C1", "anthropic", llm.TokenUsage{PromptTokens: 300, CompletionTokens: 50, ReasoningTokens: 20})
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("usage file written on Record, want it written on Flush: %v", err)
+	}
+	if _, err := tracker.Override(ScopeChannel, "C2"); err != nil {
+		t.Fatalf("Override() error = %v", err)
+	}
+
+	// A new tracker, as after a restart, sees the same usage on the next day of the month
+	reloaded := newTestTracker(t, file, day.Add(24*time.Hour))
+	daily, monthly := reloaded.Usage(ScopeProvider, "anthropic")
+	if daily.Total() != 0 || monthly != (llm.TokenUsage{PromptTokens: 300, CompletionTokens: 50, ReasoningTokens: 20}) {
+		t.Errorf("Usage() = %+v, %+v", daily, monthly)
+	}
+	if reloaded.data.Overrides["channel:C2"] != "2026-03" {
+		t.Errorf("override was not persisted: %v", reloaded.data.Overrides)
+	}
+
+	// Months later, old buckets and the expired override are pruned on the next record
+	later := newTestTracker(t, file, time.Date(2027, 3, 2, 0, 0, 0, 0, time.UTC))
+	later.Record("U1", "C1", "anthropic", llm.TokenUsage{PromptTokens: 1})
+	if err := later.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(later.data.Days) != 1 || len(later.data.Months) != 1 || len(later.data.Overrides) != 0 {
+		t.Errorf("after pruning days = %v, months = %v, overrides = %v", later.data.Days, later.data.Months, later.data.Overrides)
+	}
+
+	var disabled *Tracker
+	disabled.Record("U1", "C1", "openai", llm.TokenUsage{PromptTokens: 1})
+	if disabled.Check("U1", "C1") != nil {
+		t.Errorf("nil tracker reported an exhausted quota")
+	}
+	if err := disabled.Flush(); err != nil {
+		t.Errorf("nil tracker Flush() error = %v", err)
+	}
+}
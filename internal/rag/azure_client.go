@@ -0,0 +1,117 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/azure"
+)
+
+// AzureOpenAIClient is a client for Azure OpenAI embedding deployments
+type AzureOpenAIClient struct {
+	endpoint   string
+	deployment string
+	apiVersion string
+	apiKey     string // Empty when the HTTP client authenticates with Entra ID
+	httpClient *http.Client
+}
+
+// NewAzureOpenAIClient creates an Azure OpenAI embedding client that authenticates with an API key
+func NewAzureOpenAIClient(endpoint, deployment, apiVersion, apiKey string) *AzureOpenAIClient {
+	return newAzureOpenAIClient(endpoint, deployment, apiVersion, apiKey, &http.Client{Timeout: 120 * time.Second})
+}
+
+// NewAzureOpenAIEntraIDClient creates an Azure OpenAI embedding client that authenticates with
+// an Entra ID token from the Azure environment
+func NewAzureOpenAIEntraIDClient(endpoint, deployment, apiVersion string) (*AzureOpenAIClient, error) {
+	httpClient, err := azure.NewTokenClient(nil, 120*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return newAzureOpenAIClient(endpoint, deployment, apiVersion, "", httpClient), nil
+}
+
+func newAzureOpenAIClient(endpoint, deployment, apiVersion, apiKey string, httpClient *http.Client) *AzureOpenAIClient {
+	if apiVersion == "" {
+		apiVersion = azure.DefaultAPIVersion
+	}
+	return &AzureOpenAIClient{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		deployment: deployment,
+		apiVersion: apiVersion,
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}
+}
+
+// azureEmbedRequest represents the request payload for embeddings
+type azureEmbedRequest struct {
+	Input []string `json:"input"`
+}
+
+// azureEmbedResponse represents the response from the embeddings API
+type azureEmbedResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// EmbedQuery embeds a query string with the configured embedding deployment
+// Returns the embedding vector and token usage
+func (c *AzureOpenAIClient) EmbedQuery(ctx context.Context, query string) (*EmbeddingResult, error) {
+	jsonData, err := json.Marshal(azureEmbedRequest{Input: []string{query}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s", c.endpoint, c.deployment, c.apiVersion)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("api-key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var azureResp azureEmbedResponse
+	if err := json.Unmarshal(body, &azureResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(azureResp.Data) == 0 || len(azureResp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding vector")
+	}
+
+	return &EmbeddingResult{
+		Embedding:  azureResp.Data[0].Embedding,
+		TokensUsed: azureResp.Usage.TotalTokens,
+		Model:      azureResp.Model,
+	}, nil
+}
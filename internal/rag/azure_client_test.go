@@ -0,0 +1,66 @@
+package rag
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/azure"
+)
+
+// staticCredential returns a fixed Entra ID token
+type staticCredential struct{}
+
+func (staticCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "entra-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestAzureOpenAIClient_EmbedQuery(t *testing.T) {
+	var gotURL, gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotKey = r.Header.Get("api-key")
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"data":[{"embedding":[0.1,0.2,0.3],"index":0}],"model":"text-embedding-3-small","usage":{"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	tokenClient, err := azure.NewTokenClient(staticCredential{}, time.Second)
+	if err != nil {
+		t.Fatalf("NewTokenClient() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		client   *AzureOpenAIClient
+		wantKey  string
+		wantAuth string
+	}{
+		{"api key", NewAzureOpenAIClient(server.URL+"/", "embed", "", "secret"), "secret", ""},
+		{"entra id", newAzureOpenAIClient(server.URL, "embed", "2024-06-01", "", tokenClient), "", "Bearer entra-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.client.EmbedQuery(context.Background(), "What is revenue?")
+			if err != nil {
+				t.Fatalf("EmbedQuery() error = %v", err)
+			}
+			if len(result.Embedding) != 3 || result.TokensUsed != 4 || result.Model != "text-embedding-3-small" {
+				t.Errorf("EmbedQuery() = %+v", result)
+			}
+			if gotURL != "/openai/deployments/embed/embeddings?api-version="+tt.client.apiVersion {
+				t.Errorf("request URL = %q", gotURL)
+			}
+			if gotKey != tt.wantKey || gotAuth != tt.wantAuth {
+				t.Errorf("api-key = %q, Authorization = %q, want %q, %q", gotKey, gotAuth, tt.wantKey, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -2,15 +2,21 @@ package rag
 
 import (
 	"fmt"
+
+	appconfig "github.com/tuannvm/slack-mcp-client/internal/config"
 )
 
 // EmbeddingProviderConfig contains embedding provider-specific settings
 type EmbeddingProviderConfig struct {
-	APIKey string `json:"apiKey,omitempty"` // API key for the embedding provider
+	APIKey     string `json:"apiKey,omitempty"`     // API key for the embedding provider
+	BaseURL    string `json:"baseUrl,omitempty"`    // Azure OpenAI: resource endpoint
+	Deployment string `json:"deployment,omitempty"` // Azure OpenAI: embedding deployment name
+	APIVersion string `json:"apiVersion,omitempty"` // Azure OpenAI: API version
+	AuthType   string `json:"authType,omitempty"`   // Azure OpenAI: apiKey or entraId
 }
 
 // CreateEmbeddingProvider creates an embedding provider based on the provider name and config
-// Supports: voyage, openai (future), cohere (future), etc.
+// Supports: voyage, azure-openai, openai (future), cohere (future), etc.
 func CreateEmbeddingProvider(providerName string, config EmbeddingProviderConfig) (EmbeddingProvider, error) {
 	switch providerName {
 	case "voyage":
@@ -19,7 +25,19 @@ func CreateEmbeddingProvider(providerName string, config EmbeddingProviderConfig
 		}
 		return NewVoyageClient(config.APIKey), nil
 
+	case "azure-openai":
+		if config.BaseURL == "" || config.Deployment == "" {
+			return nil, fmt.Errorf("baseUrl and deployment are required for Azure OpenAI embedding provider")
+		}
+		if config.AuthType == appconfig.AzureAuthEntraID {
+			return NewAzureOpenAIEntraIDClient(config.BaseURL, config.Deployment, config.APIVersion)
+		}
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("API key is required for Azure OpenAI embedding provider unless authType is entraId")
+		}
+		return NewAzureOpenAIClient(config.BaseURL, config.Deployment, config.APIVersion, config.APIKey), nil
+
 	default:
-		return nil, fmt.Errorf("unsupported embedding provider: %s (supported: voyage)", providerName)
+		return nil, fmt.Errorf("unsupported embedding provider: %s (supported: voyage, azure-openai)", providerName)
 	}
 }
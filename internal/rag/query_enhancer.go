@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
 	"github.com/tuannvm/slack-mcp-client/internal/llm"
 )
 
@@ -24,23 +27,64 @@ type EnhancedQuery struct {
 	OriginalQuery   string          `json:"-"` // Not from LLM response
 }
 
+// DefaultEnhancedQuerySchema is the JSON Schema enhancement results are validated against
+// when no schema file is configured
+const DefaultEnhancedQuerySchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "enhanced_query": {"type": "string", "minLength": 1},
+    "metadata_filters": {
+      "type": "object",
+      "properties": {
+        "business_units": {"type": "array", "items": {"type": "string"}},
+        "regions": {"type": "array", "items": {"type": "string"}},
+        "dates": {"type": "array", "items": {"type": "integer", "minimum": 19000101, "maximum": 29991231}},
+        "labels": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  },
+  "required": ["enhanced_query"]
+}`
+
 // QueryEnhancer enhances queries using LLM
 type QueryEnhancer struct {
 	llmRegistry *llm.ProviderRegistry
+	schemaText  string             // Shown to the LLM
+	schema      *jsonschema.Schema // Validates the LLM response
 }
 
-// NewQueryEnhancer creates a new query enhancer
+// querySchemaURL names the schema in validation errors
+const querySchemaURL = "query-enhancement-schema.json"
+
+// NewQueryEnhancer creates a new query enhancer that validates results against DefaultEnhancedQuerySchema
 func NewQueryEnhancer(llmRegistry *llm.ProviderRegistry) *QueryEnhancer {
 	return &QueryEnhancer{
 		llmRegistry: llmRegistry,
+		schemaText:  DefaultEnhancedQuerySchema,
+		schema:      jsonschema.MustCompileString(querySchemaURL, DefaultEnhancedQuerySchema),
 	}
 }
 
-// EnhanceQuery enhances a query by extracting metadata filters and improving the query text
+// SetSchema replaces the JSON Schema that enhancement results must conform to
+func (qe *QueryEnhancer) SetSchema(schemaText string) error {
+	schema, err := jsonschema.CompileString(querySchemaURL, schemaText)
+	if err != nil {
+		return customErrors.WrapConfigError(err, "query_enhancement_schema_invalid", "invalid query enhancement schema")
+	}
+	qe.schemaText = schemaText
+	qe.schema = schema
+	return nil
+}
+
+// EnhanceQuery enhances a query by extracting metadata filters and improving the query text.
+// The LLM is asked for JSON conforming to the schema; a response that does not conform is
+// sent back once with the validation errors before giving up.
 func (qe *QueryEnhancer) EnhanceQuery(ctx context.Context, query string, today string, promptTemplate string) (*EnhancedQuery, error) {
 	// Build the prompt by replacing placeholders
 	prompt := strings.ReplaceAll(promptTemplate, "{today}", today)
 	prompt = strings.ReplaceAll(prompt, "{query}", query)
+	prompt += "\n\nRespond with only a JSON object that conforms to this JSON Schema:\n" + qe.schemaText
 
 	// Get the primary LLM provider from registry
 	provider, err := qe.llmRegistry.GetPrimaryProvider()
@@ -56,27 +100,49 @@ func (qe *QueryEnhancer) EnhanceQuery(ctx context.Context, query string, today s
 		},
 	}
 
-	// Call LLM with the prompt
-	response, err := provider.GenerateChatCompletion(ctx, messages, llm.ProviderOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to call LLM: %w", err)
+	var validationErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		// Call LLM with the prompt, in JSON mode where the provider has one
+		response, err := provider.GenerateChatCompletion(ctx, messages, llm.ProviderOptions{JSONMode: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to call LLM: %w", err)
+		}
+
+		result, err := qe.parseResponse(response.Content)
+		if err == nil {
+			// Set the original query
+			result.OriginalQuery = query
+			return result, nil
+		}
+		validationErr = err
+
+		// Retry with the errors, so the model can correct its own output
+		messages = append(messages,
+			llm.RequestMessage{Role: "assistant", Content: response.Content},
+			llm.RequestMessage{Role: "user", Content: fmt.Sprintf(
+				"Your response is invalid: %v\nReply again with only the corrected JSON object.", err)},
+		)
 	}
+	return nil, fmt.Errorf("LLM response does not conform to the query enhancement schema: %w", validationErr)
+}
 
-	responseText := response.Content
+// parseResponse validates the LLM response against the schema and decodes it
+func (qe *QueryEnhancer) parseResponse(responseText string) (*EnhancedQuery, error) {
+	// Providers without a JSON mode may still wrap the object in a code block
+	responseText = extractJSONFromCodeBlock(responseText)
+
+	var document interface{}
+	if err := json.Unmarshal([]byte(responseText), &document); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %w", err)
+	}
+	if err := qe.schema.Validate(document); err != nil {
+		return nil, err
+	}
 
-	// Parse the JSON response
 	var result EnhancedQuery
 	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		// Try to extract JSON from code blocks if direct parsing fails
-		responseText = extractJSONFromCodeBlock(responseText)
-		if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-			return nil, fmt.Errorf("failed to parse LLM response as JSON: %w, response: %s", err, responseText)
-		}
+		return nil, fmt.Errorf("response does not match the enhanced query format: %w", err)
 	}
-
-	// Set the original query
-	result.OriginalQuery = query
-
 	return &result, nil
 }
 
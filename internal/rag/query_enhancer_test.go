@@ -2,7 +2,11 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
@@ -134,6 +138,119 @@ func TestQueryEnhancer_EnhanceQuery_RecentQuery(t *testing.T) {
 	t.Logf("Labels: %v", result.MetadataFilters.Labels)
 }
 
+// scriptedEnhancementServer is an OpenAI-compatible endpoint answering with the given responses in turn.
+// It records the prompt and response format of each request.
+func scriptedEnhancementServer(t *testing.T, responses []string) (*httptest.Server, *[]string, *[]string) {
+	var prompts, formats []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+			ResponseFormat *struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var prompt strings.Builder
+		for _, msg := range req.Messages {
+			prompt.WriteString(msg.Content)
+		}
+		format := ""
+		if req.ResponseFormat != nil {
+			format = req.ResponseFormat.Type
+		}
+		prompts = append(prompts, prompt.String())
+		formats = append(formats, format)
+
+		content := responses[min(len(prompts), len(responses))-1]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"model":   "gpt-4o",
+			"choices": []map[string]interface{}{{"index": 0, "finish_reason": "stop", "message": map[string]string{"role": "assistant", "content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &prompts, &formats
+}
+
+func TestQueryEnhancer_SchemaValidation(t *testing.T) {
+	const valid = `{"enhanced_query": "APAC revenue Q3 2025", "metadata_filters": {"regions": ["APAC"], "dates": [20250930]}}`
+	const wrongDates = `{"enhanced_query": "APAC revenue Q3 2025", "metadata_filters": {"dates": ["2025-09-30"]}}`
+	tests := []struct {
+		name         string
+		responses    []string
+		wantErr      bool
+		wantRequests int
+	}{
+		{"valid JSON", []string{valid}, false, 1},
+		{"valid JSON in a code block", []string{"Here you go:\n```json\n" + valid + "\n```"}, false, 1},
+		{"corrected after validation errors", []string{wrongDates, valid}, false, 2},
+		{"corrected after prose", []string{"The query is about APAC revenue.", valid}, false, 2},
+		{"invalid twice", []string{wrongDates, `{"metadata_filters": {}}`}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, prompts, formats := scriptedEnhancementServer(t, tt.responses)
+			cfg := &config.Config{LLM: config.LLMConfig{
+				Provider:  "openai",
+				Providers: map[string]config.LLMProviderConfig{"openai": {Model: "gpt-4o", APIKey: "test-key", BaseURL: server.URL}},
+			}}
+			registry, err := llm.NewProviderRegistry(cfg, logging.New("test", logging.LevelError))
+			if err != nil {
+				t.Fatalf("NewProviderRegistry() error = %v", err)
+			}
+
+			result, err := NewQueryEnhancer(registry).EnhanceQuery(context.Background(), "Q3 revenue in APAC?", "2025-10-31", "Rewrite {query} as of {today}.")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnhanceQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(*prompts) != tt.wantRequests {
+				t.Fatalf("got %d LLM requests, want %d", len(*prompts), tt.wantRequests)
+			}
+			for i, format := range *formats {
+				if format != "json_object" {
+					t.Errorf("request %d response_format = %q, want json_object", i, format)
+				}
+			}
+			if first := (*prompts)[0]; !strings.Contains(first, "Rewrite Q3 revenue in APAC? as of 2025-10-31.") || !strings.Contains(first, `"enhanced_query"`) {
+				t.Errorf("first prompt should contain the filled-in template and the schema:\n%s", first)
+			}
+			if tt.wantRequests > 1 && !strings.Contains((*prompts)[1], "invalid") {
+				t.Errorf("retry prompt should contain the validation errors:\n%s", (*prompts)[1])
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.EnhancedQuery != "APAC revenue Q3 2025" || result.OriginalQuery != "Q3 revenue in APAC?" ||
+				len(result.MetadataFilters.Dates) != 1 || result.MetadataFilters.Dates[0] != 20250930 {
+				t.Errorf("EnhanceQuery() = %+v", result)
+			}
+		})
+	}
+}
+
+func TestQueryEnhancer_SetSchema(t *testing.T) {
+	enhancer := NewQueryEnhancer(nil)
+	if err := enhancer.SetSchema(`{"type": "object", "required": ["enhanced_query"]`); err == nil {
+		t.Errorf("SetSchema() accepted malformed JSON")
+	}
+	if err := enhancer.SetSchema(`{"type": "object", "properties": {"metadata_filters": {"properties": {"regions": {"items": {"enum": ["APAC", "EMEA"]}}}}}}`); err != nil {
+		t.Fatalf("SetSchema() error = %v", err)
+	}
+	if _, err := enhancer.parseResponse(`{"enhanced_query": "x", "metadata_filters": {"regions": ["MARS"]}}`); err == nil {
+		t.Errorf("parseResponse() accepted a region outside the custom enum")
+	}
+	if _, err := enhancer.parseResponse(`{"enhanced_query": "x", "metadata_filters": {"regions": ["EMEA"]}}`); err != nil {
+		t.Errorf("parseResponse() error = %v", err)
+	}
+}
+
 // TestExtractJSONFromCodeBlock tests the JSON extraction utility
 func TestExtractJSONFromCodeBlock(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,382 @@
+// Package redact masks PII and secrets in text before it is sent to external LLM and tracing providers.
+//
+// A Redactor replaces matches with typed markers such as "[REDACTED_EMAIL]". A Session, created per
+// user interaction, uses numbered placeholders such as "[EMAIL_1]" instead and remembers the original
+// values, so that tool arguments and answers produced by the LLM can be mapped back to the real values.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+// Names of the built-in detectors
+const (
+	DetectorEmail      = "email"
+	DetectorAPIKey     = "api_key"
+	DetectorCreditCard = "credit_card"
+)
+
+// secretGroup is the name of the capture group that limits a match to the secret part,
+// e.g. the value in "password=hunter2"
+const secretGroup = "secret"
+
+// Detector finds one kind of sensitive value
+type Detector struct {
+	Name     string                  // Detector name, also used in placeholders
+	Pattern  *regexp.Regexp          // Matches the value; a group named "secret" narrows the replaced part
+	Validate func(value string) bool // Optional check to discard false positives
+}
+
+// builtinDetector returns the detector with the given name
+func builtinDetector(name string) (Detector, bool) {
+	switch name {
+	case DetectorEmail:
+		return Detector{Name: name, Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)}, true
+	case DetectorAPIKey:
+		return Detector{Name: name, Pattern: regexp.MustCompile(strings.Join([]string{
+			`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`,                   // OpenAI, Anthropic and similar
+			`\b(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}`,       // Stripe
+			`\bxox[abposr]-[A-Za-z0-9-]{10,}`,                     // Slack tokens
+			`\bxapp-[A-Za-z0-9-]{10,}`,                            // Slack app tokens
+			`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                       // AWS access key IDs
+			`\bgh[pousr]_[A-Za-z0-9]{36,}`,                        // GitHub tokens
+			`\bgithub_pat_[A-Za-z0-9_]{22,}`,                      // GitHub fine-grained tokens
+			`\bAIza[0-9A-Za-z_-]{35}`,                             // Google API keys
+			`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]{20,}=*)`, // Authorization headers
+			`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key)\s*[:=]\s*"?(?P<secret>[^\s"',;\[][^\s"',;]{3,})`,
+		}, "|"))}, true
+	case DetectorCreditCard:
+		return Detector{Name: name, Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Validate: luhnValid}, true
+	default:
+		return Detector{}, false
+	}
+}
+
+// luhnValid reports whether the digits of value pass the Luhn checksum used by card numbers
+func luhnValid(value string) bool {
+	var digits []int
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// Redactor masks sensitive values. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	detectors []Detector
+}
+
+// New builds a Redactor from configuration. It returns nil when redaction is disabled.
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	r := &Redactor{}
+	names := cfg.Detectors
+	if len(names) == 0 {
+		names = []string{DetectorEmail, DetectorAPIKey, DetectorCreditCard}
+	}
+	for _, name := range names {
+		detector, ok := builtinDetector(name)
+		if !ok {
+			return nil, customErrors.NewConfigErrorf("unknown_redaction_detector", "unknown redaction detector %q", name)
+		}
+		r.detectors = append(r.detectors, detector)
+	}
+
+	// Custom patterns run in name order so placeholders are stable across restarts
+	customNames := make([]string, 0, len(cfg.CustomPatterns))
+	for name := range cfg.CustomPatterns {
+		customNames = append(customNames, name)
+	}
+	sort.Strings(customNames)
+	for _, name := range customNames {
+		pattern, err := regexp.Compile(cfg.CustomPatterns[name])
+		if err != nil {
+			return nil, customErrors.WrapConfigError(err, "invalid_redaction_pattern", fmt.Sprintf("invalid redaction pattern %q", name))
+		}
+		r.detectors = append(r.detectors, Detector{Name: name, Pattern: pattern})
+	}
+	return r, nil
+}
+
+// Redact replaces sensitive values with typed markers. The result cannot be restored;
+// use it for data that is only observed, such as trace payloads.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	return r.replace(text, func(kind, _ string) string {
+		return "[REDACTED_" + kind + "]"
+	})
+}
+
+// NewSession starts a reversible redaction session. It returns nil for a nil Redactor.
+func (r *Redactor) NewSession() *Session {
+	if r == nil {
+		return nil
+	}
+	return &Session{
+		redactor:      r,
+		byValue:       make(map[string]string),
+		byPlaceholder: make(map[string]string),
+		counters:      make(map[string]int),
+	}
+}
+
+// replace runs all detectors over text, replacing each match with the result of placeholder
+func (r *Redactor) replace(text string, placeholder func(kind, value string) string) string {
+	for _, detector := range r.detectors {
+		text = replaceMatches(text, detector, placeholder)
+	}
+	return text
+}
+
+func replaceMatches(text string, detector Detector, placeholder func(kind, value string) string) string {
+	matches := detector.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	secretIndexes := secretGroupIndexes(detector.Pattern)
+	kind := placeholderKind(detector.Name)
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		// Narrow the match to the secret group when one participated in the match
+		for _, group := range secretIndexes {
+			if match[2*group] >= 0 {
+				start, end = match[2*group], match[2*group+1]
+				break
+			}
+		}
+		value := text[start:end]
+		if start < last || value == "" || (detector.Validate != nil && !detector.Validate(value)) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(placeholder(kind, value))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// secretGroupIndexes returns the indexes of the capture groups named "secret"
+func secretGroupIndexes(pattern *regexp.Regexp) []int {
+	var indexes []int
+	for i, name := range pattern.SubexpNames() {
+		if name == secretGroup {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// placeholderKind turns a detector name into the upper-case form used in placeholders
+func placeholderKind(name string) string {
+	kind := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if kind == "" {
+		return "SECRET"
+	}
+	return kind
+}
+
+// placeholderPattern matches placeholders produced by a Session
+var placeholderPattern = regexp.MustCompile(`\[[A-Z0-9_]+_\d+\]`)
+
+// Session redacts text with numbered placeholders and maps them back to the original values.
+// The same value always gets the same placeholder within a session. A nil Session leaves text unchanged.
+type Session struct {
+	redactor *Redactor
+
+	mu            sync.Mutex
+	byValue       map[string]string // Original value to placeholder
+	byPlaceholder map[string]string // Placeholder to original value
+	counters      map[string]int    // Last number used per kind
+	literals      []literal
+}
+
+// literal is a known sensitive value, such as a participant's name
+type literal struct {
+	kind  string
+	value string
+}
+
+// minLiteralLength is the shortest single-word literal that is redacted; shorter ones,
+// such as the name "Al", would mask too many unrelated words
+const minLiteralLength = 3
+
+// AddLiteral registers a known sensitive value that has no pattern, such as a user's real name.
+// Literals are only replaced as whole words.
+func (s *Session) AddLiteral(kind, value string) {
+	value = strings.TrimSpace(value)
+	if s == nil || value == "" {
+		return
+	}
+	if !strings.ContainsAny(value, " \t") && utf8.RuneCountInString(value) < minLiteralLength {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.literals {
+		if l.value == value {
+			return
+		}
+	}
+	s.literals = append(s.literals, literal{kind: placeholderKind(kind), value: value})
+	// Replace longer values first so "Alice Example" wins over "Alice"
+	sort.SliceStable(s.literals, func(i, j int) bool { return len(s.literals[i].value) > len(s.literals[j].value) })
+}
+
+// Redact replaces sensitive values with placeholders such as "[EMAIL_1]"
+func (s *Session) Redact(text string) string {
+	if s == nil || text == "" {
+		return text
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range s.literals {
+		text = replaceWord(text, l.value, func() string { return s.placeholderLocked(l.kind, l.value) })
+	}
+	return s.redactor.replace(text, s.placeholderLocked)
+}
+
+// replaceWord replaces the occurrences of value that are not part of a longer word, so the name
+// "Ana" does not match inside "Analytics". Unlike \b in Go regexps, this treats non-ASCII letters
+// as word characters.
+func replaceWord(text, value string, placeholder func() string) string {
+	var sb strings.Builder
+	last, from := 0, 0
+	for {
+		i := strings.Index(text[from:], value)
+		if i < 0 {
+			break
+		}
+		start, end := from+i, from+i+len(value)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if start > 0 && isWordRune(before) || end < len(text) && isWordRune(after) {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			from = start + size
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(placeholder())
+		last, from = end, end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// placeholderLocked returns the placeholder for a value, allocating one on first use
+func (s *Session) placeholderLocked(kind, value string) string {
+	if placeholder, ok := s.byValue[value]; ok {
+		return placeholder
+	}
+	s.counters[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, s.counters[kind])
+	s.byValue[value] = placeholder
+	s.byPlaceholder[placeholder] = value
+	return placeholder
+}
+
+// Restore replaces placeholders issued by this session with the original values
+func (s *Session) Restore(text string) string {
+	if s == nil || !strings.Contains(text, "[") {
+		return text
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := s.byPlaceholder[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// RestoreArgs restores placeholders in tool call arguments, including nested maps and lists.
+// The input map is not modified.
+func (s *Session) RestoreArgs(args map[string]interface{}) map[string]interface{} {
+	if s == nil || args == nil {
+		return args
+	}
+	restored, _ := s.restoreValue(args).(map[string]interface{})
+	return restored
+}
+
+func (s *Session) restoreValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return s.Restore(v)
+	case map[string]interface{}:
+		restored := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			restored[key] = s.restoreValue(item)
+		}
+		return restored
+	case []interface{}:
+		restored := make([]interface{}, len(v))
+		for i, item := range v {
+			restored[i] = s.restoreValue(item)
+		}
+		return restored
+	default:
+		return value
+	}
+}
+
+// Count returns how many distinct values the session has redacted
+func (s *Session) Count() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.byValue)
+}
@@ -2,20 +2,320 @@ package slackbot
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
 	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/tuannvm/slack-mcp-client/internal/config"
 )
 
-type sendMessageFunc func(message string)
+const (
+	agentTraceBlockID        = "agent_trace"
+	agentTraceToggleActionID = "agent_trace_toggle"
+	agentTraceUpdateInterval = time.Second // chat.update allows about one update per second and channel
+	agentTraceMaxSteps       = 10          // Steps listed; earlier steps are only counted
+	agentTraceMaxDetail      = 150         // Characters of arguments and errors shown in detailed traces
+	maxAgentTraces           = 200         // Finished traces kept so they can be expanded and collapsed
+)
+
+// agentStep is one tool call of an agent run
+type agentStep struct {
+	tool     string
+	args     string
+	started  time.Time
+	duration time.Duration
+	err      string
+	done     bool
+}
 
+// agentCallbackHandler shows the agent's tool calls in a trace message that is updated as the agent runs
+// and collapses to a summary line once it finished. Frontends that cannot update messages get the
+// finished trace as one message.
 type agentCallbackHandler struct {
 	callbacks.SimpleHandler
-	sendMessage sendMessageFunc
+	client    *Client
+	channelID string
+	threadTS  string
+	reader    recipient
+	detailed  bool                // Show arguments and errors, and keep the steps expanded when done
+	restore   func(string) string // Restores redacted values and mentions in shown arguments
+	now       func() time.Time
+
+	mu       sync.Mutex
+	started  time.Time
+	finished time.Time // Zero while the agent runs
+	steps    []agentStep
+	expanded bool   // Steps are listed after the run
+	ts       string // The trace message, once posted
+	failed   bool   // Posting the trace failed; it is not retried
+	lastSent time.Time
+	flush    *time.Timer // Pending throttled update
+}
+
+// newAgentTrace creates the trace of an agent run, or returns nil when traces are off in the channel
+func (c *Client) newAgentTrace(channelID, threadTS string, reader recipient, restore func(string) string) *agentCallbackHandler {
+	verbosity := c.cfg.Slack.AgentTrace.ChannelVerbosity(channelID)
+	if verbosity == config.AgentTraceOff {
+		return nil
+	}
+	return &agentCallbackHandler{
+		client:    c,
+		channelID: channelID,
+		threadTS:  threadTS,
+		reader:    reader,
+		detailed:  verbosity == config.AgentTraceDetailed,
+		restore:   restore,
+		now:       time.Now,
+		started:   time.Now(),
+	}
+}
+
+func (h *agentCallbackHandler) HandleAgentAction(_ context.Context, action schema.AgentAction) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeStepLocked("not run")
+	h.steps = append(h.steps, agentStep{tool: action.Tool, args: h.restore(action.ToolInput), started: h.now()})
+	h.updateLocked()
+}
+
+func (h *agentCallbackHandler) HandleToolStart(_ context.Context, _ string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if step := h.openStepLocked(); step != nil {
+		step.started = h.now()
+	}
+}
+
+func (h *agentCallbackHandler) HandleToolEnd(_ context.Context, _ string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeStepLocked("")
+	h.updateLocked()
+}
+
+func (h *agentCallbackHandler) HandleToolError(_ context.Context, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeStepLocked(h.restore(err.Error()))
+	h.updateLocked()
+}
+
+// finish shows the final trace once the agent returned; runs without tool calls leave no trace
+func (h *agentCallbackHandler) finish() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeStepLocked("not run")
+	h.finished = h.now()
+	h.expanded = h.detailed
+	if h.flush != nil {
+		h.flush.Stop()
+		h.flush = nil
+	}
+	if len(h.steps) == 0 {
+		return
+	}
+	if _, ok := h.client.userFrontend.(blockMessageFrontend); !ok {
+		h.client.userFrontend.SendMessage(h.channelID, h.threadTS, h.textLocked(true))
+		return
+	}
+	h.sendLocked()
+	if h.ts != "" {
+		h.client.storeAgentTrace(h)
+	}
+}
+
+// toggle expands or collapses the steps of a finished trace
+func (h *agentCallbackHandler) toggle() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expanded = !h.expanded
+	h.sendLocked()
+}
+
+// openStepLocked returns the running step, if any; the caller holds the lock
+func (h *agentCallbackHandler) openStepLocked() *agentStep {
+	if len(h.steps) == 0 || h.steps[len(h.steps)-1].done {
+		return nil
+	}
+	return &h.steps[len(h.steps)-1]
+}
+
+// closeStepLocked ends the running step, failed if errText is set; the caller holds the lock
+func (h *agentCallbackHandler) closeStepLocked(errText string) {
+	step := h.openStepLocked()
+	if step == nil {
+		return
+	}
+	step.done = true
+	step.duration = h.now().Sub(step.started)
+	step.err = errText
+}
+
+// updateLocked shows the current steps, throttled to agentTraceUpdateInterval; the caller holds the lock
+func (h *agentCallbackHandler) updateLocked() {
+	if _, ok := h.client.userFrontend.(blockMessageFrontend); !ok || h.failed {
+		return
+	}
+	if wait := agentTraceUpdateInterval - h.now().Sub(h.lastSent); h.ts != "" && wait > 0 {
+		if h.flush == nil {
+			h.flush = time.AfterFunc(wait, func() {
+				h.mu.Lock()
+				defer h.mu.Unlock()
+				h.flush = nil
+				if h.finished.IsZero() {
+					h.sendLocked()
+				}
+			})
+		}
+		return
+	}
+	h.sendLocked()
 }
 
-func (handler *agentCallbackHandler) HandleChainEnd(_ context.Context, outputs map[string]any) {
-	if text, ok := outputs["text"]; ok {
-		if textStr, ok := text.(string); ok {
-			handler.sendMessage(textStr)
+// sendLocked posts or updates the trace message; the caller holds the lock
+func (h *agentCallbackHandler) sendLocked() {
+	frontend, ok := h.client.userFrontend.(blockMessageFrontend)
+	if !ok || h.failed {
+		return
+	}
+	text, blocks := h.headerLocked(), h.blocksLocked()
+	h.lastSent = h.now()
+	if h.ts == "" {
+		ts, err := frontend.PostBlocks(h.channelID, h.threadTS, text, blocks)
+		if err != nil {
+			h.client.logger.WarnKV("Failed to post agent trace", "channel", h.channelID, "error", err)
+			h.failed = true
+			return
 		}
+		h.ts = ts
+		return
+	}
+	if err := frontend.UpdateBlocks(h.channelID, h.ts, text, blocks); err != nil {
+		h.client.logger.WarnKV("Failed to update agent trace", "channel", h.channelID, "ts", h.ts, "error", err)
+	}
+}
+
+// blocksLocked renders the trace as a context block, with a button to show or hide the steps once done
+func (h *agentCallbackHandler) blocksLocked() []slack.Block {
+	running := h.finished.IsZero()
+	blocks := []slack.Block{slack.NewContextBlock(agentTraceBlockID,
+		slack.NewTextBlockObject(slack.MarkdownType, h.textLocked(running || h.expanded), false, false))}
+	if running {
+		return blocks
+	}
+	label := h.client.message(h.reader, msgAgentTraceShow, messageData{})
+	if h.expanded {
+		label = h.client.message(h.reader, msgAgentTraceHide, messageData{})
+	}
+	button := slack.NewButtonBlockElement(agentTraceToggleActionID, "toggle", slack.NewTextBlockObject(slack.PlainTextType, label, false, false))
+	return append(blocks, slack.NewActionBlock(agentTraceBlockID+"_actions", button))
+}
+
+// headerLocked renders the one-line state of the run
+func (h *agentCallbackHandler) headerLocked() string {
+	data := messageData{StepCount: len(h.steps)} // While running, the steps done so far
+	for _, step := range h.steps {
+		if step.err != "" {
+			data.FailedSteps++
+		}
+	}
+	if !h.finished.IsZero() {
+		data.Duration = formatStepDuration(h.finished.Sub(h.started))
+		return h.client.message(h.reader, msgAgentTraceDone, data)
+	}
+	if step := h.openStepLocked(); step != nil {
+		data.Tool = step.tool
+		data.StepCount--
+	}
+	return h.client.message(h.reader, msgAgentTraceActive, data)
+}
+
+// textLocked renders the header, followed by the most recent steps when withSteps is set
+func (h *agentCallbackHandler) textLocked(withSteps bool) string {
+	lines := []string{h.headerLocked()}
+	if !withSteps {
+		return lines[0]
+	}
+	steps := h.steps
+	if len(steps) > agentTraceMaxSteps {
+		lines = append(lines, fmt.Sprintf("… %d earlier steps", len(steps)-agentTraceMaxSteps))
+		steps = steps[len(steps)-agentTraceMaxSteps:]
+	}
+	for _, step := range steps {
+		lines = append(lines, h.stepLine(step))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stepLine renders one step, e.g. "✓ `get_status` · 1.2s"
+func (h *agentCallbackHandler) stepLine(step agentStep) string {
+	var line strings.Builder
+	switch {
+	case !step.done:
+		line.WriteString("… `" + step.tool + "`")
+	case step.err != "":
+		line.WriteString("✗ `" + step.tool + "` · " + formatStepDuration(step.duration))
+	default:
+		line.WriteString("✓ `" + step.tool + "` · " + formatStepDuration(step.duration))
+	}
+	if !h.detailed {
+		return line.String()
+	}
+	if args := strings.TrimSpace(step.args); args != "" && args != "{}" {
+		line.WriteString(" · `" + strings.ReplaceAll(truncateDetail(args), "`", "'") + "`")
+	}
+	if step.err != "" {
+		line.WriteString(" — " + truncateDetail(step.err))
+	}
+	return line.String()
+}
+
+// truncateDetail shortens arguments and errors to one line of agentTraceMaxDetail characters
+func truncateDetail(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > agentTraceMaxDetail {
+		return string(runes[:agentTraceMaxDetail]) + "…"
+	}
+	return text
+}
+
+// formatStepDuration shows durations under a second in milliseconds and longer ones to a tenth of a second
+func formatStepDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// storeAgentTrace keeps a finished trace so its button can expand and collapse it, dropping the oldest
+func (c *Client) storeAgentTrace(h *agentCallbackHandler) {
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+	key := h.channelID + ":" + h.ts
+	if _, exists := c.agentTraces[key]; !exists {
+		c.agentTraceOrder = append(c.agentTraceOrder, key)
+	}
+	c.agentTraces[key] = h
+	if len(c.agentTraceOrder) > maxAgentTraces {
+		delete(c.agentTraces, c.agentTraceOrder[0])
+		c.agentTraceOrder = c.agentTraceOrder[1:]
+	}
+}
+
+// toggleAgentTrace expands or collapses the trace in a message after its button was clicked
+func (c *Client) toggleAgentTrace(channelID, ts string) {
+	c.traceMu.Lock()
+	h := c.agentTraces[channelID+":"+ts]
+	c.traceMu.Unlock()
+	if h == nil {
+		// Traces are kept in memory, so buttons of traces from before a restart do nothing
+		c.logger.DebugKV("Ignored toggle of an unknown agent trace", "channel", channelID, "ts", ts)
+		return
 	}
+	h.toggle()
 }
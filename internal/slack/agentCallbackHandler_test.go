@@ -0,0 +1,33 @@
+package slackbot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentTraceStepLine(t *testing.T) {
+	longArgs := `{"query":"` + strings.Repeat("x", 200) + `"}`
+	tests := []struct {
+		name     string
+		step     agentStep
+		detailed bool
+		want     string
+	}{
+		{"running", agentStep{tool: "get_status", args: "{}"}, false, "… `get_status`"},
+		{"succeeded", agentStep{tool: "get_status", args: `{"service":"api"}`, duration: 1234 * time.Millisecond, done: true}, false, "✓ `get_status` · 1.2s"},
+		{"failed", agentStep{tool: "deploy", duration: 40 * time.Millisecond, err: "permission denied", done: true}, false, "✗ `deploy` · 40ms"},
+		{"detailed", agentStep{tool: "get_status", args: `{"service":"api"}`, duration: 2 * time.Second, done: true}, true, "✓ `get_status` · 2s · `{\"service\":\"api\"}`"},
+		{"detailed error", agentStep{tool: "deploy", args: "{}", duration: 300 * time.Millisecond, err: "permission\ndenied", done: true}, true, "✗ `deploy` · 300ms — permission denied"},
+		{"long arguments", agentStep{tool: "search", args: longArgs, done: true}, true, "✓ `search` · 0s · `" + longArgs[:agentTraceMaxDetail] + "…`"},
+		{"backticks", agentStep{tool: "run", args: "{\"cmd\":\"`ls`\"}", done: true}, true, "✓ `run` · 0s · `{\"cmd\":\"'ls'\"}`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &agentCallbackHandler{detailed: tt.detailed}
+			if got := h.stepLine(tt.step); got != tt.want {
+				t.Errorf("stepLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
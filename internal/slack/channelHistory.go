@@ -0,0 +1,78 @@
+package slackbot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// channelHistorySubtypes are the message subtypes worth showing as channel context;
+// joins, topic changes and other housekeeping messages are skipped
+var channelHistorySubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"thread_broadcast": true,
+	"file_share":       true,
+	"me_message":       true,
+}
+
+// channelHistoryLimits returns the limits for a channel, and false if the channel has not opted in
+func (c *Client) channelHistoryLimits(channelID string) (maxMessages int, maxAge time.Duration, ok bool) {
+	cfg := c.cfg.Slack.ChannelHistory
+	limits, ok := cfg.Channels[channelID]
+	if !ok {
+		return 0, 0, false
+	}
+	maxMessages, maxAgeHours := cfg.MaxMessages, cfg.MaxAgeHours
+	if limits.MaxMessages > 0 {
+		maxMessages = limits.MaxMessages
+	}
+	if limits.MaxAgeHours > 0 {
+		maxAgeHours = limits.MaxAgeHours
+	}
+	return maxMessages, time.Duration(maxAgeHours) * time.Hour, true
+}
+
+// recentChannelHistory returns the recent messages of a channel as context for a top-level mention,
+// oldest first, or "" if the channel has not opted in. The mention itself is left out.
+func (c *Client) recentChannelHistory(channelID, threadTS, timestamp string, mentions *mentionResolver) string {
+	if timestamp == "" || threadTS != timestamp {
+		return "" // Only for top-level mentions; replies in a thread already have the thread as context
+	}
+	maxMessages, maxAge, ok := c.channelHistoryLimits(channelID)
+	if !ok {
+		return ""
+	}
+	frontend, ok := c.userFrontend.(channelHistoryFrontend)
+	if !ok {
+		return ""
+	}
+
+	var oldest time.Time
+	if maxAge > 0 {
+		oldest = time.Now().Add(-maxAge)
+	}
+	keep := func(msg slack.Message) bool {
+		return msg.Timestamp != timestamp && channelHistorySubtypes[msg.SubType] && strings.TrimSpace(msg.Text) != ""
+	}
+	messages, err := frontend.GetChannelHistory(channelID, oldest, maxMessages, keep)
+	if err != nil {
+		c.logger.WarnKV("Failed to fetch channel history", "channel", channelID, "error", err)
+		return ""
+	}
+
+	lines := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		text := strings.ReplaceAll(mentions.Resolve(msg.Text), "\n", " \\n ")
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", formatSlackTimestamp(msg.Timestamp), messageAuthor(msg, mentions), text))
+	}
+	c.logger.DebugKV("Loaded channel history", "channel", channelID, "messages", len(lines))
+
+	// Slack returns newest first; the LLM reads the channel in order
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
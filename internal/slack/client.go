@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
 
@@ -22,7 +25,12 @@ import (
 	"github.com/tuannvm/slack-mcp-client/internal/llm"
 	"github.com/tuannvm/slack-mcp-client/internal/mcp"
 	"github.com/tuannvm/slack-mcp-client/internal/observability"
+	"github.com/tuannvm/slack-mcp-client/internal/prompt"
+	"github.com/tuannvm/slack-mcp-client/internal/quota"
 	"github.com/tuannvm/slack-mcp-client/internal/rag"
+	"github.com/tuannvm/slack-mcp-client/internal/redact"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client represents the Slack client application.
@@ -32,17 +40,35 @@ type Client struct {
 	mcpClients             map[string]*mcp.Client
 	llmMCPBridge           *handlers.LLMMCPBridge
 	llmRegistry            *llm.ProviderRegistry // LLM provider registry
+	router                 *llm.Router           // Chooses the provider and model tier per request; nil when routing is disabled
 	cfg                    *config.Config        // Holds the application configuration
 	messageHistory         map[string][]Message
 	historyLimit           int
+	historySeq             atomic.Uint64 // Numbers history messages that have no Slack timestamp
 	discoveredTools        map[string]mcp.ToolInfo
 	tracingHandler         observability.TracingHandler
 	queryEnhancer          *rag.QueryEnhancer // Query enhancer for all queries (not just RAG)
 	queryEnhancementPrompt string             // Query enhancement prompt template loaded from file
+
+	messages   *messageCatalog  // User-facing message templates
+	adminUsers map[string]bool  // Users shown raw error details
+	redactor   *redact.Redactor // Masks PII and secrets sent to LLM and tracing providers; nil when disabled
+	quotas     *quota.Tracker   // Token usage accounting and quotas; nil when disabled
+
+	systemPrompt *prompt.Template   // Custom prompt template; nil when no custom prompt is configured
+	stopWatch    context.CancelFunc // Stops watching the prompt file
+
+	shortcutMu       sync.Mutex
+	pendingShortcuts map[string]*messageShortcut // Message shortcuts waiting for their modal to be submitted
+
+	traceMu         sync.Mutex
+	agentTraces     map[string]*agentCallbackHandler // Finished agent traces by channel and message timestamp
+	agentTraceOrder []string                         // Keys of agentTraces, oldest first
 }
 
 // Message represents a message in the conversation history
 type Message struct {
+	ID             string    // Unique within the thread: the Slack timestamp, or a generated ID for bot-internal messages
 	Role           string    // "user", "assistant", or "tool"
 	Content        string    // The message content
 	Timestamp      time.Time // When the message was sent/received
@@ -50,6 +76,10 @@ type Message struct {
 	UserID         string
 	RealName       string
 	Email          string
+	ToolName       string // Tool called by an assistant message, or that produced a tool result
+	ToolArgs       string // JSON-encoded arguments of a tool call
+	ToolCallID     string // Links a tool call to its result
+	TraceID        string // Trace of the interaction that produced the message, if tracing is enabled
 }
 
 // NewClient creates a new Slack client instance.
@@ -238,46 +268,67 @@ func NewClient(userFrontend UserFrontend, stdLogger *logging.Logger, mcpClients
 				"Failed to create LLM registry for query enhancement")
 		}
 		queryEnhancer = rag.NewQueryEnhancer(qeRegistry)
+		if cfg.QueryEnhancementSchemaFile != "" {
+			schema, err := os.ReadFile(cfg.QueryEnhancementSchemaFile)
+			if err != nil {
+				clientLogger.ErrorKV("Failed to read query enhancement schema file",
+					"file", cfg.QueryEnhancementSchemaFile, "error", err)
+				return nil, customErrors.WrapConfigError(err, "query_enhancement_schema_file_read_failed",
+					"Failed to read query enhancement schema file")
+			}
+			if err := queryEnhancer.SetSchema(string(schema)); err != nil {
+				clientLogger.ErrorKV("Invalid query enhancement schema", "file", cfg.QueryEnhancementSchemaFile, "error", err)
+				return nil, err
+			}
+			clientLogger.InfoKV("Loaded query enhancement schema from file", "file", cfg.QueryEnhancementSchemaFile)
+		}
 		clientLogger.InfoKV("Created query enhancer for all queries", "provider", cfg.QueryEnhancementProvider)
 	}
 
-	// Wire up RAG embedding provider
-	// Note: Query enhancement is now done in Slack client before LLM call
-	if ragClient, ok := rawClientMap["rag"].(*rag.Client); ok {
-		// Create embedding provider if configured
-		if cfg.RAG.EmbeddingProvider != "" {
-			// Get embedding provider config
-			var embeddingConfig rag.EmbeddingProviderConfig
-			if providerCfg, exists := cfg.RAG.EmbeddingProviders[cfg.RAG.EmbeddingProvider]; exists {
-				embeddingConfig = rag.EmbeddingProviderConfig{
-					APIKey: providerCfg.APIKey,
-				}
-			} else {
-				clientLogger.WarnKV("Embedding provider config not found in RAG.EmbeddingProviders",
-					"provider", cfg.RAG.EmbeddingProvider)
-			}
-
-			provider, err := rag.CreateEmbeddingProvider(cfg.RAG.EmbeddingProvider, embeddingConfig)
-			if err != nil {
-				clientLogger.ErrorKV("Failed to create embedding provider, continuing without embeddings", "provider", cfg.RAG.EmbeddingProvider, "error", err)
-			} else {
-				ragClient.SetEmbeddingProvider(provider)
-				clientLogger.InfoKV("Enabled RAG embeddings", "provider", cfg.RAG.EmbeddingProvider)
+	// Create the embedding provider, shared by RAG and the semantic response cache
+	var embeddingProvider rag.EmbeddingProvider
+	ragClient, hasRAG := rawClientMap["rag"].(*rag.Client)
+	semanticCache := cfg.LLM.Cache.Enabled && cfg.LLM.Cache.Semantic.Enabled
+	if cfg.RAG.EmbeddingProvider != "" && (hasRAG || semanticCache) {
+		// Get embedding provider config
+		var embeddingConfig rag.EmbeddingProviderConfig
+		if providerCfg, exists := cfg.RAG.EmbeddingProviders[cfg.RAG.EmbeddingProvider]; exists {
+			embeddingConfig = rag.EmbeddingProviderConfig{
+				APIKey:     providerCfg.APIKey,
+				BaseURL:    providerCfg.BaseURL,
+				Deployment: providerCfg.Deployment,
+				APIVersion: providerCfg.APIVersion,
+				AuthType:   providerCfg.AuthType,
 			}
+		} else {
+			clientLogger.WarnKV("Embedding provider config not found in RAG.EmbeddingProviders",
+				"provider", cfg.RAG.EmbeddingProvider)
 		}
-	}
 
-	// Load custom prompt from file if specified and customPrompt is empty
-	if cfg.LLM.CustomPromptFile != "" && cfg.LLM.CustomPrompt == "" {
-		content, err := os.ReadFile(cfg.LLM.CustomPromptFile)
+		provider, err := rag.CreateEmbeddingProvider(cfg.RAG.EmbeddingProvider, embeddingConfig)
 		if err != nil {
-			clientLogger.ErrorKV("Failed to read custom prompt file", "file", cfg.LLM.CustomPromptFile, "error", err)
-			return nil, customErrors.WrapConfigError(err, "custom_prompt_file_read_failed", "Failed to read custom prompt file")
+			clientLogger.ErrorKV("Failed to create embedding provider, continuing without embeddings", "provider", cfg.RAG.EmbeddingProvider, "error", err)
+		} else {
+			embeddingProvider = provider
 		}
-		cfg.LLM.CustomPrompt = string(content)
-		clientLogger.InfoKV("Loaded custom prompt from file", "file", cfg.LLM.CustomPromptFile)
 	}
 
+	// Wire up RAG embedding provider
+	// Note: Query enhancement is now done in Slack client before LLM call
+	if hasRAG && embeddingProvider != nil {
+		ragClient.SetEmbeddingProvider(embeddingProvider)
+		clientLogger.InfoKV("Enabled RAG embeddings", "provider", cfg.RAG.EmbeddingProvider)
+	}
+
+	// The custom prompt is a template rendered per request; prompt files are reloaded when they change
+	systemPrompt, err := prompt.New(cfg.LLM.CustomPrompt, cfg.LLM.CustomPromptFile, clientLogger)
+	if err != nil {
+		clientLogger.ErrorKV("Failed to load custom prompt", "file", cfg.LLM.CustomPromptFile, "error", err)
+		return nil, err
+	}
+	if cfg.LLM.CustomPrompt == "" && cfg.LLM.CustomPromptFile != "" {
+		clientLogger.InfoKV("Loaded custom prompt from file", "file", cfg.LLM.CustomPromptFile)
+	}
 	// Pass the raw map to the bridge with the configured log level
 	llmMCPBridge := handlers.NewLLMMCPBridgeFromClientsWithLogLevel(
 		rawClientMap,
@@ -289,8 +340,33 @@ func NewClient(userFrontend UserFrontend, stdLogger *logging.Logger, mcpClients
 	)
 	clientLogger.InfoKV("LLM-MCP bridge initialized", "clients", len(mcpClients), "tools", len(discoveredTools))
 
+	var cacheEmbedder llm.Embedder
+	if embeddingProvider != nil {
+		cacheEmbedder = llm.EmbedderFunc(func(ctx context.Context, text string) ([]float32, error) {
+			result, err := embeddingProvider.EmbedQuery(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			return result.Embedding, nil
+		})
+	}
+	if responseCache := llm.NewResponseCache(cfg.LLM.Cache, cacheEmbedder, clientLogger); responseCache != nil {
+		llmMCPBridge.SetResponseCache(responseCache)
+		clientLogger.InfoKV("Enabled LLM response cache", "ttl_seconds", cfg.LLM.Cache.TTLSeconds,
+			"max_entries", cfg.LLM.Cache.MaxEntries, "semantic", cacheEmbedder != nil && cfg.LLM.Cache.Semantic.Enabled)
+	}
+
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize observability
 	tracingHandler := observability.NewTracingHandler(cfg, clientLogger)
+	if redactor != nil {
+		tracingHandler = observability.NewRedactingHandler(tracingHandler, redactor.Redact)
+		clientLogger.InfoKV("Redaction enabled", "custom_patterns", len(cfg.Redaction.CustomPatterns), "redact_user_names", cfg.Redaction.RedactUserNames)
+	}
 
 	// Wire up tracing handler to RAG client for embedding span tracking
 	if ragClient, ok := rawClientMap["rag"].(*rag.Client); ok {
@@ -298,6 +374,28 @@ func NewClient(userFrontend UserFrontend, stdLogger *logging.Logger, mcpClients
 		clientLogger.DebugKV("Set tracing handler on RAG client", "client", "rag")
 	}
 
+	messages, err := newMessageCatalog(cfg.Messages)
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := quota.New(cfg.Quotas, clientLogger)
+	if err != nil {
+		return nil, err
+	}
+	if quotas != nil {
+		clientLogger.InfoKV("Enabled LLM token quotas", "usage_file", cfg.Quotas.UsageFile,
+			"user_daily", cfg.Quotas.User.DailyTokens, "user_monthly", cfg.Quotas.User.MonthlyTokens,
+			"channel_daily", cfg.Quotas.Channel.DailyTokens, "channel_monthly", cfg.Quotas.Channel.MonthlyTokens)
+	}
+	adminUsers := make(map[string]bool, len(cfg.Slack.AdminUsers))
+	for _, userID := range cfg.Slack.AdminUsers {
+		adminUsers[userID] = true
+	}
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go systemPrompt.Watch(watchCtx)
+	go quotas.Run(watchCtx)
+
 	// --- Create and return Client instance ---
 	return &Client{
 		logger:                 clientLogger,
@@ -305,6 +403,7 @@ func NewClient(userFrontend UserFrontend, stdLogger *logging.Logger, mcpClients
 		mcpClients:             mcpClients,
 		llmMCPBridge:           llmMCPBridge,
 		llmRegistry:            registry,
+		router:                 llm.NewRouter(cfg.LLM.Routing, registry, clientLogger),
 		cfg:                    cfg,
 		messageHistory:         make(map[string][]Message),
 		historyLimit:           cfg.Slack.MessageHistory, // Store configured number of messages per channel
@@ -312,6 +411,14 @@ func NewClient(userFrontend UserFrontend, stdLogger *logging.Logger, mcpClients
 		tracingHandler:         tracingHandler,
 		queryEnhancer:          queryEnhancer,          // Query enhancer for all queries
 		queryEnhancementPrompt: queryEnhancementPrompt, // Query enhancement prompt template
+		messages:               messages,
+		adminUsers:             adminUsers,
+		redactor:               redactor,
+		quotas:                 quotas,
+		pendingShortcuts:       make(map[string]*messageShortcut),
+		agentTraces:            make(map[string]*agentCallbackHandler),
+		systemPrompt:           systemPrompt,
+		stopWatch:              stopWatch,
 	}, nil
 }
 
@@ -325,6 +432,10 @@ func (c *Client) Run() error {
 // Close gracefully closes the Slack client
 func (c *Client) Close() error {
 	c.logger.Info("Closing Slack client...")
+	c.stopWatch()
+	if err := c.quotas.Flush(); err != nil {
+		c.logger.WarnKV("Failed to persist LLM usage", "error", err)
+	}
 	// Note: socketmode.Client doesn't have a public Close method
 	// The client will stop when the context is cancelled or when there's a connection error
 	return nil
@@ -349,6 +460,16 @@ func (c *Client) handleEvents() {
 			c.userFrontend.Ack(*evt.Request)
 			c.logger.InfoKV("Received EventsAPI event", "type", eventsAPIEvent.Type)
 			c.handleEventMessage(eventsAPIEvent)
+		case socketmode.EventTypeInteractive:
+			callback, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				c.logger.WarnKV("Ignored unexpected interactive event type", "type", fmt.Sprintf("%T", evt.Data))
+				continue
+			}
+			// Ack right away: Slack requires it within 3 seconds, and an empty ack closes submitted modals
+			c.userFrontend.Ack(*evt.Request)
+			c.logger.InfoKV("Received interactive event", "type", callback.Type, "callback_id", callback.CallbackID)
+			c.handleInteraction(callback)
 		default:
 			c.logger.DebugKV("Ignored event type", "type", evt.Type)
 		}
@@ -413,21 +534,30 @@ func historyKey(channelID, threadTS string) string {
 
 // addToHistory adds a message to the channel history
 func (c *Client) addToHistory(channelID, threadTS, timestamp, role, content, userID, realName, email string) {
-	key := historyKey(channelID, threadTS)
-	history, exists := c.messageHistory[key]
-	if !exists {
-		history = []Message{}
-	}
-
-	// Add the new message
-	message := Message{
+	c.appendHistory(channelID, threadTS, Message{
 		Role:           role,
 		Content:        content,
-		Timestamp:      time.Now(),
 		SlackTimestamp: timestamp,
 		UserID:         userID,
 		RealName:       realName,
 		Email:          email,
+	})
+}
+
+// appendHistory adds a fully populated message to the thread history, stamping it with the current time
+func (c *Client) appendHistory(channelID, threadTS string, message Message) {
+	key := historyKey(channelID, threadTS)
+	history, exists := c.messageHistory[key]
+	if !exists {
+		history = []Message{}
+	}
+
+	message.Timestamp = time.Now()
+	if message.ID == "" {
+		message.ID = message.SlackTimestamp
+	}
+	if message.ID == "" {
+		message.ID = c.newHistoryID("msg")
 	}
 	history = append(history, message)
 
@@ -439,43 +569,53 @@ func (c *Client) addToHistory(channelID, threadTS, timestamp, role, content, use
 	c.messageHistory[key] = history
 }
 
-// getContextFromHistory builds a context string from message history
-//
-//nolint:unused // Reserved for future use
-func (c *Client) getContextFromHistory(channelID string, threadTS string) string {
-	history, exists := c.messageHistory[historyKey(channelID, threadTS)]
-	if !exists || len(history) == 0 {
-		return ""
+// addParticipantNames registers the names of the thread's participants and mentioned users as values to redact
+func (c *Client) addParticipantNames(redaction *redact.Session, channelID, threadTS string, profile *UserProfile, mentions *mentionResolver) {
+	if profile.realName != "Unknown" {
+		redaction.AddLiteral("person", profile.realName)
+	}
+	for _, msg := range c.messageHistory[historyKey(channelID, threadTS)] {
+		if msg.RealName != "Unknown" {
+			redaction.AddLiteral("person", msg.RealName)
+		}
 	}
+	for _, name := range mentions.userNames() {
+		redaction.AddLiteral("person", name)
+	}
+}
 
-	var contextBuilder strings.Builder
-	contextBuilder.WriteString("Previous conversation context:\n---\n") // Clearer start marker
+// newHistoryID returns an ID for a history message or tool call that has none from Slack or the model
+func (c *Client) newHistoryID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, c.historySeq.Add(1))
+}
 
+// getHistoryMessages returns the thread history as conversation turns for the LLM.
+// Mention tokens are replaced with readable names.
+func (c *Client) getHistoryMessages(channelID string, threadTS string, mentions *mentionResolver) []llm.HistoryMessage {
+	history := c.messageHistory[historyKey(channelID, threadTS)]
+	messages := make([]llm.HistoryMessage, 0, len(history))
 	for _, msg := range history {
-		switch msg.Role {
-		case "assistant":
-			prefix := "Assistant"
-			sanitizedContent := strings.ReplaceAll(msg.Content, "\n", " \\n ")
-			contextBuilder.WriteString(fmt.Sprintf("%s: %s\n", prefix, sanitizedContent))
-		case "tool":
-			prefix := "Tool Result"
-			sanitizedContent := strings.ReplaceAll(msg.Content, "\n", " \\n ")
-			contextBuilder.WriteString(fmt.Sprintf("%s: %s\n", prefix, sanitizedContent))
+		turn := llm.HistoryMessage{ID: msg.ID, Content: mentions.Resolve(msg.Content)}
+		switch {
+		case msg.Role == "tool":
+			turn.Role = llm.HistoryRoleTool
+			turn.ToolCallID = msg.ToolCallID
+			turn.ToolName = msg.ToolName
+		case msg.Role == "assistant":
+			turn.Role = llm.HistoryRoleAssistant
+			if msg.ToolName != "" {
+				turn.ToolCall = &llm.HistoryToolCall{ID: msg.ToolCallID, Name: msg.ToolName, Arguments: msg.ToolArgs}
+			}
 		default: // "user" or any other role
-			prefix := "User"
-			userInfo := ""
+			turn.Role = llm.HistoryRoleUser
 			if msg.UserID != "" {
-				userInfo = fmt.Sprintf(" (User: %s, Name: %s, Email: %s)", msg.UserID, msg.RealName, msg.Email)
+				turn.Author = fmt.Sprintf("%s (User: %s, Email: %s)", msg.RealName, msg.UserID, msg.Email)
 			}
-			sanitizedContent := strings.ReplaceAll(msg.Content, "\n", " \\n ")
-			contextBuilder.WriteString(fmt.Sprintf("%s: %s%s\n", prefix, sanitizedContent, userInfo))
 		}
+		messages = append(messages, turn)
 	}
-	contextBuilder.WriteString("---\n") // Clearer end marker
-
-	contextString := contextBuilder.String()
-	c.logger.DebugKV("Built conversation context", "channel", channelID, "context", contextString) // Log the built context
-	return contextString
+	c.logger.DebugKV("Built conversation history", "channel", channelID, "messages", len(messages))
+	return messages
 }
 
 // handleUserPrompt sends the user's text to the configured LLM provider.
@@ -483,6 +623,30 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 	c.logger.DebugKV("Routing prompt via configured provider", "provider", c.cfg.LLM.Provider)
 	c.logger.DebugKV("User prompt", "text", userPrompt)
 
+	if notifier, ok := c.userFrontend.(promptHandledNotifier); ok {
+		defer notifier.PromptHandled(channelID, threadTS)
+	}
+
+	reader := c.recipientFor(profile.userId)
+	if format, ok := parseTranscriptCommand(userPrompt); ok {
+		c.exportTranscript(channelID, threadTS, format, reader)
+		return
+	}
+	if cmd, ok := parseQuotaCommand(userPrompt); ok {
+		c.handleQuotaCommand(channelID, threadTS, cmd, reader)
+		return
+	}
+	if c.quotaExhausted(channelID, threadTS, reader) {
+		return
+	}
+	redaction := c.redactor.NewSession()
+	forcedTier, userPrompt := c.router.ParseForcedTier(userPrompt)
+
+	// History keeps the Slack text; the LLM sees readable names instead of mention tokens
+	mentions := c.newMentionResolver()
+	slackPrompt := userPrompt
+	userPrompt = mentions.Resolve(userPrompt)
+
 	ctx, span := c.tracingHandler.StartTrace(context.Background(), "slack-user-interaction", userPrompt, map[string]string{
 		"session_id":   fmt.Sprintf("%s-%s", channelID, threadTS),
 		"user_email":   profile.email,
@@ -503,6 +667,10 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 			// key := fmt.Sprintf("%s:%s", msg.UserID, msg.Content)
 			existingMessages[msg.SlackTimestamp] = true
 		}
+		// The prompt being handled is added below, with its trace ID
+		if timestamp != "" {
+			existingMessages[timestamp] = true
+		}
 		for _, reply := range replies {
 			// replyKey := fmt.Sprintf("%s:%s", reply.User, reply.Text)
 			if !existingMessages[reply.Timestamp] {
@@ -521,10 +689,28 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 		}
 	}
 
-	// Get context from history
-	contextHistory := c.getContextFromHistory(channelID, threadTS)
+	// Earlier turns of the thread, as typed messages
+	conversation := c.getHistoryMessages(channelID, threadTS, mentions)
+
+	// Quote messages linked from the prompt; they are passed to the LLM but not stored in history
+	linkedMessages := c.expandPermalinks(slackPrompt, channelID, profile.userId, mentions)
+	channelHistory := c.recentChannelHistory(channelID, threadTS, timestamp, mentions)
+
+	if c.cfg.Redaction.RedactUserNames {
+		c.addParticipantNames(redaction, channelID, threadTS, profile, mentions)
+	}
+	route := c.routeRequest(ctx, userPrompt, forcedTier, linkedMessages != "" || strings.Contains(userPrompt, sharedMessageIntro), redaction)
 
-	c.addToHistory(channelID, threadTS, timestamp, "user", userPrompt, profile.userId, profile.realName, profile.email) // Add user message to history
+	// Add user message to history
+	c.appendHistory(channelID, threadTS, Message{
+		Role:           "user",
+		Content:        slackPrompt,
+		SlackTimestamp: timestamp,
+		UserID:         profile.userId,
+		RealName:       profile.realName,
+		Email:          profile.email,
+		TraceID:        traceIDFromContext(ctx),
+	})
 
 	// Show a temporary "typing" indicator
 	c.userFrontend.SendMessage(channelID, threadTS, c.cfg.Slack.ThinkingMessage)
@@ -547,7 +733,7 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 			})
 
 		startTime := time.Now()
-		enhanced, err := c.queryEnhancer.EnhanceQuery(qeCtx, userPrompt, today, c.queryEnhancementPrompt)
+		enhanced, err := c.queryEnhancer.EnhanceQuery(qeCtx, redaction.Redact(userPrompt), today, c.queryEnhancementPrompt)
 		duration := time.Since(startTime)
 
 		c.tracingHandler.SetDuration(qeSpan, duration)
@@ -558,7 +744,7 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 			c.tracingHandler.RecordError(qeSpan, err, "ERROR")
 			enhancedQuery = userPrompt
 		} else {
-			enhancedQuery = enhanced.EnhancedQuery
+			enhancedQuery = redaction.Restore(enhanced.EnhancedQuery)
 			queryMetadata = &enhanced.MetadataFilters
 
 			fmt.Printf("[Query Enhancement] OUTPUT: '%s'\n", enhancedQuery)
@@ -584,12 +770,13 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 		// No query enhancer configured, use original query
 		enhancedQuery = userPrompt
 	}
+	enhancedQuery = withLinkedMessages(enhancedQuery, linkedMessages)
 
 	if !c.cfg.LLM.UseAgent {
 		// Prepare the final prompt with custom prompt as system instruction
 		// Use ENHANCED query instead of original userPrompt
 		var finalPrompt string
-		customPrompt := c.cfg.LLM.CustomPrompt
+		customPrompt := redaction.Redact(c.renderSystemPrompt(channelID, profile, route))
 		if customPrompt != "" {
 			// Use custom prompt as system instruction, then add enhanced query
 			finalPrompt = fmt.Sprintf("System instructions: %s\n\nUser: %s", customPrompt, enhancedQuery)
@@ -598,7 +785,7 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 			finalPrompt = enhancedQuery
 		}
 
-		llmCtx, llmSpan := c.tracingHandler.StartLLMSpan(ctx, "llm-call", c.cfg.LLM.Providers[c.cfg.LLM.Provider].Model, finalPrompt, map[string]interface{}{
+		llmCtx, llmSpan := c.tracingHandler.StartLLMSpan(ctx, "llm-call", c.routeModel(route), finalPrompt, map[string]interface{}{
 			"temperature": c.cfg.LLM.Providers[c.cfg.LLM.Provider].Temperature,
 			"max_tokens":  c.cfg.LLM.Providers[c.cfg.LLM.Provider].MaxTokens,
 		})
@@ -606,16 +793,32 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 		startTime := time.Now()
 
 		// Call LLM using the integrated logic with system instruction
-		llmResponse, err := c.llmMCPBridge.CallLLM(finalPrompt, contextHistory)
+		request := handlers.LLMRequest{
+			ChannelID:      channelID,
+			UserID:         profile.userId,
+			Route:          route,
+			SystemPrompt:   customPrompt,
+			Prompt:         finalPrompt,
+			Question:       enhancedQuery,
+			History:        conversation,
+			ChannelHistory: channelHistory,
+			Redaction:      redaction,
+		}
+		llmResponse, contextReport, err := c.llmMCPBridge.CallLLMWithRequest(llmCtx, request)
 
 		duration := time.Since(startTime)
+		c.recordUsage(profile.userId, channelID, contextReport)
+		c.recordContextReport(llmCtx, contextReport)
+		provider := c.recordProviderAttempts(llmCtx, llmSpan, contextReport)
 
 		// Set duration and handle response
 		c.tracingHandler.SetDuration(llmSpan, duration)
 
 		if err != nil {
-			c.logger.ErrorKV("Error from LLM provider", "provider", c.cfg.LLM.Provider, "error", err)
-			c.userFrontend.SendMessage(channelID, threadTS, fmt.Sprintf("Sorry, I encountered an error with the LLM provider ('%s'): %v", c.cfg.LLM.Provider, err))
+			c.logger.ErrorKV("Error from LLM provider", "provider", provider, "error", err)
+			data := reader.errorData(err, traceIDFromContext(ctx))
+			data.Provider = provider
+			c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgLLMError, data))
 			c.tracingHandler.RecordError(llmSpan, err, "ERROR")
 			llmSpan.End()
 			return
@@ -635,75 +838,142 @@ func (c *Client) handleUserPrompt(userPrompt, channelID, threadTS string, timest
 		if usageDetails["total_tokens"] > 0 {
 			c.tracingHandler.SetTokenUsage(llmSpan, usageDetails["prompt_tokens"], usageDetails["output_tokens"], usageDetails["reasoning_tokens"], usageDetails["total_tokens"])
 		}
+		c.recordCacheUsage(llmSpan, llmResponse.GenerationInfo)
 
-		c.logger.InfoKV("Received response from LLM", "provider", c.cfg.LLM.Provider, "length", len(llmResponse.Content))
+		c.logger.InfoKV("Received response from LLM", "provider", provider, "length", len(llmResponse.Content))
 		c.tracingHandler.RecordSuccess(llmSpan, "LLM call succeeded")
 		llmSpan.End()
 
 		// Process the LLM response through the MCP pipeline
-		// Pass enhancedQuery instead of userPrompt so re-prompt uses enhanced query
 		// Pass queryMetadata so it can be forwarded to RAG search
-		c.processLLMResponseAndReply(llmCtx, llmResponse, enhancedQuery, queryMetadata, channelID, threadTS)
+		c.processLLMResponseAndReply(llmCtx, request, llmResponse, enhancedQuery, queryMetadata, channelID, threadTS, reader, redaction, mentions)
 	} else {
 		// Agent path with enhanced tracing
 		agentCtx, agentSpan := c.tracingHandler.StartSpan(ctx, "llm-agent-call", "generation", userPrompt, map[string]string{
 			"provider": c.cfg.LLM.Provider,
 			"is_agent": "true",
 		})
-		sendMsg := func(msg string) {
-			// Trace each messages sent by the agent
-			_, msgSpan := c.tracingHandler.StartSpan(agentCtx, "agent-message-send", "event", msg, map[string]string{
-				"channel_id":     channelID,
-				"thread_ts":      threadTS,
-				"message_type":   "agent_intermediate",
-				"message_length": fmt.Sprintf("%d", len(msg)),
-			})
-
-			c.addToHistory(channelID, threadTS, "", "assistant", msg, "", "", "") // Original LLM response (tool call JSON)
-			c.userFrontend.SendMessage(channelID, threadTS, msg)
-			c.tracingHandler.RecordSuccess(msgSpan, "Agent message sent successfully")
-			msgSpan.End()
+		restore := func(text string) string { return mentions.Restore(redaction.Restore(text)) }
+		// Tool steps are shown in a trace message; the answer is sent once the agent returns
+		var callbackHandler callbacks.Handler = callbacks.SimpleHandler{}
+		agentTrace := c.newAgentTrace(channelID, threadTS, reader, restore)
+		if agentTrace != nil {
+			callbackHandler = agentTrace
 		}
 
 		startTime := time.Now()
-		llmResponse, err := c.llmMCPBridge.CallLLMAgent(
-			profile.realName,
-			c.cfg.LLM.CustomPrompt,
-			userPrompt,
-			contextHistory,
-			&agentCallbackHandler{
-				callbacks.SimpleHandler{},
-				sendMsg,
-			})
+		llmResponse, contextReport, err := c.llmMCPBridge.CallLLMAgent(
+			agentCtx,
+			channelID,
+			route,
+			redaction.Redact(profile.realName),
+			redaction.Redact(c.renderSystemPrompt(channelID, profile, route)),
+			withLinkedMessages(userPrompt, linkedMessages),
+			conversation,
+			channelHistory,
+			redaction,
+			callbackHandler)
 		duration := time.Since(startTime)
+		if agentTrace != nil {
+			agentTrace.finish()
+		}
+		c.recordUsage(profile.userId, channelID, contextReport)
+		c.recordContextReport(agentCtx, contextReport)
+		provider := c.recordProviderAttempts(agentCtx, agentSpan, contextReport)
 
 		// Set duration
 		c.tracingHandler.SetDuration(agentSpan, duration)
 
 		if err != nil {
-			c.logger.ErrorKV("Error from LLM provider", "provider", c.cfg.LLM.Provider, "error", err)
-			c.userFrontend.SendMessage(channelID, threadTS, fmt.Sprintf("Sorry, I encountered an error with the LLM provider ('%s'): %v", c.cfg.LLM.Provider, err))
+			c.logger.ErrorKV("Error from LLM provider", "provider", provider, "error", err)
+			data := reader.errorData(err, traceIDFromContext(ctx))
+			data.Provider = provider
+			c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgLLMError, data))
 			c.tracingHandler.RecordError(agentSpan, err, "ERROR")
 			agentSpan.End()
 			return
 		}
-		c.logger.InfoKV("Received response from LLM", "provider", c.cfg.LLM.Provider, "length", len(llmResponse))
+		c.logger.InfoKV("Received response from LLM", "provider", provider, "length", len(llmResponse))
 
 		// Set Output
 		c.tracingHandler.SetOutput(agentSpan, llmResponse)
 
 		// Send the final response back to Slack
 		if llmResponse == "" {
-			c.userFrontend.SendMessage(channelID, threadTS, "(LLM returned an empty response)")
+			c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgEmptyResponse, messageData{TraceID: traceIDFromContext(ctx)}))
 			c.tracingHandler.RecordError(agentSpan, fmt.Errorf("LLM returned an empty response"), "ERROR")
 
 		} else {
+			answer := restore(llmResponse)
+			c.appendHistory(channelID, threadTS, Message{Role: "assistant", Content: answer, TraceID: traceIDFromContext(agentCtx)})
+			c.userFrontend.SendMessage(channelID, threadTS, answer)
 			c.tracingHandler.RecordSuccess(agentSpan, "LLM agent call succeeded")
 		}
 		agentSpan.End()
 	}
 }
 
+// recordContextReport records token budget truncation decisions as child spans of the current trace
+func (c *Client) recordContextReport(ctx context.Context, report *llm.ContextReport) {
+	if !report.Truncated() {
+		return
+	}
+	for _, decision := range report.Decisions {
+		_, span := c.tracingHandler.StartSpan(ctx, "context-truncation", "event", string(decision.Section), map[string]string{
+			"section":         string(decision.Section),
+			"action":          decision.Action,
+			"original_tokens": fmt.Sprintf("%d", decision.OriginalTokens),
+			"kept_tokens":     fmt.Sprintf("%d", decision.KeptTokens),
+			"budget":          fmt.Sprintf("%d", report.Budget),
+			"final_tokens":    fmt.Sprintf("%d", report.FinalTokens),
+		})
+		c.tracingHandler.RecordSuccess(span, fmt.Sprintf("Section %s %s to fit token budget", decision.Section, decision.Action))
+		span.End()
+	}
+}
+
+// recordProviderAttempts records the providers tried for a call: the one that answered as an attribute of
+// the call's span, and each failed or skipped provider as a child span. It returns the provider that
+// answered, or the last one tried when none did.
+func (c *Client) recordProviderAttempts(ctx context.Context, span trace.Span, report *llm.ContextReport) string {
+	provider := c.cfg.LLM.Provider
+	if report == nil {
+		return provider
+	}
+	for _, attempt := range report.Attempts {
+		if attempt.Outcome != llm.AttemptSkipped {
+			provider = attempt.Provider
+		}
+		if attempt.Outcome == llm.AttemptSuccess {
+			continue
+		}
+		_, attemptSpan := c.tracingHandler.StartSpan(ctx, "llm-provider-failover", "event", attempt.Provider, map[string]string{
+			"provider": attempt.Provider,
+			"outcome":  attempt.Outcome,
+			"duration": attempt.Duration.String(),
+		})
+		c.tracingHandler.RecordError(attemptSpan, attempt.Err, "WARNING")
+		attemptSpan.End()
+	}
+	span.SetAttributes(attribute.String("llm.provider", provider))
+	if report.CacheHit {
+		span.SetAttributes(attribute.Bool("llm.cache_hit", true))
+	}
+	if answered := llm.AnsweredBy(report.Attempts); answered != "" && answered != c.cfg.LLM.Provider {
+		c.logger.InfoKV("LLM request answered by fallback provider", "provider", answered, "primary", c.cfg.LLM.Provider)
+	}
+	return provider
+}
+
+// recordCacheUsage records the prompt tokens the provider read from or wrote to its prompt cache
+func (c *Client) recordCacheUsage(span trace.Span, generationInfo map[string]interface{}) {
+	cacheRead := getIntFromMap(generationInfo, "CacheReadInputTokens")
+	cacheWrite := getIntFromMap(generationInfo, "CacheCreationInputTokens")
+	if cacheRead > 0 || cacheWrite > 0 {
+		c.tracingHandler.SetCacheUsage(span, cacheRead, cacheWrite)
+	}
+}
+
 // getIntFromMap safely extracts an int value from a map[string]interface{} by key.
 func getIntFromMap(m map[string]interface{}, key string) int {
 	if m == nil {
@@ -731,26 +1001,6 @@ func getIntFromMap(m map[string]interface{}, key string) int {
 	return 0
 }
 
-func (c *Client) extractToolNameFromResponse(response string) string {
-	// Try to parse JSON to extract tool name
-	var toolCall struct {
-		Tool string `json:"tool"`
-	}
-
-	if err := json.Unmarshal([]byte(response), &toolCall); err == nil && toolCall.Tool != "" {
-		return toolCall.Tool
-	}
-
-	// Fallback: look for tool names in the response text
-	for toolName := range c.discoveredTools {
-		if strings.Contains(response, toolName) {
-			return toolName
-		}
-	}
-
-	return "unknown"
-}
-
 func (c *Client) detectIfToolUsedLLM(toolName, response string) bool {
 	// Simple heuristics to detect if tool used LLM
 	switch {
@@ -784,9 +1034,9 @@ func (c *Client) estimateToolTokenUsage(toolName, prompt, response string) int {
 	}
 }
 
-// processLLMResponseAndReply processes the LLM response, handles tool results with re-prompting, and sends the final reply.
-// Incorporates logic previously in LLMClient.ProcessToolResponse.
-func (c *Client) processLLMResponseAndReply(traceCtx context.Context, llmResponse *llms.ContentChoice, userPrompt string, queryMetadata *rag.MetadataFilters, channelID, threadTS string) {
+// processLLMResponseAndReply processes the LLM response, runs any tool calls until the model answers, and sends the final reply.
+// The request is the one that produced the response; tool results are added to it for the follow-up calls.
+func (c *Client) processLLMResponseAndReply(traceCtx context.Context, request handlers.LLMRequest, llmResponse *llms.ContentChoice, userPrompt string, queryMetadata *rag.MetadataFilters, channelID, threadTS string, reader recipient, redaction *redact.Session, mentions *mentionResolver) {
 	// Start tool processing span
 	ctx, span := c.tracingHandler.StartSpan(traceCtx, "tool-processing", "span", userPrompt, map[string]string{
 		"channel_id":      channelID,
@@ -795,6 +1045,7 @@ func (c *Client) processLLMResponseAndReply(traceCtx context.Context, llmRespons
 		"response_length": fmt.Sprintf("%d", len(llmResponse.Content)),
 	})
 	defer span.End()
+	traceID := traceIDFromContext(ctx)
 	// Log the raw LLM response for debugging
 	c.logger.DebugKV("Raw LLM response", "response", logging.TruncateForLog(fmt.Sprintf("%v", llmResponse), 500))
 	extraArgs := map[string]interface{}{
@@ -809,70 +1060,17 @@ func (c *Client) processLLMResponseAndReply(traceCtx context.Context, llmRespons
 
 	c.logger.DebugKV("Added extra arguments", "channel_id", channelID, "thread_ts", threadTS)
 
-	// --- Process Tool Response (Logic from LLMClient.ProcessToolResponse) ---
 	var finalResponse string
-	var isToolResult bool
+	var toolSteps int
 	var toolProcessingErr error
 
 	if c.llmMCPBridge == nil {
 		// If bridge is nil, just use the original response
 		finalResponse = llmResponse.Content
-		isToolResult = false
-		toolProcessingErr = nil
 		c.logger.Warn("LLMMCPBridge is nil, skipping tool processing")
 	} else {
-		// Extract tool call from LLM response using bridge's logic (single source of truth)
-		toolCall, err := c.llmMCPBridge.ExtractToolCall(llmResponse)
-		if err != nil {
-			finalResponse = fmt.Sprintf("Sorry, I encountered an error extracting tool call: %v", err)
-			isToolResult = false
-			toolProcessingErr = err
-			c.logger.ErrorKV("Failed to extract tool call", "error", err)
-		} else if toolCall != nil {
-			// Tool call detected - execute it with tracing
-			c.logger.InfoKV("Tool call detected", "tool", toolCall.Tool)
-
-			// Marshal args for tracing
-			argsJSON, _ := json.Marshal(toolCall.Args)
-
-			// Start tool execution span with tool arguments as input
-			// IMPORTANT: Use the returned context so child spans (embedding, retriever) are properly nested
-			toolExecCtx, toolExecSpan := c.tracingHandler.StartSpan(ctx, "tool-execution", "tool", string(argsJSON), map[string]string{
-				"bridge_available": "true",
-				"response_type":    "processing",
-				"tool_name":        toolCall.Tool,
-			})
-
-			// Create a context with timeout from the span context
-			toolCtx, cancel := context.WithTimeout(toolExecCtx, 1*time.Minute)
-			defer cancel()
-
-			startTime := time.Now()
-			// Execute the tool call
-			processedResponse, err := c.llmMCPBridge.ExecuteToolCall(toolCtx, toolCall, extraArgs)
-			toolDuration := time.Since(startTime)
-			c.tracingHandler.SetDuration(toolExecSpan, toolDuration)
-
-			if err != nil {
-				finalResponse = fmt.Sprintf("Sorry, I encountered an error while trying to use a tool: %v", err)
-				isToolResult = false
-				toolProcessingErr = err
-				c.tracingHandler.RecordError(toolExecSpan, err, "ERROR")
-			} else {
-				finalResponse = processedResponse
-				isToolResult = true
-				c.tracingHandler.SetOutput(toolExecSpan, processedResponse)
-				c.tracingHandler.RecordSuccess(toolExecSpan, "Tool executed successfully")
-			}
-			toolExecSpan.End()
-		} else {
-			// No tool call detected - just use LLM response content
-			finalResponse = llmResponse.Content
-			isToolResult = false
-			c.logger.Debug("No tool call detected, using LLM response as-is")
-		}
+		finalResponse, toolSteps, toolProcessingErr = c.runToolLoop(ctx, request, llmResponse, extraArgs, channelID, threadTS, reader, redaction, mentions)
 	}
-	// --- End of Process Tool Response Logic ---
 
 	if toolProcessingErr != nil {
 		c.tracingHandler.RecordError(span, toolProcessingErr, "ERROR")
@@ -880,82 +1078,7 @@ func (c *Client) processLLMResponseAndReply(traceCtx context.Context, llmRespons
 		c.userFrontend.SendMessage(channelID, threadTS, finalResponse) // Post the error message
 		return
 	}
-
-	if isToolResult {
-		c.logger.Debug("Tool executed. Re-prompting LLM with tool result.")
-		c.logger.DebugKV("Tool result", "result", logging.TruncateForLog(finalResponse, 500))
-
-		// Always re-prompt LLM with tool results for synthesis
-		// Construct a new prompt incorporating the enhanced query and the tool result
-		rePrompt := fmt.Sprintf("The user asked: '%s'\n\nI searched the knowledge base and found the following relevant information:\n```\n%s\n```\n\nPlease analyze and synthesize this retrieved information to provide a comprehensive response to the user's request. Use the detailed information from the search results according to your system instructions.", userPrompt, finalResponse)
-
-		// Start re-prompt span
-		executedToolName := c.extractToolNameFromResponse(llmResponse.Content)
-		_, repromptSpan := c.tracingHandler.StartLLMSpan(ctx, "llm-reprompt",
-			c.cfg.LLM.Providers[c.cfg.LLM.Provider].Model,
-			rePrompt,
-			map[string]interface{}{
-				"is_reprompt":           true,
-				"tool_name":             executedToolName,                                                      // Add this
-				"tool_estimated_tokens": c.estimateToolTokenUsage(executedToolName, userPrompt, finalResponse), // Add this
-			})
-
-		// Add history
-		c.addToHistory(channelID, threadTS, "", "assistant", llmResponse.Content, "", "", "") // Original LLM response (tool call JSON)
-		c.addToHistory(channelID, threadTS, "", "tool", finalResponse, "", "", "")            // Tool execution result
-
-		c.logger.DebugKV("Re-prompting LLM", "prompt", rePrompt)
-
-		// Re-prompt using the LLM client with custom prompt as system instruction
-		var repromptErr error
-		// Prepare the re-prompt with custom prompt as system instruction
-		var finalRePrompt string
-		customPrompt := c.cfg.LLM.CustomPrompt
-
-		if customPrompt != "" {
-			// Use custom prompt as system instruction for re-prompt too
-			finalRePrompt = fmt.Sprintf("System instructions: %s\n\n%s", customPrompt, rePrompt)
-		} else {
-			finalRePrompt = rePrompt
-		}
-		startTime := time.Now()
-
-		finalResStruct, repromptErr := c.llmMCPBridge.CallLLM(finalRePrompt, c.getContextFromHistory(channelID, threadTS))
-
-		duration := time.Since(startTime)
-		// Set duration
-		c.tracingHandler.SetDuration(repromptSpan, duration)
-
-		if repromptErr != nil {
-			c.tracingHandler.RecordError(repromptSpan, repromptErr, "ERROR")
-			c.logger.ErrorKV("Error during LLM re-prompt", "error", repromptErr)
-			// Fallback: Show the tool result and the error
-			finalResponse = fmt.Sprintf("Tool Result:\n```%s```\n\n(Error generating final response: %v)", finalResponse, repromptErr)
-			c.tracingHandler.RecordError(span, repromptErr, "ERROR")
-		} else {
-			c.logger.DebugKV("LLM re-prompt successful", "response", logging.TruncateForLog(fmt.Sprintf("%v", finalResStruct), 500))
-			finalResponse = finalResStruct.Content
-			repromptUsageDetails := map[string]int{
-				"prompt_tokens":     getIntFromMap(finalResStruct.GenerationInfo, "PromptTokens"),
-				"completion_tokens": getIntFromMap(finalResStruct.GenerationInfo, "CompletionTokens"),
-				"reasoning_tokens":  getIntFromMap(finalResStruct.GenerationInfo, "ReasoningTokens"),
-				"total_tokens":      getIntFromMap(finalResStruct.GenerationInfo, "TotalTokens"),
-			}
-			if repromptUsageDetails["total_tokens"] > 0 {
-				c.tracingHandler.SetTokenUsage(repromptSpan,
-					repromptUsageDetails["prompt_tokens"],
-					repromptUsageDetails["completion_tokens"],
-					repromptUsageDetails["reasoning_tokens"],
-					repromptUsageDetails["total_tokens"])
-			}
-			c.tracingHandler.SetOutput(repromptSpan, finalResponse)
-			c.tracingHandler.RecordSuccess(repromptSpan, "LLM re-prompt successful")
-		}
-		repromptSpan.End()
-	} else {
-		// No tool was executed, add assistant response to history
-		c.addToHistory(channelID, threadTS, "", "assistant", finalResponse, "", "", "")
-	}
+	c.appendHistory(channelID, threadTS, Message{Role: "assistant", Content: finalResponse, TraceID: traceID})
 
 	// Start message sending span
 	_, msgSpan := c.tracingHandler.StartSpan(ctx, "slack-message-send", "event", userPrompt, map[string]string{
@@ -963,11 +1086,12 @@ func (c *Client) processLLMResponseAndReply(traceCtx context.Context, llmRespons
 		"thread_ts":             threadTS,
 		"final_response_length": fmt.Sprintf("%d", len(finalResponse)),
 		"is_empty_response":     fmt.Sprintf("%t", finalResponse == ""),
-		"had_tool_execution":    fmt.Sprintf("%t", isToolResult),
+		"had_tool_execution":    fmt.Sprintf("%t", toolSteps > 0),
+		"tool_steps":            fmt.Sprintf("%d", toolSteps),
 	})
 	// Send the final response back to Slack
 	if finalResponse == "" {
-		c.userFrontend.SendMessage(channelID, threadTS, "(LLM returned an empty response)")
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgEmptyResponse, messageData{TraceID: traceID}))
 		c.tracingHandler.RecordError(msgSpan, fmt.Errorf("LLM returned an empty response"), "ERROR")
 
 	} else {
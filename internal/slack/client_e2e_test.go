@@ -0,0 +1,840 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+	"github.com/tuannvm/slack-mcp-client/internal/slack/slackfake"
+)
+
+const e2eTimeout = 10 * time.Second
+
+// fakeToolCallsPrefix marks a scripted reply as native tool calls, e.g. `tool_calls:[{"id":"1","name":"x","arguments":"{}"}]`
+const fakeToolCallsPrefix = "tool_calls:"
+
+// fakeLLM is an OpenAI-compatible chat completions endpoint returning scripted answers
+type fakeLLM struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []string // Concatenated message contents of each request
+	reply    func(prompt string) string
+}
+
+func newFakeLLM(t *testing.T, reply func(prompt string) string) *fakeLLM {
+	f := &fakeLLM{reply: reply}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content    string `json:"content"`
+				ToolCallID string `json:"tool_call_id"`
+				ToolCalls  []struct {
+					ID string `json:"id"`
+				} `json:"tool_calls"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		for _, msg := range req.Messages {
+			switch {
+			case msg.ToolCallID != "":
+				parts = append(parts, fmt.Sprintf("tool_result(%s): %s", msg.ToolCallID, msg.Content))
+			case len(msg.ToolCalls) > 0:
+				parts = append(parts, fmt.Sprintf("tool_call(%s)", msg.ToolCalls[0].ID))
+			default:
+				parts = append(parts, msg.Content)
+			}
+		}
+		prompt := strings.Join(parts, "\n")
+
+		f.mu.Lock()
+		f.requests = append(f.requests, prompt)
+		f.mu.Unlock()
+
+		message := map[string]interface{}{"role": "assistant", "content": f.reply(prompt)}
+		finishReason := "stop"
+		if calls, ok := strings.CutPrefix(message["content"].(string), fakeToolCallsPrefix); ok {
+			var scripted []struct{ ID, Name, Arguments string }
+			if err := json.Unmarshal([]byte(calls), &scripted); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			var toolCalls []map[string]interface{}
+			for _, call := range scripted {
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"id":       call.ID,
+					"type":     "function",
+					"function": map[string]string{"name": call.Name, "arguments": call.Arguments},
+				})
+			}
+			message = map[string]interface{}{"role": "assistant", "content": "", "tool_calls": toolCalls}
+			finishReason = "tool_calls"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   "gpt-4o",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       message,
+				"finish_reason": finishReason,
+			}},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeLLM) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// e2eHarness runs a Client against the fake Slack server and a fake LLM
+type e2eHarness struct {
+	slack  *slackfake.Server
+	llm    *fakeLLM
+	client *Client
+}
+
+// newE2EHarness starts a bot against the fakes; options adjust the default configuration
+func newE2EHarness(t *testing.T, reply func(prompt string) string, options ...func(*config.Config)) *e2eHarness {
+	t.Helper()
+
+	fake := slackfake.NewServer()
+	t.Cleanup(fake.Close)
+	fake.AddUser(slack.User{ID: "U1", RealName: "Alice Example", Profile: slack.UserProfile{Email: "alice@example.com"}})
+	llmServer := newFakeLLM(t, reply)
+
+	cfg := &config.Config{
+		Slack: config.SlackConfig{
+			BotToken:        "xoxb-test",
+			AppToken:        "xapp-test",
+			MessageHistory:  50,
+			ThinkingMessage: "Thinking...",
+		},
+		LLM: config.LLMConfig{
+			Provider: "openai",
+			Providers: map[string]config.LLMProviderConfig{
+				"openai": {Model: "gpt-4o", APIKey: "test-key", BaseURL: llmServer.server.URL},
+			},
+			// Skip tokenizer downloads; budgeting is covered by the llm package tests
+			ContextBudget: config.ContextBudgetConfig{Disabled: true},
+		},
+	}
+
+	for _, option := range options {
+		option(cfg)
+	}
+
+	logger := logging.New("e2e", logging.LevelError)
+	frontend, err := GetSlackClient(cfg.Slack.BotToken, cfg.Slack.AppToken, logger, cfg.Slack.ThinkingMessage,
+		slack.OptionAPIURL(fake.APIURL()))
+	if err != nil {
+		t.Fatalf("GetSlackClient() error = %v", err)
+	}
+	client, err := NewClient(frontend, logger, nil, nil, cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	go func() { _ = client.Run() }()
+
+	if err := fake.WaitForConnection(e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+	return &e2eHarness{slack: fake, llm: llmServer, client: client}
+}
+
+// waitForReply waits for a bot message in the thread other than the thinking message
+func (h *e2eHarness) waitForReply(t *testing.T, channel, threadTS string) slackfake.Call {
+	t.Helper()
+	call, err := h.slack.WaitForCall("chat.postMessage", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("channel") == channel && c.Param("thread_ts") == threadTS && c.Param("text") != "Thinking..."
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return call
+}
+
+func TestClientE2E_AppMentionRepliesInThread(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "The answer is 42" })
+
+	ts, err := h.slack.MentionBot("C1", "U1", "what is the answer?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", ts)
+
+	if !strings.Contains(reply.Param("text")+reply.Param("blocks"), "The answer is 42") {
+		t.Errorf("reply = %q, want the LLM answer", reply.Param("text"))
+	}
+	if reply.Token != "xoxb-test" {
+		t.Errorf("reply posted with token %q, want the bot token", reply.Token)
+	}
+	if _, err := h.slack.WaitForCall("chat.delete", e2eTimeout, nil); err != nil {
+		t.Errorf("thinking message was not deleted: %v", err)
+	}
+	for _, msg := range h.slack.Messages("C1") {
+		if msg.Text == "Thinking..." {
+			t.Errorf("thinking message still present in channel")
+		}
+	}
+	if calls := h.slack.Calls("users.profile.get"); len(calls) == 0 || calls[0].Param("user") != "U1" {
+		t.Errorf("expected a profile lookup for U1, got %+v", calls)
+	}
+	if requests := h.llm.Requests(); len(requests) != 1 || !strings.Contains(requests[0], "what is the answer?") {
+		t.Errorf("LLM requests = %q, want one containing the question", requests)
+	}
+}
+
+func TestClientE2E_ThreadHistoryReachesLLM(t *testing.T) {
+	h := newE2EHarness(t, func(prompt string) string { return "noted" })
+
+	root := h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "my favourite colour is teal"}})
+	if _, err := h.slack.MentionBot("C1", "U1", "what is my favourite colour?", root); err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", root)
+
+	requests := h.llm.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0], "teal") {
+		t.Errorf("LLM request should include earlier thread messages, got %q", requests)
+	}
+}
+
+func TestClientE2E_DirectMessage(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "hello in private" })
+
+	ts, err := h.slack.SendDirectMessage("D1", "U1", "hi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "D1", ts)
+}
+
+func TestClientE2E_SlackFailures(t *testing.T) {
+	var h *e2eHarness
+	h = newE2EHarness(t, func(string) string {
+		// The thinking message has been posted by now; fail the block-formatted answer
+		h.slack.FailNext("chat.postMessage", "invalid_blocks")
+		return "Status: healthy\nBuild: passing"
+	})
+	h.slack.FailNext("users.profile.get", "user_not_found")
+	h.slack.RateLimitNext("conversations.replies", time.Second)
+
+	ts, err := h.slack.MentionBot("C1", "U1", "status?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Block Kit attempt fails, then the bot retries as plain text
+	if _, err := h.slack.WaitForCall("chat.postMessage", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("thread_ts") == ts && c.Param("blocks") != ""
+	}); err != nil {
+		t.Fatalf("expected a Block Kit reply first: %v", err)
+	}
+	if _, err := h.slack.WaitForCall("chat.postMessage", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("thread_ts") == ts && c.Param("blocks") == "" && strings.Contains(c.Param("text"), "healthy")
+	}); err != nil {
+		t.Fatalf("expected a plain-text fallback reply: %v", err)
+	}
+
+	answers := 0
+	for _, msg := range h.slack.Messages("C1") {
+		if strings.Contains(msg.Text, "healthy") {
+			answers++
+		}
+	}
+	if answers != 1 {
+		t.Errorf("got %d answers stored in the channel, want 1", answers)
+	}
+}
+
+func TestClientE2E_MessageShortcut(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "The disk filled up because of old logs" })
+
+	alert := slack.Message{Msg: slack.Msg{
+		User: "U2",
+		Text: "ALERT: disk usage at 98%",
+		Attachments: []slack.Attachment{{
+			Title:  "db-1",
+			Fields: []slack.AttachmentField{{Title: "mount", Value: "/var/lib/postgresql"}},
+		}},
+	}}
+	alert.Timestamp = h.slack.AddMessage("C1", alert)
+
+	envelope, err := h.slack.Send(socketmode.RequestTypeInteractive, &slack.InteractionCallback{
+		Type:       slack.InteractionTypeMessageAction,
+		CallbackID: MessageShortcutCallbackID,
+		TriggerID:  "trigger-1",
+		User:       slack.User{ID: "U1"},
+		Channel:    slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C1"}}},
+		Message:    alert,
+		MessageTs:  alert.Timestamp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.slack.WaitForAck(envelope, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	open, err := h.slack.WaitForCall("views.open", e2eTimeout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opened struct {
+		TriggerID string     `json:"trigger_id"`
+		View      slack.View `json:"view"`
+	}
+	if err := json.Unmarshal(open.Body, &opened); err != nil {
+		t.Fatalf("failed to decode views.open body: %v", err)
+	}
+	if opened.TriggerID != "trigger-1" || opened.View.CallbackID != messageShortcutModalCallbackID {
+		t.Fatalf("unexpected modal: trigger %q, callback %q", opened.TriggerID, opened.View.CallbackID)
+	}
+
+	if _, err := h.slack.Send(socketmode.RequestTypeInteractive, &slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		User: slack.User{ID: "U1"},
+		View: slack.View{
+			CallbackID:      messageShortcutModalCallbackID,
+			PrivateMetadata: opened.View.PrivateMetadata,
+			State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+				shortcutInstructionBlockID: {shortcutInstructionActionID: {Value: "find the root cause"}},
+			}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reply := h.waitForReply(t, "C1", alert.Timestamp)
+	if !strings.Contains(reply.Param("text")+reply.Param("blocks"), "old logs") {
+		t.Errorf("reply = %q, want the LLM answer", reply.Param("text"))
+	}
+
+	requests := h.llm.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d LLM requests, want 1", len(requests))
+	}
+	for _, want := range []string{"find the root cause", "ALERT: disk usage at 98%", "mount: /var/lib/postgresql", "/archives/C1/p"} {
+		if !strings.Contains(requests[0], want) {
+			t.Errorf("LLM request missing %q:\n%s", want, requests[0])
+		}
+	}
+}
+
+func TestClientE2E_TranscriptExport(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "Restart the pod" })
+
+	root, err := h.slack.MentionBot("C1", "U1", "how do I fix the crash loop?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", root)
+
+	if _, err := h.slack.MentionBot("C1", "U1", "export transcript json", root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.slack.WaitForCall("files.completeUploadExternal", e2eTimeout, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := h.slack.Uploads()
+	if len(uploads) != 1 {
+		t.Fatalf("got %d uploads, want 1", len(uploads))
+	}
+	upload := uploads[0]
+	if upload.Channel != "C1" || upload.ThreadTS != root || !strings.HasSuffix(upload.Filename, ".json") {
+		t.Errorf("upload = %+v, want a JSON file in the thread", upload)
+	}
+	var exported transcript
+	if err := json.Unmarshal(upload.Content, &exported); err != nil {
+		t.Fatalf("uploaded transcript is not valid JSON: %v", err)
+	}
+	var kinds []string
+	for _, msg := range exported.Messages {
+		kinds = append(kinds, msg.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != transcriptUserPrompt || kinds[1] != transcriptModelAnswer ||
+		exported.Messages[1].Content != "Restart the pod" {
+		t.Errorf("transcript messages = %+v, want the prompt and the answer", exported.Messages)
+	}
+	if requests := h.llm.Requests(); len(requests) != 1 {
+		t.Errorf("the export command should not reach the LLM, got %d requests", len(requests))
+	}
+}
+
+func TestClientE2E_Redaction(t *testing.T) {
+	h := newE2EHarness(t, func(prompt string) string {
+		// Answer with the placeholder the model was given
+		return "I will notify [EMAIL_1] about the rotated key."
+	}, func(cfg *config.Config) {
+		cfg.Redaction = config.RedactionConfig{Enabled: true, RedactUserNames: true}
+	})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "tell carol@example.com that sk-live-abcdefghijklmnopqrstuvwx leaked", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", ts)
+
+	requests := h.llm.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d LLM requests, want 1", len(requests))
+	}
+	for _, secret := range []string{"carol@example.com", "sk-live-abcdefghijklmnopqrstuvwx", "alice@example.com", "Alice Example"} {
+		if strings.Contains(requests[0], secret) {
+			t.Errorf("LLM request contains %q:\n%s", secret, requests[0])
+		}
+	}
+	if !strings.Contains(requests[0], "[EMAIL_1]") || !strings.Contains(requests[0], "[API_KEY_1]") {
+		t.Errorf("LLM request should contain placeholders:\n%s", requests[0])
+	}
+	if text := reply.Param("text") + reply.Param("blocks"); !strings.Contains(text, "carol@example.com") {
+		t.Errorf("reply = %q, want the placeholder mapped back to the real email", text)
+	}
+}
+
+func TestClientE2E_Quota(t *testing.T) {
+	usageFile := filepath.Join(t.TempDir(), "usage.json")
+	h := newE2EHarness(t, func(string) string { return "The answer is 42" }, func(cfg *config.Config) {
+		// The fake LLM reports 15 tokens per call
+		cfg.Quotas = config.QuotaConfig{Enabled: true, UsageFile: usageFile, User: config.QuotaLimits{DailyTokens: 15}}
+	})
+
+	first, err := h.slack.MentionBot("C1", "U1", "what is the answer?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", first)
+
+	second, err := h.slack.MentionBot("C1", "U1", "and the question?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", second)
+	if text := reply.Param("text") + reply.Param("blocks"); !strings.Contains(text, "daily AI token quota") || !strings.Contains(text, "15 of 15 tokens") {
+		t.Errorf("reply = %q, want the quota message", text)
+	}
+	if requests := h.llm.Requests(); len(requests) != 1 {
+		t.Errorf("got %d LLM requests, want the second prompt to be refused", len(requests))
+	}
+	// Usage is written periodically and when the client closes
+	if err := h.client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if content, err := os.ReadFile(usageFile); err != nil || !strings.Contains(string(content), `"user:U1"`) {
+		t.Errorf("usage file = %q, %v, want the user's usage persisted", content, err)
+	}
+}
+
+func TestClientE2E_MentionResolution(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "Sure, @bob and @oncall will look at #incidents." })
+	h.slack.AddUser(slack.User{ID: "U2", RealName: "Bob Builder", Profile: slack.UserProfile{DisplayName: "bob"}})
+	h.slack.AddChannel(slack.Channel{GroupConversation: slack.GroupConversation{Name: "incidents", Conversation: slack.Conversation{ID: "C9"}}})
+	h.slack.AddUserGroup(slack.UserGroup{ID: "S1", Handle: "oncall", Name: "On-call"})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "ask <@U2> and <!subteam^S1> to check <#C9|>", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", ts)
+
+	requests := h.llm.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0], "ask @bob and @oncall to check #incidents") {
+		t.Errorf("LLM request should contain readable names, got %q", requests)
+	}
+	if text := reply.Param("text"); !strings.Contains(text, "<@U2> and <!subteam^S1> will look at <#C9>") {
+		t.Errorf("reply = %q, want names mapped back to mentions", text)
+	}
+}
+
+func TestClientE2E_PermalinkExpansion(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "It means the disk is full." }, func(cfg *config.Config) {
+		cfg.Slack.Permalinks = config.PermalinkConfig{MaxLinks: 3, IncludeThread: true, MaxThreadMessages: 20}
+	})
+	h.slack.AddUser(slack.User{ID: "U2", RealName: "Bob", Profile: slack.UserProfile{DisplayName: "bob"}})
+	h.slack.AddUser(slack.User{ID: "U3", RealName: "Gina Guest", IsRestricted: true})
+	h.slack.AddChannel(slack.Channel{IsChannel: true, GroupConversation: slack.GroupConversation{Name: "general", Conversation: slack.Conversation{ID: "C1"}}})
+	h.slack.AddChannel(slack.Channel{IsChannel: true, GroupConversation: slack.GroupConversation{Name: "alerts", Members: []string{"U2"}, Conversation: slack.Conversation{ID: "C2"}}})
+	h.slack.AddChannel(slack.Channel{IsChannel: true, GroupConversation: slack.GroupConversation{Name: "partner", Conversation: slack.Conversation{ID: "C3", IsExtShared: true}}})
+	h.slack.AddChannel(slack.Channel{IsChannel: true, GroupConversation: slack.GroupConversation{Name: "secret-ops", Members: []string{"U1"}, Conversation: slack.Conversation{ID: "G1", IsPrivate: true}}})
+	h.slack.AddChannel(slack.Channel{IsChannel: true, GroupConversation: slack.GroupConversation{Name: "hr", Members: []string{"U2"}, Conversation: slack.Conversation{ID: "G2", IsPrivate: true}}})
+
+	alert := h.slack.AddMessage("C2", slack.Message{Msg: slack.Msg{User: "U2", Text: "ENOSPC: no space left on device"}})
+	h.slack.AddMessage("C2", slack.Message{Msg: slack.Msg{User: "U1", Text: "looking", ThreadTimestamp: alert}})
+	private := h.slack.AddMessage("G1", slack.Message{Msg: slack.Msg{User: "U2", Text: "db password rotated"}})
+	hr := h.slack.AddMessage("G2", slack.Message{Msg: slack.Msg{User: "U2", Text: "salary review notes"}})
+	link := func(channel, ts string) string {
+		return "<https://fake.slack.com/archives/" + channel + "/p" + strings.ReplaceAll(ts, ".", "") + ">"
+	}
+
+	// Public channel links are expanded with their thread; private ones are not shown in a channel
+	ts, err := h.slack.MentionBot("C1", "U1", "what does this mean? "+link("C2", alert)+" and "+link("G1", private), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", ts)
+	request := h.llm.Requests()[0]
+	for _, want := range []string{"Thread in #alerts", "> @bob, ", "> ENOSPC: no space left on device", "> looking"} {
+		if !strings.Contains(request, want) {
+			t.Errorf("LLM request missing %q:\n%s", want, request)
+		}
+	}
+	if strings.Contains(request, "db password rotated") {
+		t.Errorf("private channel message leaked into a public channel reply:\n%s", request)
+	}
+
+	// In a DM, private links are expanded only for members of the linked channel
+	ts, err = h.slack.SendDirectMessage("D1", "U1", "summarize "+link("G1", private)+" and "+link("G2", hr), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "D1", ts)
+	request = h.llm.Requests()[1]
+	if !strings.Contains(request, "> db password rotated") {
+		t.Errorf("LLM request should quote the private message the user can see:\n%s", request)
+	}
+	if strings.Contains(request, "salary review notes") {
+		t.Errorf("message from a channel the user is not in leaked:\n%s", request)
+	}
+
+	// Public links are not expanded for guests outside the linked channel or into Slack Connect channels
+	ts, err = h.slack.MentionBot("C1", "U3", "what does this mean? "+link("C2", alert), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", ts)
+	ts, err = h.slack.MentionBot("C3", "U1", "what does this mean? "+link("C2", alert), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C3", ts)
+	for _, request := range h.llm.Requests()[2:] {
+		if strings.Contains(request, "ENOSPC") {
+			t.Errorf("public channel message leaked to a guest or a shared channel:\n%s", request)
+		}
+	}
+}
+
+func TestClientE2E_ChannelHistory(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "Today the deploy failed and was rolled back." }, func(cfg *config.Config) {
+		cfg.Slack.ChannelHistory = config.ChannelHistoryConfig{
+			MaxMessages: 50,
+			MaxAgeHours: 24,
+			Channels:    map[string]config.ChannelHistoryLimits{"C1": {MaxMessages: 2}},
+		}
+	})
+	old := time.Now().Add(-48 * time.Hour)
+	h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "last week's outage", Timestamp: slackTimestamp(old)}})
+	h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "morning everyone"}})
+	h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "deploy failed"}})
+	h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", SubType: "channel_join", Text: "<@U1> has joined the channel"}})
+	h.slack.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "rolled back"}})
+	h.slack.AddMessage("C3", slack.Message{Msg: slack.Msg{User: "U1", Text: "not opted in"}})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "summarize what happened here today", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", ts)
+
+	request := h.llm.Requests()[0]
+	start, end := strings.Index(request, "<<<CHANNEL_HISTORY"), strings.Index(request, "CHANNEL_HISTORY>>>")
+	if start < 0 || end < start {
+		t.Fatalf("LLM request has no delimited channel history:\n%s", request)
+	}
+	history := request[start:end]
+	if !strings.Contains(history, "Alice Example: deploy failed\n[") || !strings.HasSuffix(strings.TrimSpace(history), "Alice Example: rolled back") {
+		t.Errorf("channel history should hold the last 2 messages, oldest first:\n%s", history)
+	}
+	for _, unwanted := range []string{"morning everyone", "last week's outage", "has joined", "summarize what happened"} {
+		if strings.Contains(history, unwanted) {
+			t.Errorf("channel history should not contain %q:\n%s", unwanted, history)
+		}
+	}
+	historyCalls := h.slack.Calls("conversations.history")
+	for _, call := range historyCalls {
+		if call.Param("channel") != "C1" || call.Param("oldest") == "" {
+			t.Errorf("conversations.history called with %v, want channel C1 and oldest set", call.Params)
+		}
+	}
+
+	// Channels that did not opt in, and replies in threads, don't load channel history
+	ts, err = h.slack.MentionBot("C3", "U1", "what happened?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C3", ts)
+	if _, err = h.slack.MentionBot("C1", "U1", "and then?", ts); err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", ts)
+	if calls := h.slack.Calls("conversations.history"); len(calls) != len(historyCalls) {
+		t.Errorf("got %d conversations.history calls, want %d", len(calls), len(historyCalls))
+	}
+}
+
+func TestClientE2E_ToolLoop(t *testing.T) {
+	// The model calls two tools at once, then chains another call on their results. No MCP servers are
+	// connected, so every call fails and the error is sent back as the tool result.
+	h := newE2EHarness(t, func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "cannot call any more tools"):
+			return "Both lookups failed, so I can't tell who is on call."
+		case strings.Contains(prompt, "tool_result(call_3)"):
+			return fakeToolCallsPrefix + `[{"id":"call_4","name":"get_schedule","arguments":"{}"}]`
+		case strings.Contains(prompt, "tool_result(call_2)"):
+			return fakeToolCallsPrefix + `[{"id":"call_3","name":"get_schedule","arguments":"{\"team\":\"sre\"}"}]`
+		case strings.Contains(prompt, "who is on call"):
+			return fakeToolCallsPrefix + `[{"id":"call_1","name":"get_team","arguments":"{\"user\":\"alice\"}"},` +
+				`{"id":"call_2","name":"get_rotation","arguments":"{}"}]`
+		}
+		return "The answer is 42"
+	}, func(cfg *config.Config) {
+		cfg.LLM.UseNativeTools = true
+		cfg.LLM.ToolLoop = config.ToolLoopConfig{MaxIterations: 2}
+	})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "who is on call for my team?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", ts)
+	if got := reply.Param("text"); got != "Both lookups failed, so I can't tell who is on call." {
+		t.Errorf("reply = %q", got)
+	}
+
+	requests := h.llm.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d LLM requests, want 3 (question, two tool rounds)", len(requests))
+	}
+	followUp := requests[1]
+	for _, want := range []string{"tool_call(call_1)\ntool_result(call_1): Error:", "tool_call(call_2)\ntool_result(call_2): Error:", "get_team"} {
+		if !strings.Contains(followUp, want) {
+			t.Errorf("first follow-up request should contain %q:\n%s", want, followUp)
+		}
+	}
+	if strings.Contains(followUp, "cannot call any more tools") {
+		t.Errorf("first follow-up request should still allow tools:\n%s", followUp)
+	}
+	// After the second round the iteration budget is used up and the model must answer
+	final := requests[2]
+	if !strings.Contains(final, "tool_result(call_1)") || !strings.Contains(final, "tool_result(call_3)") || !strings.Contains(final, "cannot call any more tools") {
+		t.Errorf("final request should hold every tool result and ask for an answer:\n%s", final)
+	}
+}
+
+func TestClientE2E_BridgeTimeout(t *testing.T) {
+	h := newE2EHarness(t, func(string) string {
+		time.Sleep(time.Second)
+		return "Too late"
+	}, func(cfg *config.Config) {
+		cfg.Timeouts.BridgeOperationTimeout = "100ms"
+	})
+
+	start := time.Now()
+	ts, err := h.slack.MentionBot("C1", "U1", "what is the answer?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := h.waitForReply(t, "C1", ts)
+	if got := reply.Param("text"); !strings.Contains(got, "error with the LLM provider") {
+		t.Errorf("reply = %q, want the LLM error", got)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("reply took %s, want it within the bridge timeout", elapsed)
+	}
+}
+
+func TestClientE2E_PromptTemplate(t *testing.T) {
+	h := newE2EHarness(t, func(string) string { return "Done" }, func(cfg *config.Config) {
+		cfg.LLM.CustomPrompt = "You help {{.UserName}} ({{.UserTitle}}) in #{{.ChannelName}} of team {{.Extras.team}}, " +
+			"using {{.Model}} on {{.TimeZone}} time."
+		cfg.LLM.PromptExtras = map[string]string{"team": "everyone"}
+		cfg.LLM.ChannelPromptExtras = map[string]map[string]string{"C1": {"team": "sre"}}
+	})
+	h.slack.AddUser(slack.User{ID: "U1", RealName: "Alice Example", TZ: "Europe/Berlin", Profile: slack.UserProfile{Title: "On-call engineer"}})
+	h.slack.AddChannel(slack.Channel{GroupConversation: slack.GroupConversation{Name: "ops", Conversation: slack.Conversation{ID: "C1"}}})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "restart the api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.waitForReply(t, "C1", ts)
+
+	requests := h.llm.Requests()
+	want := "You help Alice Example (On-call engineer) in #ops of team sre, using gpt-4o on Europe/Berlin time."
+	if len(requests) != 1 || !strings.Contains(requests[0], want) {
+		t.Errorf("LLM requests = %q, want the rendered prompt %q", requests, want)
+	}
+}
+
+func TestClientE2E_AgentTrace(t *testing.T) {
+	// No MCP servers are connected, so the agent's tool call fails and shows as a failed step
+	h := newE2EHarness(t, func(prompt string) string {
+		if strings.Contains(prompt, "tool_result(call_1)") {
+			return "The api service could not be checked."
+		}
+		return fakeToolCallsPrefix + `[{"id":"call_1","name":"get_status","arguments":"{\"service\":\"api\"}"}]`
+	}, func(cfg *config.Config) {
+		cfg.LLM.UseAgent = true
+		cfg.LLM.AgentMode = config.AgentModeNative
+		cfg.LLM.MaxAgentIterations = 5
+		cfg.Slack.AgentTrace = config.AgentTraceConfig{Verbosity: config.AgentTraceDetailed}
+	})
+
+	ts, err := h.slack.MentionBot("C1", "U1", "is the api up?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.slack.WaitForCall("chat.postMessage", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("thread_ts") == ts && strings.Contains(c.Param("text"), "Running `get_status`")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := h.slack.WaitForCall("chat.postMessage", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("thread_ts") == ts && strings.Contains(c.Param("text"), "could not be checked")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(reply.Param("text"), "tool_calls") {
+		t.Errorf("reply = %q, want only the answer", reply.Param("text"))
+	}
+
+	var traceTS string
+	for _, msg := range h.slack.Messages("C1") {
+		if strings.HasPrefix(msg.Text, ":wrench: Made 1 tool call in") {
+			traceTS = msg.Timestamp
+		}
+	}
+	if traceTS == "" {
+		t.Fatalf("trace was not updated with the summary; messages = %+v", h.slack.Messages("C1"))
+	}
+	done, err := h.slack.WaitForCall("chat.update", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("ts") == traceTS && strings.Contains(c.Param("blocks"), "Hide steps")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Detailed traces stay expanded, with the arguments and the error of each step
+	for _, want := range []string{"(1 failed)", "✗ `get_status`", `{\"service\":\"api\"}`, "unknown tool"} {
+		if !strings.Contains(done.Param("blocks"), want) {
+			t.Errorf("finished trace should contain %q: %s", want, done.Param("blocks"))
+		}
+	}
+	if _, err := h.slack.Send(socketmode.RequestTypeInteractive, &slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		User:           slack.User{ID: "U1"},
+		Container:      slack.Container{ChannelID: "C1", MessageTs: traceTS},
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: agentTraceToggleActionID}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	collapsed, err := h.slack.WaitForCall("chat.update", e2eTimeout, func(c slackfake.Call) bool {
+		return c.Param("ts") == traceTS && strings.Contains(c.Param("blocks"), "Show steps")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(collapsed.Param("blocks"), "✗ `get_status`") {
+		t.Errorf("collapsed trace should only show the summary: %s", collapsed.Param("blocks"))
+	}
+}
+
+func slackTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+func TestSlackClient_GetChannelHistoryPaginates(t *testing.T) {
+	fake := slackfake.NewServer()
+	t.Cleanup(fake.Close)
+	for i := 0; i < 250; i++ {
+		fake.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: fmt.Sprintf("message %d", i)}})
+	}
+	frontend, err := GetSlackClient("xoxb-test", "xapp-test", logging.New("e2e", logging.LevelError), "Thinking...", slack.OptionAPIURL(fake.APIURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	odd := func(msg slack.Message) bool {
+		return strings.HasSuffix(msg.Text, "1") || strings.HasSuffix(msg.Text, "3")
+	}
+	messages, err := frontend.GetChannelHistory("C1", time.Time{}, 45, odd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first page of 200 messages only has 40 matches
+	if len(messages) != 45 || messages[0].Text != "message 243" || messages[44].Text != "message 23" {
+		t.Errorf("got %d messages from %q to %q", len(messages), messages[0].Text, messages[len(messages)-1].Text)
+	}
+	if calls := fake.Calls("conversations.history"); len(calls) != 2 {
+		t.Errorf("got %d conversations.history calls, want 2 pages", len(calls))
+	}
+}
+
+func TestSlackClient_ConcurrentUserLookups(t *testing.T) {
+	fake := slackfake.NewServer()
+	t.Cleanup(fake.Close)
+	for i := 0; i < 5; i++ {
+		fake.AddUser(slack.User{ID: fmt.Sprintf("U%d", i), RealName: fmt.Sprintf("User %d", i), TZ: "Europe/Berlin"})
+	}
+	frontend, err := GetSlackClient("xoxb-test", "xapp-test", logging.New("e2e", logging.LevelError), "Thinking...", slack.OptionAPIURL(fake.APIURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Prompts are handled concurrently and share the user caches
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			if _, err := frontend.GetUserInfo(userID); err != nil {
+				t.Errorf("GetUserInfo(%s) error = %v", userID, err)
+			}
+			if tz, err := frontend.GetUserTimeZone(userID); err != nil || tz != "Europe/Berlin" {
+				t.Errorf("GetUserTimeZone(%s) = %q, %v", userID, tz, err)
+			}
+		}(fmt.Sprintf("U%d", i%5))
+	}
+	wg.Wait()
+
+	// Concurrent misses may fetch a user twice, but once cached no lookup calls Slack again
+	fetched := len(fake.Calls("users.info"))
+	if fetched < 5 {
+		t.Errorf("got %d users.info calls, want at least one per user", fetched)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := frontend.GetUserTimeZone(fmt.Sprintf("U%d", i)); err != nil {
+			t.Errorf("GetUserTimeZone(U%d) error = %v", i, err)
+		}
+	}
+	if calls := fake.Calls("users.info"); len(calls) != fetched {
+		t.Errorf("got %d users.info calls after the lookups were cached, want %d", len(calls), fetched)
+	}
+}
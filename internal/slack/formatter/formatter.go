@@ -138,20 +138,45 @@ func FormatMessage(text string, options FormatOptions) []slack.MsgOption {
 				msgOptions = append(msgOptions, slack.MsgOptionText(fallbackText, false))
 			} else {
 				// Failed to parse blocks, fall back to text
-				msgOptions = append(msgOptions, slack.MsgOptionText(text, options.EscapeText))
+				msgOptions = append(msgOptions, textOption(text, options.EscapeText))
 			}
 		} else {
 			// Not valid JSON, treat as text
-			msgOptions = append(msgOptions, slack.MsgOptionText(text, options.EscapeText))
+			msgOptions = append(msgOptions, textOption(text, options.EscapeText))
 		}
 	case TextFormat:
 		// Simple text message with mrkdwn
-		msgOptions = append(msgOptions, slack.MsgOptionText(text, options.EscapeText))
+		msgOptions = append(msgOptions, textOption(text, options.EscapeText))
 	}
 
 	return msgOptions
 }
 
+// textOption sets the message text, escaping it if requested. Mentions are kept so they notify users.
+func textOption(text string, escape bool) slack.MsgOption {
+	if escape {
+		text = EscapeTextKeepingMentions(text)
+	}
+	return slack.MsgOptionText(text, false)
+}
+
+// mentionTokenPattern matches Slack user, channel, user group and special mentions,
+// e.g. <@U123>, <#C123|general>, <!subteam^S123|@oncall> and <!here>
+var mentionTokenPattern = regexp.MustCompile(`<(?:@[UW][A-Z0-9]+|#C[A-Z0-9]+|!subteam\^S[A-Z0-9]+|!(?:here|channel|everyone))(?:\|[^<>]*)?>`)
+
+// EscapeTextKeepingMentions escapes &, < and > like EscapeMarkdown, except inside mention tokens
+func EscapeTextKeepingMentions(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, match := range mentionTokenPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(EscapeMarkdown(text[last:match[0]]))
+		sb.WriteString(text[match[0]:match[1]])
+		last = match[1]
+	}
+	sb.WriteString(EscapeMarkdown(text[last:]))
+	return sb.String()
+}
+
 // CreateBlockMessage creates a Block Kit message with the given options
 func CreateBlockMessage(text string, blockOptions BlockOptions) string {
 	blocks := []map[string]interface{}{}
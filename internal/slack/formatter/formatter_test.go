@@ -275,3 +275,25 @@ Result: Passed`,
 		})
 	}
 }
+
+func TestEscapeTextKeepingMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "a < b && c > d", "a &lt; b &amp;&amp; c &gt; d"},
+		{"user mention", "ping <@U123ABC> about it", "ping <@U123ABC> about it"},
+		{"channel and group", "see <#C0456|general>, cc <!subteam^S789|@oncall>", "see <#C0456|general>, cc <!subteam^S789|@oncall>"},
+		{"special mention", "<!here> deploy done", "<!here> deploy done"},
+		{"links are escaped", "<https://example.com|site>", "&lt;https://example.com|site&gt;"},
+		{"malformed mention", "<@alice> & <@U1", "&lt;@alice&gt; &amp; &lt;@U1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeTextKeepingMentions(tt.input); got != tt.expected {
+				t.Errorf("EscapeTextKeepingMentions(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
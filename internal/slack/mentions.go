@@ -0,0 +1,199 @@
+package slackbot
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mentionPattern matches Slack mention tokens: users <@U123>, channels <#C123|general>,
+// user groups <!subteam^S123|@oncall> and special mentions such as <!here>.
+// Group 1 is the kind, group 2 the ID and group 3 the optional label.
+var mentionPattern = regexp.MustCompile(`<(@|#|!subteam\^|!)([A-Za-z0-9]+)(?:\|([^<>]*))?>`)
+
+// mentionResolver turns Slack mention tokens into readable names before text is sent to the LLM,
+// and maps those names in the LLM's answer back to mention tokens. Create one per interaction,
+// so that only names seen in the conversation are turned back into mentions.
+type mentionResolver struct {
+	client *Client
+
+	mu        sync.Mutex
+	tokens    map[string]string // Readable name such as "@alice" to the Slack token
+	ambiguous map[string]bool   // Names shared by several users or channels, never mapped back
+}
+
+func (c *Client) newMentionResolver() *mentionResolver {
+	return &mentionResolver{
+		client:    c,
+		tokens:    make(map[string]string),
+		ambiguous: make(map[string]bool),
+	}
+}
+
+// Resolve replaces mention tokens with "@name", "#channel" or "@group-handle".
+// Tokens that cannot be resolved are shown with their ID, e.g. "@U123".
+func (m *mentionResolver) Resolve(text string) string {
+	if m == nil || !strings.Contains(text, "<") {
+		return text
+	}
+	return mentionPattern.ReplaceAllStringFunc(text, func(token string) string {
+		parts := mentionPattern.FindStringSubmatch(token)
+		kind, id, label := parts[1], parts[2], strings.TrimSpace(parts[3])
+		switch kind {
+		case "@":
+			if label == "" {
+				label = m.userName(id)
+			}
+			return m.remember("@"+strings.TrimPrefix(label, "@"), "<@"+id+">")
+		case "#":
+			if label == "" {
+				label = m.channelName(id)
+			}
+			return m.remember("#"+strings.TrimPrefix(label, "#"), "<#"+id+">")
+		case "!subteam^":
+			if label == "" {
+				label = m.groupHandle(id)
+			}
+			return m.remember("@"+strings.TrimPrefix(label, "@"), "<!subteam^"+id+">")
+		default:
+			// Special mentions like <!here> are shown but never mapped back, so the bot can't ping a whole channel
+			if label == "" {
+				label = id
+			}
+			return "@" + strings.TrimPrefix(label, "@")
+		}
+	})
+}
+
+func (m *mentionResolver) userName(userID string) string {
+	profile, err := m.client.userFrontend.GetUserInfo(userID)
+	if err != nil {
+		m.client.logger.WarnKV("Failed to resolve user mention", "user", userID, "error", err)
+		return userID
+	}
+	if name := profile.mentionName(); name != "" && name != "Unknown" {
+		return name
+	}
+	return userID
+}
+
+func (m *mentionResolver) channelName(channelID string) string {
+	directory, ok := m.client.userFrontend.(directoryFrontend)
+	if !ok {
+		return channelID
+	}
+	name, err := directory.GetChannelName(channelID)
+	if err != nil || name == "" {
+		m.client.logger.WarnKV("Failed to resolve channel link", "channel", channelID, "error", err)
+		return channelID
+	}
+	return name
+}
+
+func (m *mentionResolver) groupHandle(groupID string) string {
+	directory, ok := m.client.userFrontend.(directoryFrontend)
+	if !ok {
+		return groupID
+	}
+	handle, err := directory.GetUserGroupHandle(groupID)
+	if err != nil || handle == "" {
+		m.client.logger.WarnKV("Failed to resolve user group mention", "group", groupID, "error", err)
+		return groupID
+	}
+	return handle
+}
+
+// remember records the token a readable name stands for and returns the name
+func (m *mentionResolver) remember(name, token string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.tokens[name]; ok && existing != token {
+		m.ambiguous[name] = true
+	}
+	m.tokens[name] = token
+	return name
+}
+
+// userNames returns the names of the users mentioned so far, without the leading "@"
+func (m *mentionResolver) userNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name, token := range m.tokens {
+		if strings.HasPrefix(token, "<@") {
+			names = append(names, strings.TrimPrefix(name, "@"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Restore replaces names produced by Resolve with their mention tokens, so "ping @alice" notifies alice
+func (m *mentionResolver) Restore(text string) string {
+	if m == nil || (!strings.Contains(text, "@") && !strings.Contains(text, "#")) {
+		return text
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.tokens))
+	for name := range m.tokens {
+		if !m.ambiguous[name] {
+			names = append(names, name)
+		}
+	}
+	// Longer names first, so "@alice.smith" is not restored as "@alice" followed by ".smith"
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	// Scan once so that restored tokens are never matched again
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] == '@' || text[i] == '#' {
+			if name := matchName(text, i, names); name != "" {
+				sb.WriteString(m.tokens[name])
+				i += len(name)
+				continue
+			}
+		}
+		sb.WriteByte(text[i])
+		i++
+	}
+	return sb.String()
+}
+
+// matchName returns the first name found as a whole word at position i of text,
+// e.g. "@al" in "hi @al." but not in "x@al" or "@alex"
+func matchName(text string, i int, names []string) string {
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	if i > 0 && isNameRune(before) {
+		return ""
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(text[i:], name) {
+			continue
+		}
+		rest := text[i+len(name):]
+		after, _ := utf8.DecodeRuneInString(rest)
+		if rest == "" || !isNameRune(after) || isTrailingPunctuation(rest) {
+			return name
+		}
+	}
+	return ""
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// isTrailingPunctuation reports whether rest starts with punctuation ending a sentence, like "." in "@al."
+func isTrailingPunctuation(rest string) bool {
+	r, size := utf8.DecodeRuneInString(rest)
+	if r != '.' && r != '-' {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(rest[size:])
+	return size == len(rest) || !isNameRune(next)
+}
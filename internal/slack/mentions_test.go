@@ -0,0 +1,39 @@
+package slackbot
+
+import "testing"
+
+func TestMentionResolver_RoundTrip(t *testing.T) {
+	// Labelled tokens resolve without any lookups
+	m := (&Client{}).newMentionResolver()
+	resolved := m.Resolve("<!here> ask <@U1|alice> and <@U2|alice.smith> in <#C1|ops>, cc <!subteam^S1|@oncall>")
+	if want := "@here ask @alice and @alice.smith in #ops, cc @oncall"; resolved != want {
+		t.Fatalf("Resolve() = %q, want %q", resolved, want)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"names", "Pinging @alice and @oncall in #ops.", "Pinging <@U1> and <!subteam^S1> in <#C1>."},
+		{"longest name wins", "@alice.smith, not @alice", "<@U2>, not <@U1>"},
+		{"partial words are kept", "@alex mailed bob@alice and #opsroom", "@alex mailed bob@alice and #opsroom"},
+		{"special mentions are not restored", "@here @channel", "@here @channel"},
+		{"existing tokens are kept", "<@U1> said hi", "<@U1> said hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Restore(tt.input); got != tt.want {
+				t.Errorf("Restore(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionResolver_AmbiguousNames(t *testing.T) {
+	m := (&Client{}).newMentionResolver()
+	m.Resolve("<@U1|sam> and <@U2|sam> and <@U3|kim>")
+	if got := m.Restore("thanks @sam and @kim"); got != "thanks @sam and <@U3>" {
+		t.Errorf("Restore() = %q, want ambiguous names left as text", got)
+	}
+}
@@ -0,0 +1,214 @@
+package slackbot
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+// IDs of the user-facing messages that can be overridden in the message catalog
+const (
+	msgLLMError         = "llm_error"          // The LLM provider call failed
+	msgEmptyResponse    = "empty_response"     // The LLM returned no content
+	msgToolCallError    = "tool_call_error"    // The tool call in the LLM response could not be parsed
+	msgToolError        = "tool_error"         // A tool call failed and the answer could not be generated
+	msgRepromptError    = "reprompt_error"     // Tools succeeded but the final answer could not be generated
+	msgTranscriptEmpty  = "transcript_empty"   // A transcript was requested for a thread without history
+	msgTranscriptFailed = "transcript_failed"  // The transcript could not be rendered or uploaded
+	msgQuotaExceeded    = "quota_exceeded"     // The user or channel used up its token quota
+	msgQuotaStatus      = "quota_status"       // Reply to "quota": the user's token usage
+	msgQuotaOverride    = "quota_override"     // An admin lifted a quota
+	msgQuotaForbidden   = "quota_forbidden"    // A non-admin tried to lift a quota
+	msgQuotaDisabled    = "quota_disabled"     // Quota commands were used while quotas are disabled
+	msgAgentTraceActive = "agent_trace_active" // Agent trace header while the agent runs
+	msgAgentTraceDone   = "agent_trace_done"   // Agent trace header once the agent finished
+	msgAgentTraceShow   = "agent_trace_show"   // Button expanding the agent trace
+	msgAgentTraceHide   = "agent_trace_hide"   // Button collapsing the agent trace
+)
+
+// errorReference is appended to error messages so users can quote them when asking for help
+const errorReference = " Error code: `{{.ErrorCode}}`{{if .TraceID}}, trace ID: `{{.TraceID}}`{{end}}."
+
+// defaultMessageTemplates are the built-in English messages, used when the catalog has no override
+var defaultMessageTemplates = map[string]string{
+	msgLLMError:         "Sorry, I encountered an error with the LLM provider ('{{.Provider}}'){{if .Error}}: {{.Error}}{{end}}." + errorReference,
+	msgEmptyResponse:    "(LLM returned an empty response)",
+	msgToolCallError:    "Sorry, I encountered an error extracting the tool call{{if .Error}}: {{.Error}}{{end}}." + errorReference,
+	msgToolError:        "Sorry, I encountered an error while trying to use a tool{{if .Tool}} (`{{.Tool}}`){{end}}{{if .Error}}: {{.Error}}{{end}}." + errorReference,
+	msgRepromptError:    "Tool Result:\n```{{.ToolResult}}```\n\n(Error generating final response{{if .Error}}: {{.Error}}{{end}}." + errorReference + ")",
+	msgTranscriptEmpty:  "There is no conversation history stored for this thread yet.",
+	msgTranscriptFailed: "Sorry, I could not export the transcript{{if .Error}}: {{.Error}}{{end}}." + errorReference,
+	msgQuotaExceeded: "Sorry, {{if eq .QuotaScope \"channel\"}}this channel has{{else}}you have{{end}} used up {{if eq .QuotaScope \"channel\"}}its{{else}}your{{end}} {{.QuotaPeriod}} AI token quota " +
+		"({{.TokensUsed}} of {{.TokenLimit}} tokens). It resets on {{.ResetsAt}}; ask an admin if you need more before then.",
+	msgQuotaStatus: "Your AI token usage: {{.DailyTokens}}{{if .DailyLimit}} of {{.DailyLimit}}{{end}} tokens today, " +
+		"{{.MonthlyTokens}}{{if .MonthlyLimit}} of {{.MonthlyLimit}}{{end}} tokens this month.",
+	msgQuotaOverride:  "Lifted the token quota of {{.QuotaTarget}} until {{.ResetsAt}}.",
+	msgQuotaForbidden: "Sorry, only admins can lift token quotas.",
+	msgQuotaDisabled:  "Token quotas are not enabled.",
+	msgAgentTraceActive: ":hourglass_flowing_sand: {{if .Tool}}Running `{{.Tool}}`{{else}}Thinking{{end}}…" +
+		"{{if .StepCount}} ({{.StepCount}} tool call{{if ne .StepCount 1}}s{{end}} done){{end}}",
+	msgAgentTraceDone: ":wrench: Made {{.StepCount}} tool call{{if ne .StepCount 1}}s{{end}} in {{.Duration}}{{if .FailedSteps}} ({{.FailedSteps}} failed){{end}}",
+	msgAgentTraceShow: "Show steps",
+	msgAgentTraceHide: "Hide steps",
+}
+
+// messageData holds the fields available to message templates
+type messageData struct {
+	Provider   string // LLM provider name
+	Tool       string // Tool name, for tool errors
+	ToolResult string // Tool output, for re-prompt errors
+	ErrorCode  string // Machine-readable error code, e.g. "llm_request_failed"
+	TraceID    string // Trace of the interaction, when tracing is enabled
+	Error      string // Raw error details; only set for admins
+	IsAdmin    bool
+
+	QuotaScope    string // "user" or "channel", for quota messages
+	QuotaPeriod   string // "daily" or "monthly"
+	QuotaTarget   string // User or channel mention whose quota was lifted
+	TokensUsed    int    // Tokens used in the exhausted quota's period
+	TokenLimit    int
+	DailyTokens   int // Tokens used today, for the usage status
+	DailyLimit    int // 0 when there is no daily limit
+	MonthlyTokens int
+	MonthlyLimit  int
+	ResetsAt      string // When the quota resets or the override ends, e.g. "2026-04-01 00:00 UTC"
+
+	StepCount   int    // Tool calls of the agent so far, for agent trace headers
+	FailedSteps int    // Tool calls that failed
+	Duration    string // Time the agent ran, e.g. "3.4s"
+}
+
+// recipient is the user a message is rendered for
+type recipient struct {
+	userID string
+	locale string // Slack locale such as "en-US", or "" if unknown
+	admin  bool
+}
+
+// errorData fills in the error fields of a message, hiding raw details from non-admins
+func (r recipient) errorData(err error, traceID string) messageData {
+	data := messageData{ErrorCode: "internal_error", TraceID: traceID, IsAdmin: r.admin}
+	if code, ok := customErrors.GetErrorCode(err); ok && code != "" {
+		data.ErrorCode = code
+	}
+	if r.admin && err != nil {
+		data.Error = err.Error()
+	}
+	return data
+}
+
+// messageCatalog renders user-facing messages from per-locale templates
+type messageCatalog struct {
+	defaultLocale string
+	locales       map[string]map[string]*template.Template // By normalized locale, then message ID
+	builtin       map[string]*template.Template
+}
+
+// newMessageCatalog parses the built-in and configured templates
+func newMessageCatalog(cfg config.MessagesConfig) (*messageCatalog, error) {
+	catalog := &messageCatalog{
+		defaultLocale: normalizeLocale(cfg.DefaultLocale),
+		locales:       make(map[string]map[string]*template.Template),
+		builtin:       make(map[string]*template.Template),
+	}
+	for id, text := range defaultMessageTemplates {
+		catalog.builtin[id] = template.Must(template.New(id).Parse(text))
+	}
+
+	for locale, messages := range cfg.Locales {
+		parsed := make(map[string]*template.Template, len(messages))
+		for id, text := range messages {
+			if _, known := defaultMessageTemplates[id]; !known {
+				return nil, customErrors.NewConfigErrorf("unknown_message_id", "unknown message ID %q in locale %q", id, locale)
+			}
+			tmpl, err := template.New(id).Parse(text)
+			if err != nil {
+				return nil, customErrors.WrapConfigError(err, "invalid_message_template", fmt.Sprintf("invalid template for message %q in locale %q", id, locale))
+			}
+			// Execute once with sample data so references to unknown fields fail at startup
+			if err := tmpl.Execute(&strings.Builder{}, messageData{}); err != nil {
+				return nil, customErrors.WrapConfigError(err, "invalid_message_template", fmt.Sprintf("invalid template for message %q in locale %q", id, locale))
+			}
+			parsed[id] = tmpl
+		}
+		catalog.locales[normalizeLocale(locale)] = parsed
+	}
+	return catalog, nil
+}
+
+// ValidateMessages checks that configured message templates parse and only use known message IDs and fields
+func ValidateMessages(cfg config.MessagesConfig) error {
+	_, err := newMessageCatalog(cfg)
+	return err
+}
+
+// hasLocales reports whether any templates are configured, i.e. whether user locales matter
+func (m *messageCatalog) hasLocales() bool {
+	return len(m.locales) > 0
+}
+
+// render executes the template for a message, preferring the user's locale, then its language,
+// then the default locale and finally the built-in English text
+func (m *messageCatalog) render(id, locale string, data messageData) string {
+	for _, candidate := range m.localeCandidates(locale) {
+		tmpl, ok := m.locales[candidate][id]
+		if !ok {
+			continue
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err == nil {
+			return sb.String()
+		}
+		break // Fall back to the built-in text rather than showing a half-rendered message
+	}
+
+	var sb strings.Builder
+	if err := m.builtin[id].Execute(&sb, data); err != nil {
+		return fmt.Sprintf("Sorry, something went wrong. Error code: %s", data.ErrorCode)
+	}
+	return sb.String()
+}
+
+func (m *messageCatalog) localeCandidates(locale string) []string {
+	var candidates []string
+	for _, l := range []string{normalizeLocale(locale), m.defaultLocale} {
+		if l == "" {
+			continue
+		}
+		candidates = append(candidates, l)
+		if language, _, found := strings.Cut(l, "-"); found {
+			candidates = append(candidates, language)
+		}
+	}
+	return candidates
+}
+
+// normalizeLocale lowercases a locale and uses "-" as separator, so "ja_JP" matches "ja-JP"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// recipientFor describes the user a reply is for. The user's locale is only looked up when
+// the catalog has localized templates, to avoid an extra Slack API call per user otherwise.
+func (c *Client) recipientFor(userID string) recipient {
+	r := recipient{userID: userID, admin: c.adminUsers[userID]}
+	if !c.messages.hasLocales() || userID == "" {
+		return r
+	}
+	if frontend, ok := c.userFrontend.(localeFrontend); ok {
+		locale, err := frontend.GetUserLocale(userID)
+		if err != nil {
+			c.logger.WarnKV("Failed to get user locale", "user", userID, "error", err)
+		}
+		r.locale = locale
+	}
+	return r
+}
+
+// message renders a user-facing message for the recipient
+func (c *Client) message(r recipient, id string, data messageData) string {
+	return c.messages.render(id, r.locale, data)
+}
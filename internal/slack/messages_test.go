@@ -0,0 +1,99 @@
+package slackbot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	customErrors "github.com/tuannvm/slack-mcp-client/internal/common/errors"
+	"github.com/tuannvm/slack-mcp-client/internal/config"
+)
+
+func TestMessageCatalog_LocaleFallback(t *testing.T) {
+	catalog, err := newMessageCatalog(config.MessagesConfig{
+		DefaultLocale: "en-US",
+		Locales: map[string]map[string]string{
+			"ja":    {msgEmptyResponse: "（空の応答）"},
+			"fr_FR": {msgEmptyResponse: "(réponse vide)"},
+			"en":    {msgEmptyResponse: "(nothing to say)"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newMessageCatalog() error = %v", err)
+	}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"ja-JP", "（空の応答）"},
+		{"fr-FR", "(réponse vide)"},
+		{"de-DE", "(nothing to say)"}, // Default locale's language
+		{"", "(nothing to say)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := catalog.render(msgEmptyResponse, tt.locale, messageData{}); got != tt.want {
+				t.Errorf("render(%q) = %q, want %q", tt.locale, got, tt.want)
+			}
+		})
+	}
+
+	// Messages without an override use the built-in text
+	if got := catalog.render(msgTranscriptEmpty, "ja-JP", messageData{}); got != "There is no conversation history stored for this thread yet." {
+		t.Errorf("built-in fallback = %q", got)
+	}
+}
+
+func TestMessageCatalog_HidesRawErrorsFromNonAdmins(t *testing.T) {
+	catalog, err := newMessageCatalog(config.MessagesConfig{DefaultLocale: "en-US"})
+	if err != nil {
+		t.Fatalf("newMessageCatalog() error = %v", err)
+	}
+	llmErr := customErrors.WrapSlackError(fmt.Errorf("dial tcp 10.0.0.5:443: connection refused"), "llm_request_failed", "LLM request failed")
+
+	user := recipient{userID: "U1"}
+	data := user.errorData(llmErr, "4bf92f3577b34da6a3ce929d0e0e4736")
+	data.Provider = "openai"
+	got := catalog.render(msgLLMError, "", data)
+	if strings.Contains(got, "10.0.0.5") {
+		t.Errorf("non-admin message leaks the raw error: %q", got)
+	}
+	for _, want := range []string{"'openai'", "`llm_request_failed`", "`4bf92f3577b34da6a3ce929d0e0e4736`"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("non-admin message %q missing %q", got, want)
+		}
+	}
+
+	admin := recipient{userID: "U2", admin: true}
+	data = admin.errorData(llmErr, "")
+	data.Provider = "openai"
+	got = catalog.render(msgLLMError, "", data)
+	if !strings.Contains(got, "connection refused") || strings.Contains(got, "trace ID") {
+		t.Errorf("admin message = %q, want raw error details and no trace ID", got)
+	}
+
+	if data := user.errorData(fmt.Errorf("plain error"), ""); data.ErrorCode != "internal_error" {
+		t.Errorf("ErrorCode = %q, want internal_error for errors without a code", data.ErrorCode)
+	}
+}
+
+func TestMessageCatalog_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		locales map[string]map[string]string
+		want    string
+	}{
+		{"unknown message", map[string]map[string]string{"en": {"greeting": "hi"}}, "unknown message ID"},
+		{"syntax error", map[string]map[string]string{"en": {msgLLMError: "{{.Provider"}}, "invalid template"},
+		{"unknown field", map[string]map[string]string{"en": {msgLLMError: "{{.Model}}"}}, "invalid template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessages(config.MessagesConfig{Locales: tt.locales})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateMessages() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package slackbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePermalinks(t *testing.T) {
+	text := "what does this mean? <https://acme.slack.com/archives/C0123ABC/p1700000000000100> and " +
+		"<https://acme.enterprise.slack.com/archives/G0456DEF/p1700000050000200?thread_ts=1699999999.000300&cid=G0456DEF|this reply>, " +
+		"again https://acme.slack.com/archives/C0123ABC/p1700000000000100 and https://example.com/archives/C1/p1700000000000100"
+
+	got := parsePermalinks(text, 5)
+	want := []permalink{
+		{url: "https://acme.slack.com/archives/C0123ABC/p1700000000000100", channelID: "C0123ABC", ts: "1700000000.000100", threadTS: "1700000000.000100"},
+		{url: "https://acme.enterprise.slack.com/archives/G0456DEF/p1700000050000200?thread_ts=1699999999.000300&cid=G0456DEF", channelID: "G0456DEF", ts: "1700000050.000200", threadTS: "1699999999.000300"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePermalinks() = %+v, want %+v", got, want)
+	}
+	if got := parsePermalinks(text, 1); len(got) != 1 {
+		t.Errorf("parsePermalinks(max 1) returned %d links", len(got))
+	}
+}
+
+func TestFormatSlackTimestamp(t *testing.T) {
+	if got := formatSlackTimestamp("1700000000.000100"); got != "2023-11-14 22:13 UTC" {
+		t.Errorf("formatSlackTimestamp() = %q", got)
+	}
+	if got := formatSlackTimestamp("not-a-ts"); got != "not-a-ts" {
+		t.Errorf("formatSlackTimestamp() = %q, want the input unchanged", got)
+	}
+}
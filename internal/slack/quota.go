@@ -0,0 +1,119 @@
+package slackbot
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tuannvm/slack-mcp-client/internal/llm"
+	"github.com/tuannvm/slack-mcp-client/internal/quota"
+)
+
+// quotaResetFormat is how quota reset times are shown to users
+const quotaResetFormat = "2006-01-02 15:04 UTC"
+
+// quotaTargetPattern matches the user or channel mention of "quota override", e.g. "<@U123>" or "<#C123|general>"
+var quotaTargetPattern = regexp.MustCompile(`^<([@#])([A-Z0-9]+)(?:\|[^>]*)?>$`)
+
+// quotaCommand is a parsed "quota" command
+type quotaCommand struct {
+	override bool   // "quota override": lift a quota instead of showing usage
+	scope    string // quota.ScopeUser or quota.ScopeChannel, for overrides
+	id       string
+}
+
+// parseQuotaCommand recognizes "quota", which shows the user's token usage, and
+// "quota override <@user>|<#channel>", which lifts a quota until the end of the month.
+// Any other text, including longer sentences starting with the same word, is a regular prompt.
+func parseQuotaCommand(text string) (quotaCommand, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "quota" {
+		return quotaCommand{}, false
+	}
+	if len(fields) == 1 {
+		return quotaCommand{}, true
+	}
+	if len(fields) != 3 || strings.ToLower(fields[1]) != "override" {
+		return quotaCommand{}, false
+	}
+	match := quotaTargetPattern.FindStringSubmatch(fields[2])
+	if match == nil {
+		return quotaCommand{}, false
+	}
+	scope := quota.ScopeUser
+	if match[1] == "#" {
+		scope = quota.ScopeChannel
+	}
+	return quotaCommand{override: true, scope: scope, id: match[2]}, true
+}
+
+// handleQuotaCommand replies to a quota command
+func (c *Client) handleQuotaCommand(channelID, threadTS string, cmd quotaCommand, reader recipient) {
+	if c.quotas == nil {
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgQuotaDisabled, messageData{}))
+		return
+	}
+	if !cmd.override {
+		daily, monthly := c.quotas.Usage(quota.ScopeUser, reader.userID)
+		limits := c.quotas.Limits(quota.ScopeUser, reader.userID)
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgQuotaStatus, messageData{
+			DailyTokens:   daily.Total(),
+			DailyLimit:    limits.DailyTokens,
+			MonthlyTokens: monthly.Total(),
+			MonthlyLimit:  limits.MonthlyTokens,
+		}))
+		return
+	}
+
+	if !reader.admin {
+		c.logger.WarnKV("Non-admin tried to lift a quota", "user", reader.userID, "scope", cmd.scope, "id", cmd.id)
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgQuotaForbidden, messageData{}))
+		return
+	}
+	until, err := c.quotas.Override(cmd.scope, cmd.id)
+	if err != nil {
+		// The override applies in memory even if it could not be persisted
+		c.logger.ErrorKV("Failed to persist quota override", "scope", cmd.scope, "id", cmd.id, "error", err)
+	}
+	target := "<@" + cmd.id + ">"
+	if cmd.scope == quota.ScopeChannel {
+		target = "<#" + cmd.id + ">"
+	}
+	c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgQuotaOverride, messageData{
+		QuotaTarget: target,
+		ResetsAt:    until.Format(quotaResetFormat),
+	}))
+}
+
+// quotaExhausted tells the user when they or the channel used up a quota, and reports whether they did.
+// Admins are never limited.
+func (c *Client) quotaExhausted(channelID, threadTS string, reader recipient) bool {
+	if reader.admin {
+		return false
+	}
+	exceeded := c.quotas.Check(reader.userID, channelID)
+	if exceeded == nil {
+		return false
+	}
+	c.logger.InfoKV("LLM quota exhausted", "user", reader.userID, "channel", channelID,
+		"scope", exceeded.Scope, "period", exceeded.Period, "used", exceeded.Used, "limit", exceeded.Limit)
+	c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgQuotaExceeded, messageData{
+		QuotaScope:  exceeded.Scope,
+		QuotaPeriod: exceeded.Period,
+		TokensUsed:  exceeded.Used,
+		TokenLimit:  exceeded.Limit,
+		ResetsAt:    exceeded.ResetsAt.Format(quotaResetFormat),
+	}))
+	return true
+}
+
+// recordUsage accounts the tokens of an LLM call to the user, the channel and the provider that answered
+func (c *Client) recordUsage(userID, channelID string, report *llm.ContextReport) {
+	if report == nil {
+		return
+	}
+	provider := llm.AnsweredBy(report.Attempts)
+	if provider == "" {
+		provider = c.cfg.LLM.Provider
+	}
+	c.quotas.Record(userID, channelID, provider, report.Usage)
+}
@@ -0,0 +1,33 @@
+package slackbot
+
+import (
+	"testing"
+
+	"github.com/tuannvm/slack-mcp-client/internal/quota"
+)
+
+func TestParseQuotaCommand(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   quotaCommand
+		wantOK bool
+	}{
+		{"quota", quotaCommand{}, true},
+		{"  Quota ", quotaCommand{}, true},
+		{"quota override <@U123ABC>", quotaCommand{override: true, scope: quota.ScopeUser, id: "U123ABC"}, true},
+		{"QUOTA Override <@U123ABC|alice>", quotaCommand{override: true, scope: quota.ScopeUser, id: "U123ABC"}, true},
+		{"quota override <#C42|general>", quotaCommand{override: true, scope: quota.ScopeChannel, id: "C42"}, true},
+		{"quota override alice", quotaCommand{}, false},
+		{"quota override", quotaCommand{}, false},
+		{"quota for the sales team", quotaCommand{}, false},
+		{"what is my quota", quotaCommand{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			cmd, ok := parseQuotaCommand(tt.text)
+			if cmd != tt.want || ok != tt.wantOK {
+				t.Errorf("parseQuotaCommand(%q) = %+v, %v, want %+v, %v", tt.text, cmd, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package slackbot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/tuannvm/slack-mcp-client/internal/llm"
+	"github.com/tuannvm/slack-mcp-client/internal/redact"
+)
+
+// routeRequest picks the provider and model tier for a prompt and traces the decision.
+// The prompt is redacted first, since the router may send it to a classifier model.
+func (c *Client) routeRequest(ctx context.Context, prompt, forcedTier string, hasAttachments bool, redaction *redact.Session) llm.RouteDecision {
+	if c.router == nil {
+		return llm.RouteDecision{}
+	}
+
+	toolNames := make([]string, 0, len(c.discoveredTools))
+	for name := range c.discoveredTools {
+		toolNames = append(toolNames, name)
+	}
+	sort.Strings(toolNames)
+
+	routeCtx, span := c.tracingHandler.StartSpan(ctx, "llm-routing", "span", prompt, map[string]string{
+		"forced_tier":     forcedTier,
+		"has_attachments": fmt.Sprintf("%t", hasAttachments),
+	})
+	defer span.End()
+
+	decision := c.router.Route(routeCtx, llm.RouteRequest{
+		Prompt:         redaction.Redact(prompt),
+		HasAttachments: hasAttachments,
+		ToolNames:      toolNames,
+		ForcedTier:     forcedTier,
+	})
+	span.SetAttributes(
+		attribute.String("routing.tier", decision.Tier),
+		attribute.String("routing.provider", decision.Provider),
+		attribute.String("routing.model", decision.Model),
+		attribute.String("routing.reason", decision.Reason),
+	)
+	c.tracingHandler.SetOutput(span, decision.Tier)
+	c.tracingHandler.RecordSuccess(span, "Request routed: "+decision.Reason)
+	c.logger.InfoKV("Routed request", "tier", decision.Tier, "provider", decision.Provider, "model", decision.Model, "reason", decision.Reason)
+	return decision
+}
+
+// routeModel returns the model a routed request is sent to
+func (c *Client) routeModel(route llm.RouteDecision) string {
+	if route.Model != "" {
+		return route.Model
+	}
+	provider := route.Provider
+	if provider == "" {
+		provider = c.cfg.LLM.Provider
+	}
+	return c.cfg.LLM.Providers[provider].Model
+}
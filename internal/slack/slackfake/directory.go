@@ -0,0 +1,64 @@
+package slackfake
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/slack-go/slack"
+)
+
+// AddChannel adds channel metadata returned by conversations.info; channel.Members is
+// returned by conversations.members. Messages can be posted to channels that were never added.
+func (s *Server) AddChannel(channel slack.Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[channel.ID] = channel
+}
+
+// AddUserGroup adds a user group returned by usergroups.list
+func (s *Server) AddUserGroup(group slack.UserGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[group.ID] = group
+}
+
+func handleConversationInfo(s *Server, call Call) map[string]interface{} {
+	channel, ok := s.channels[call.Param("channel")]
+	if !ok {
+		return errorResponse("channel_not_found")
+	}
+	return map[string]interface{}{"channel": channel}
+}
+
+func handleUserGroupsList(s *Server, call Call) map[string]interface{} {
+	groups := make([]slack.UserGroup, 0, len(s.groups))
+	for _, group := range s.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return map[string]interface{}{"usergroups": groups}
+}
+
+func handleConversationMembers(s *Server, call Call) map[string]interface{} {
+	channel, ok := s.channels[call.Param("channel")]
+	if !ok {
+		return errorResponse("channel_not_found")
+	}
+	members := channel.Members
+	offset, _ := strconv.Atoi(call.Param("cursor"))
+	if offset > len(members) {
+		offset = len(members)
+	}
+	end := len(members)
+	if limit, _ := strconv.Atoi(call.Param("limit")); limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	nextCursor := ""
+	if end < len(members) {
+		nextCursor = strconv.Itoa(end)
+	}
+	return map[string]interface{}{
+		"members":           members[offset:end],
+		"response_metadata": map[string]string{"next_cursor": nextCursor},
+	}
+}
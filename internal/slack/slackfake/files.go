@@ -0,0 +1,111 @@
+package slackfake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Upload is a file uploaded through the files.getUploadURLExternal flow
+type Upload struct {
+	ID       string
+	Filename string
+	Title    string
+	Content  []byte
+	Channel  string // Set once the upload is completed and shared
+	ThreadTS string
+	Comment  string // Initial comment posted with the file
+	Complete bool
+}
+
+// Uploads returns a copy of the completed uploads, in completion order
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var uploads []Upload
+	for _, call := range s.callsLocked("files.completeUploadExternal") {
+		var files []slack.FileSummary
+		_ = json.Unmarshal([]byte(call.Param("files")), &files)
+		for _, file := range files {
+			if upload, ok := s.uploads[file.ID]; ok && upload.Complete {
+				copied := *upload
+				copied.Content = append([]byte(nil), upload.Content...)
+				uploads = append(uploads, copied)
+			}
+		}
+	}
+	return uploads
+}
+
+// handleUpload stores the multipart file posted to an upload URL
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, uploadPath)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = file.Close() }()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload, ok := s.uploads[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	upload.Content = content
+	s.notifyLocked()
+	_, _ = w.Write([]byte("OK - " + strconv.Itoa(len(content))))
+}
+
+func handleGetUploadURL(s *Server, call Call) map[string]interface{} {
+	if call.Param("filename") == "" || call.Param("length") == "" {
+		return errorResponse("invalid_arguments")
+	}
+	id := s.nextIDLocked("F")
+	s.uploads[id] = &Upload{ID: id, Filename: call.Param("filename")}
+	return map[string]interface{}{"upload_url": s.URL + uploadPath + id, "file_id": id}
+}
+
+// handleCompleteUpload shares uploaded files, posting a message with the files to the channel or thread
+func handleCompleteUpload(s *Server, call Call) map[string]interface{} {
+	var files []slack.FileSummary
+	if err := json.Unmarshal([]byte(call.Param("files")), &files); err != nil || len(files) == 0 {
+		return errorResponse("invalid_arguments")
+	}
+
+	var shared []slack.File
+	for _, summary := range files {
+		upload, ok := s.uploads[summary.ID]
+		if !ok || upload.Content == nil {
+			return errorResponse("file_not_found")
+		}
+		upload.Title = summary.Title
+		upload.Channel = call.Param("channel_id")
+		upload.ThreadTS = call.Param("thread_ts")
+		upload.Comment = call.Param("initial_comment")
+		upload.Complete = true
+		shared = append(shared, slack.File{ID: upload.ID, Name: upload.Filename, Title: upload.Title, Size: len(upload.Content)})
+	}
+
+	if channel := call.Param("channel_id"); channel != "" {
+		s.addMessageLocked(channel, slack.Message{Msg: slack.Msg{
+			User:            BotUserID,
+			BotID:           BotID,
+			Text:            call.Param("initial_comment"),
+			ThreadTimestamp: call.Param("thread_ts"),
+			Files:           shared,
+		}})
+	}
+	return map[string]interface{}{"files": files}
+}
@@ -0,0 +1,509 @@
+// Package slackfake provides an in-process fake of the Slack Web API and Socket Mode
+// endpoints used by slack-go, for end-to-end tests without a real workspace.
+package slackfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+)
+
+// Identifiers of the fake workspace and its bot user
+const (
+	TeamID    = "T0FAKE"
+	BotUserID = "U0BOT"
+	BotID     = "B0BOT"
+	AppID     = "A0FAKE"
+)
+
+// socketModePath is where the Socket Mode websocket is served
+const socketModePath = "/socket-mode"
+
+// uploadPath is where file contents are posted after files.getUploadURLExternal
+const uploadPath = "/upload/"
+
+// Call is a recorded Web API request
+type Call struct {
+	Method string     // API method, e.g. "chat.postMessage"
+	Token  string     // Token sent in the Authorization header or the token form field
+	Params url.Values // Query and form parameters
+	Body   []byte     // Raw request body (useful for JSON requests)
+	Time   time.Time
+}
+
+// Param returns the first value of a request parameter
+func (c Call) Param(key string) string {
+	return c.Params.Get(key)
+}
+
+// scriptedResponse is a response queued for the next call to a method
+type scriptedResponse struct {
+	errorCode  string // Slack error code returned with "ok": false
+	retryAfter int    // When > 0, respond with HTTP 429 and this Retry-After in seconds
+}
+
+// Handler serves a Web API method. It is called with the server lock held and returns
+// the JSON response body; "ok": true is added when the response has no "ok" field.
+type Handler func(s *Server, call Call) map[string]interface{}
+
+// Server is a fake Slack Web API and Socket Mode server
+type Server struct {
+	URL string // Base URL of the server; the Web API lives under URL + "/api/"
+
+	httpServer   *httptest.Server
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+
+	mu       sync.Mutex
+	changed  chan struct{} // Closed and replaced whenever recorded state changes
+	calls    []Call
+	scripted map[string][]scriptedResponse
+	handlers map[string]Handler
+	users    map[string]slack.User
+	channels map[string]slack.Channel   // Channel metadata for conversations.info
+	groups   map[string]slack.UserGroup // User groups for usergroups.list
+	messages map[string][]slack.Message // By channel, in posting order
+	conns    []*socketConn
+	acks     map[string]json.RawMessage // Ack payloads by envelope ID
+	uploads  map[string]*Upload         // Files by ID, in any upload stage
+	lastTS   time.Time
+	nextID   int
+}
+
+// NewServer starts a fake Slack server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		pingInterval: 500 * time.Millisecond,
+		changed:      make(chan struct{}),
+		scripted:     make(map[string][]scriptedResponse),
+		users:        make(map[string]slack.User),
+		channels:     make(map[string]slack.Channel),
+		groups:       make(map[string]slack.UserGroup),
+		messages:     make(map[string][]slack.Message),
+		acks:         make(map[string]json.RawMessage),
+		uploads:      make(map[string]*Upload),
+	}
+	// slack-go sends Origin: https://api.slack.com, which would fail the default same-origin check
+	s.upgrader.CheckOrigin = func(*http.Request) bool { return true }
+	s.handlers = defaultHandlers()
+	s.users[BotUserID] = slack.User{ID: BotUserID, Name: "bot", RealName: "Bot", IsBot: true, Profile: slack.UserProfile{RealName: "Bot", BotID: BotID}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", s.handleAPI)
+	mux.HandleFunc(socketModePath, s.handleSocketMode)
+	mux.HandleFunc(uploadPath, s.handleUpload)
+	s.httpServer = httptest.NewServer(mux)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// APIURL returns the Web API base URL to pass to slack.OptionAPIURL
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+// Close closes all Socket Mode connections and shuts the server down
+func (s *Server) Close() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.close()
+	}
+	s.httpServer.Close()
+}
+
+// Handle registers or replaces the handler for a Web API method
+func (s *Server) Handle(method string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// FailNext makes the next call to method return "ok": false with the given Slack error code
+func (s *Server) FailNext(method, errorCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted[method] = append(s.scripted[method], scriptedResponse{errorCode: errorCode})
+}
+
+// RateLimitNext makes the next call to method fail with HTTP 429 and the given Retry-After
+func (s *Server) RateLimitNext(method string, retryAfter time.Duration) {
+	seconds := int(retryAfter / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted[method] = append(s.scripted[method], scriptedResponse{retryAfter: seconds})
+}
+
+// AddUser adds a user to the fake workspace
+func (s *Server) AddUser(user slack.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.Profile.RealName == "" {
+		user.Profile.RealName = user.RealName
+	}
+	s.users[user.ID] = user
+}
+
+// AddMessage stores a message in a channel as if it had been posted earlier.
+// A timestamp is assigned when msg.Timestamp is empty. Returns the message timestamp.
+func (s *Server) AddMessage(channel string, msg slack.Message) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addMessageLocked(channel, msg)
+}
+
+func (s *Server) addMessageLocked(channel string, msg slack.Message) string {
+	if msg.Timestamp == "" {
+		msg.Timestamp = s.nextTimestampLocked()
+	}
+	if msg.Type == "" {
+		msg.Type = "message"
+	}
+	msg.Channel = channel
+	s.messages[channel] = append(s.messages[channel], msg)
+	s.notifyLocked()
+	return msg.Timestamp
+}
+
+// Messages returns a copy of all messages in a channel, including thread replies
+func (s *Server) Messages(channel string) []slack.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]slack.Message, len(s.messages[channel]))
+	copy(messages, s.messages[channel])
+	return messages
+}
+
+// Calls returns the recorded calls, optionally filtered to a single method
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.callsLocked(method)
+}
+
+func (s *Server) callsLocked(method string) []Call {
+	var calls []Call
+	for _, call := range s.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// WaitForCall waits until a call to method matching the predicate (nil matches any) has been recorded
+func (s *Server) WaitForCall(method string, timeout time.Duration, match func(Call) bool) (Call, error) {
+	var found Call
+	ok := s.waitFor(timeout, func() bool {
+		for _, call := range s.callsLocked(method) {
+			if match == nil || match(call) {
+				found = call
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		return Call{}, fmt.Errorf("timed out after %s waiting for %s", timeout, method)
+	}
+	return found, nil
+}
+
+// waitFor blocks until cond (evaluated with the lock held) is true or the timeout expires
+func (s *Server) waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		if cond() {
+			s.mu.Unlock()
+			return true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// nextTimestampLocked returns a unique, increasing Slack-style timestamp
+func (s *Server) nextTimestampLocked() string {
+	now := time.Now().Truncate(time.Microsecond)
+	if !now.After(s.lastTS) {
+		now = s.lastTS.Add(time.Microsecond)
+	}
+	s.lastTS = now
+	return fmt.Sprintf("%d.%06d", now.Unix(), now.Nanosecond()/int(time.Microsecond))
+}
+
+// nextIDLocked returns a unique identifier with the given prefix
+func (s *Server) nextIDLocked(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%06d", prefix, s.nextID)
+}
+
+// handleAPI records a Web API call and dispatches it to the method handler
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	body, _ := io.ReadAll(r.Body)
+	call := Call{
+		Method: method,
+		Token:  strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		Params: url.Values{},
+		Body:   body,
+		Time:   time.Now(),
+	}
+	for key, values := range r.URL.Query() {
+		call.Params[key] = values
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for key, values := range form {
+				call.Params[key] = values
+			}
+		}
+	}
+	if call.Token == "" {
+		call.Token = call.Params.Get("token")
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.notifyLocked()
+
+	var response map[string]interface{}
+	if queue := s.scripted[method]; len(queue) > 0 {
+		scripted := queue[0]
+		s.scripted[method] = queue[1:]
+		s.mu.Unlock()
+
+		if scripted.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(scripted.retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"ok": false, "error": scripted.errorCode})
+		return
+	}
+
+	handler, ok := s.handlers[method]
+	if !ok {
+		response = map[string]interface{}{"ok": false, "error": "unknown_method"}
+	} else {
+		response = handler(s, call)
+		if _, hasOK := response["ok"]; !hasOK {
+			response["ok"] = true
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, response)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// errorResponse builds a Slack error response
+func errorResponse(code string) map[string]interface{} {
+	return map[string]interface{}{"ok": false, "error": code}
+}
+
+// defaultHandlers implements the Web API methods used by the bot
+func defaultHandlers() map[string]Handler {
+	return map[string]Handler{
+		"auth.test": func(s *Server, call Call) map[string]interface{} {
+			return map[string]interface{}{
+				"url":     s.URL + "/",
+				"team":    "Fake Workspace",
+				"user":    "bot",
+				"team_id": TeamID,
+				"user_id": BotUserID,
+				"bot_id":  BotID,
+			}
+		},
+		"apps.connections.open": func(s *Server, call Call) map[string]interface{} {
+			return map[string]interface{}{
+				"url": "ws" + strings.TrimPrefix(s.URL, "http") + socketModePath,
+			}
+		},
+		"chat.postMessage": func(s *Server, call Call) map[string]interface{} {
+			channel := call.Param("channel")
+			if channel == "" {
+				return errorResponse("channel_not_found")
+			}
+			msg := slack.Message{Msg: slack.Msg{
+				User:            BotUserID,
+				BotID:           BotID,
+				Text:            call.Param("text"),
+				ThreadTimestamp: call.Param("thread_ts"),
+			}}
+			if blocks := call.Param("blocks"); blocks != "" {
+				_ = json.Unmarshal([]byte(blocks), &msg.Blocks)
+			}
+			msg.Timestamp = s.addMessageLocked(channel, msg)
+			msg.Channel = channel
+			return map[string]interface{}{"channel": channel, "ts": msg.Timestamp, "message": msg}
+		},
+		"chat.update": func(s *Server, call Call) map[string]interface{} {
+			channel, ts := call.Param("channel"), call.Param("ts")
+			for i, msg := range s.messages[channel] {
+				if msg.Timestamp == ts {
+					s.messages[channel][i].Text = call.Param("text")
+					if blocks := call.Param("blocks"); blocks != "" {
+						_ = json.Unmarshal([]byte(blocks), &s.messages[channel][i].Blocks)
+					}
+					s.notifyLocked()
+					return map[string]interface{}{"channel": channel, "ts": ts, "text": call.Param("text")}
+				}
+			}
+			return errorResponse("message_not_found")
+		},
+		"chat.delete": func(s *Server, call Call) map[string]interface{} {
+			channel, ts := call.Param("channel"), call.Param("ts")
+			for i, msg := range s.messages[channel] {
+				if msg.Timestamp == ts {
+					s.messages[channel] = append(s.messages[channel][:i], s.messages[channel][i+1:]...)
+					s.notifyLocked()
+					return map[string]interface{}{"channel": channel, "ts": ts}
+				}
+			}
+			return errorResponse("message_not_found")
+		},
+		"conversations.replies": func(s *Server, call Call) map[string]interface{} {
+			channel, ts := call.Param("channel"), call.Param("ts")
+			var thread []slack.Message
+			for _, msg := range s.messages[channel] {
+				if msg.Timestamp == ts || msg.ThreadTimestamp == ts {
+					thread = append(thread, msg)
+				}
+			}
+			if len(thread) == 0 {
+				return errorResponse("thread_not_found")
+			}
+			return paginate(thread, call)
+		},
+		"conversations.history": func(s *Server, call Call) map[string]interface{} {
+			channel, oldest := call.Param("channel"), call.Param("oldest")
+			var history []slack.Message
+			for _, msg := range s.messages[channel] {
+				if oldest != "" && compareTimestamps(msg.Timestamp, oldest) <= 0 {
+					continue
+				}
+				if msg.ThreadTimestamp == "" || msg.ThreadTimestamp == msg.Timestamp {
+					history = append(history, msg)
+				}
+			}
+			// Slack returns channel history newest first
+			sort.SliceStable(history, func(i, j int) bool { return history[i].Timestamp > history[j].Timestamp })
+			return paginate(history, call)
+		},
+		"chat.getPermalink": func(s *Server, call Call) map[string]interface{} {
+			channel, ts := call.Param("channel"), call.Param("message_ts")
+			return map[string]interface{}{
+				"channel":   channel,
+				"permalink": fmt.Sprintf("%s/archives/%s/p%s", s.URL, channel, strings.ReplaceAll(ts, ".", "")),
+			}
+		},
+		"views.open": func(s *Server, call Call) map[string]interface{} {
+			var req struct {
+				TriggerID string          `json:"trigger_id"`
+				View      json.RawMessage `json:"view"`
+			}
+			if err := json.Unmarshal(call.Body, &req); err != nil || req.TriggerID == "" {
+				return errorResponse("invalid_arguments")
+			}
+			var view map[string]interface{}
+			_ = json.Unmarshal(req.View, &view)
+			view["id"] = s.nextIDLocked("V")
+			return map[string]interface{}{"view": view}
+		},
+		"files.getUploadURLExternal":   handleGetUploadURL,
+		"files.completeUploadExternal": handleCompleteUpload,
+		"conversations.info":           handleConversationInfo,
+		"conversations.members":        handleConversationMembers,
+		"usergroups.list":              handleUserGroupsList,
+		"users.info": func(s *Server, call Call) map[string]interface{} {
+			user, ok := s.users[call.Param("user")]
+			if !ok {
+				return errorResponse("user_not_found")
+			}
+			return map[string]interface{}{"user": user}
+		},
+		"users.profile.get": func(s *Server, call Call) map[string]interface{} {
+			user, ok := s.users[call.Param("user")]
+			if !ok {
+				return errorResponse("user_not_found")
+			}
+			return map[string]interface{}{"profile": user.Profile}
+		},
+	}
+}
+
+// compareTimestamps compares two message timestamps numerically, returning -1, 0 or 1
+func compareTimestamps(a, b string) int {
+	x, _ := strconv.ParseFloat(a, 64)
+	y, _ := strconv.ParseFloat(b, 64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// paginate applies the limit and cursor parameters to a message list.
+// Cursors are opaque to clients; here they are the offset of the next page.
+func paginate(messages []slack.Message, call Call) map[string]interface{} {
+	offset, _ := strconv.Atoi(call.Param("cursor"))
+	if offset > len(messages) {
+		offset = len(messages)
+	}
+	limit, _ := strconv.Atoi(call.Param("limit"))
+	end := len(messages)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	nextCursor := ""
+	if end < len(messages) {
+		nextCursor = strconv.Itoa(end)
+	}
+	page := messages[offset:end]
+	if page == nil {
+		page = []slack.Message{}
+	}
+	return map[string]interface{}{
+		"messages":          page,
+		"has_more":          nextCursor != "",
+		"response_metadata": map[string]string{"next_cursor": nextCursor},
+	}
+}
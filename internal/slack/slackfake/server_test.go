@@ -0,0 +1,111 @@
+package slackfake
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestServer_ConversationsHistoryPagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	for _, text := range []string{"one", "two", "three"} {
+		s.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: text}})
+	}
+	// Thread replies are not part of the channel history
+	root := s.Messages("C1")[0].Timestamp
+	s.AddMessage("C1", slack.Message{Msg: slack.Msg{User: "U1", Text: "reply", ThreadTimestamp: root}})
+
+	api := slack.New("xoxb-test", slack.OptionAPIURL(s.APIURL()))
+	var texts []string
+	cursor := ""
+	for {
+		resp, err := api.GetConversationHistory(&slack.GetConversationHistoryParameters{ChannelID: "C1", Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("GetConversationHistory() error = %v", err)
+		}
+		for _, msg := range resp.Messages {
+			texts = append(texts, msg.Text)
+		}
+		cursor = resp.ResponseMetaData.NextCursor
+		if !resp.HasMore {
+			break
+		}
+	}
+
+	want := []string{"three", "two", "one"}
+	if len(texts) != len(want) {
+		t.Fatalf("history = %v, want %v", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("history[%d] = %q, want %q", i, texts[i], want[i])
+		}
+	}
+	if calls := s.Calls("conversations.history"); len(calls) != 2 {
+		t.Errorf("recorded %d history calls, want 2", len(calls))
+	}
+}
+
+func TestServer_ScriptedFailures(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := slack.New("xoxb-test", slack.OptionAPIURL(s.APIURL()))
+
+	s.FailNext("chat.postMessage", "channel_not_found")
+	s.RateLimitNext("chat.postMessage", 2*time.Second)
+
+	if _, _, err := api.PostMessage("C1", slack.MsgOptionText("hi", false)); err == nil || err.Error() != "channel_not_found" {
+		t.Errorf("first PostMessage() error = %v, want channel_not_found", err)
+	}
+
+	_, _, err := api.PostMessage("C1", slack.MsgOptionText("hi", false))
+	var rateLimited *slack.RateLimitedError
+	if !errors.As(err, &rateLimited) || rateLimited.RetryAfter != 2*time.Second {
+		t.Errorf("second PostMessage() error = %v, want rate limit with 2s retry", err)
+	}
+
+	if _, _, err := api.PostMessage("C1", slack.MsgOptionText("hi", false)); err != nil {
+		t.Errorf("third PostMessage() error = %v, want success", err)
+	}
+	if got := len(s.Messages("C1")); got != 1 {
+		t.Errorf("channel has %d messages, want 1", got)
+	}
+}
+
+func TestServer_FileUpload(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := slack.New("xoxb-test", slack.OptionAPIURL(s.APIURL()))
+
+	content := "# transcript\n"
+	file, err := api.UploadFileV2(slack.UploadFileV2Parameters{
+		Channel:         "C1",
+		ThreadTimestamp: "1.000001",
+		Filename:        "transcript.md",
+		Title:           "Transcript",
+		Content:         content,
+		FileSize:        len(content),
+	})
+	if err != nil {
+		t.Fatalf("UploadFileV2() error = %v", err)
+	}
+
+	uploads := s.Uploads()
+	if len(uploads) != 1 {
+		t.Fatalf("got %d uploads, want 1", len(uploads))
+	}
+	got := uploads[0]
+	if got.ID != file.ID || got.Filename != "transcript.md" || got.Title != "Transcript" || string(got.Content) != content {
+		t.Errorf("upload = %+v, want the uploaded file", got)
+	}
+	if got.Channel != "C1" || got.ThreadTS != "1.000001" {
+		t.Errorf("upload shared to %s/%s, want C1/1.000001", got.Channel, got.ThreadTS)
+	}
+	if messages := s.Messages("C1"); len(messages) != 1 || len(messages[0].Files) != 1 {
+		t.Errorf("expected one message with the shared file, got %+v", messages)
+	}
+}
@@ -0,0 +1,217 @@
+package slackfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// socketConn is a Socket Mode websocket connection from the bot
+type socketConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex // gorilla/websocket allows only one concurrent writer
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (c *socketConn) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteJSON(v)
+}
+
+func (c *socketConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.ws.Close()
+	})
+}
+
+// handleSocketMode upgrades the connection, greets the client and records acks until it disconnects
+func (s *Server) handleSocketMode(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &socketConn{ws: ws, done: make(chan struct{})}
+	defer conn.close()
+
+	if err := conn.writeJSON(map[string]interface{}{
+		"type":            "hello",
+		"num_connections": 1,
+		"connection_info": map[string]string{"app_id": AppID},
+	}); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.notifyLocked()
+	s.mu.Unlock()
+	defer s.removeConn(conn)
+
+	// slack-go treats a connection without pings as dead
+	go func() {
+		ticker := time.NewTicker(s.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-conn.done:
+				return
+			case <-ticker.C:
+				if err := ws.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(time.Second)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var ack struct {
+			EnvelopeID string          `json:"envelope_id"`
+			Payload    json.RawMessage `json:"payload"`
+		}
+		if err := ws.ReadJSON(&ack); err != nil {
+			return
+		}
+		if ack.EnvelopeID == "" {
+			continue
+		}
+		s.mu.Lock()
+		s.acks[ack.EnvelopeID] = ack.Payload
+		s.notifyLocked()
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) removeConn(conn *socketConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.conns {
+		if c == conn {
+			s.conns = append(s.conns[:i], s.conns[i+1:]...)
+			break
+		}
+	}
+	s.notifyLocked()
+}
+
+// WaitForConnection waits until the bot has opened a Socket Mode connection
+func (s *Server) WaitForConnection(timeout time.Duration) error {
+	if !s.waitFor(timeout, func() bool { return len(s.conns) > 0 }) {
+		return fmt.Errorf("timed out after %s waiting for a Socket Mode connection", timeout)
+	}
+	return nil
+}
+
+// Send delivers a Socket Mode request of the given type (e.g. socketmode.RequestTypeInteractive)
+// to the connected bot and returns its envelope ID
+func (s *Server) Send(requestType string, payload interface{}) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	s.mu.Lock()
+	if len(s.conns) == 0 {
+		s.mu.Unlock()
+		return "", fmt.Errorf("no Socket Mode connection")
+	}
+	conn := s.conns[len(s.conns)-1]
+	envelopeID := s.nextIDLocked("env-")
+	s.mu.Unlock()
+
+	request := socketmode.Request{
+		Type:                   requestType,
+		EnvelopeID:             envelopeID,
+		Payload:                raw,
+		AcceptsResponsePayload: requestType != socketmode.RequestTypeEventsAPI,
+	}
+	if err := conn.writeJSON(request); err != nil {
+		return "", fmt.Errorf("failed to send Socket Mode request: %w", err)
+	}
+	return envelopeID, nil
+}
+
+// SendEvent wraps an Events API inner event (e.g. *slackevents.AppMentionEvent) in an
+// event_callback envelope and delivers it to the bot. The event must set its Type field.
+func (s *Server) SendEvent(event interface{}) (string, error) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event: %w", err)
+	}
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &typed); err != nil || typed.Type == "" {
+		return "", fmt.Errorf("event must have a type")
+	}
+
+	s.mu.Lock()
+	eventID := s.nextIDLocked("Ev")
+	s.mu.Unlock()
+
+	return s.Send(socketmode.RequestTypeEventsAPI, map[string]interface{}{
+		"token":      "fake-verification-token",
+		"team_id":    TeamID,
+		"api_app_id": AppID,
+		"type":       slackevents.CallbackEvent,
+		"event_id":   eventID,
+		"event_time": time.Now().Unix(),
+		"event":      json.RawMessage(raw),
+	})
+}
+
+// MentionBot posts a message mentioning the bot in a channel (in a thread when threadTS is set)
+// and delivers the app_mention event. Returns the message timestamp.
+func (s *Server) MentionBot(channel, user, text, threadTS string) (string, error) {
+	text = fmt.Sprintf("<@%s> %s", BotUserID, text)
+	ts := s.AddMessage(channel, slack.Message{Msg: slack.Msg{User: user, Text: text, ThreadTimestamp: threadTS}})
+	_, err := s.SendEvent(&slackevents.AppMentionEvent{
+		Type:            string(slackevents.AppMention),
+		User:            user,
+		Text:            text,
+		TimeStamp:       ts,
+		ThreadTimeStamp: threadTS,
+		Channel:         channel,
+		EventTimeStamp:  ts,
+	})
+	return ts, err
+}
+
+// SendDirectMessage posts a message to a DM channel and delivers the message event. Returns the message timestamp.
+func (s *Server) SendDirectMessage(channel, user, text, threadTS string) (string, error) {
+	ts := s.AddMessage(channel, slack.Message{Msg: slack.Msg{User: user, Text: text, ThreadTimestamp: threadTS}})
+	_, err := s.SendEvent(&slackevents.MessageEvent{
+		Type:            string(slackevents.Message),
+		User:            user,
+		Text:            text,
+		TimeStamp:       ts,
+		ThreadTimeStamp: threadTS,
+		Channel:         channel,
+		ChannelType:     "im",
+		EventTimeStamp:  ts,
+	})
+	return ts, err
+}
+
+// WaitForAck waits until the bot acknowledges the envelope and returns the ack payload, if any
+func (s *Server) WaitForAck(envelopeID string, timeout time.Duration) (json.RawMessage, error) {
+	var payload json.RawMessage
+	ok := s.waitFor(timeout, func() bool {
+		p, acked := s.acks[envelopeID]
+		payload = p
+		return acked
+	})
+	if !ok {
+		return nil, fmt.Errorf("timed out after %s waiting for ack of %s", timeout, envelopeID)
+	}
+	return payload, nil
+}
@@ -3,118 +3,488 @@ package slackbot
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
+
 	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
-	"io"
-	"os"
-	"os/user"
+	"github.com/tuannvm/slack-mcp-client/internal/slack/formatter"
+)
+
+// Identifiers used by the local frontend in place of real Slack IDs
+const (
+	stdioDefaultUserID    = "ULOCAL"
+	stdioDefaultChannelID = "CLOCAL"
+	stdioBotUserID        = "UBOT"
+	stdioBotID            = "BLOCAL"
+	stdioBotName          = "bot"
+
+	// defaultStdioReplyTimeout bounds how long the REPL waits for the bot to finish answering
+	defaultStdioReplyTimeout = 5 * time.Minute
 )
 
+const stdioHelp = `Commands:
+  /user <id> [name]   switch the current user (name is optional)
+  /channel <id>       switch channel and start a new thread (IDs starting with D are DMs)
+  /thread <ts>        continue an existing thread in the current channel
+  /threads            list threads in the current channel
+  /new                start a new thread with the next message
+  /help               show this help
+  /quit               exit`
+
+// StdioOptions configures the local terminal frontend
+type StdioOptions struct {
+	ThinkingMessage string        // Message the bot sends while working; shown as a status line, never stored
+	Script          bool          // Replay Input as a transcript and write a markdown transcript to Output
+	ReplyTimeout    time.Duration // How long to wait for each answer (default: 5 minutes)
+}
+
+// StdioClient is a terminal frontend that simulates Slack users, channels and threads
+// so prompts and tools can be exercised without a Slack workspace.
 type StdioClient struct {
 	events chan socketmode.Event
 	Output io.Writer
 	Input  io.Reader
 	logger *logging.Logger
+
+	thinkingMessage string
+	script          bool
+	color           bool
+	replyTimeout    time.Duration
+	now             func() time.Time
+	handled         chan struct{} // Signalled when the bot has finished answering a prompt
+
+	mu        sync.Mutex
+	userID    string
+	userNames map[string]string
+	channelID string
+	threadTS  string                     // Empty means the next message starts a new thread
+	threads   map[string][]slack.Message // Messages by historyKey(channel, thread)
+	lastTS    time.Time
 }
 
-func NewStdioClient(stdLogger *logging.Logger) *StdioClient {
+// NewStdioClient creates a terminal frontend reading from stdin and writing to stdout
+func NewStdioClient(stdLogger *logging.Logger, opts StdioOptions) *StdioClient {
 	logLevel := getLogLevel(stdLogger)
 	stdioLogger := logging.New("stdio-client", logLevel)
+
+	replyTimeout := opts.ReplyTimeout
+	if replyTimeout <= 0 {
+		replyTimeout = defaultStdioReplyTimeout
+	}
+
 	return &StdioClient{
-		events: make(chan socketmode.Event, 50),
-		Output: os.Stdout,
-		Input:  os.Stdin,
-		logger: stdioLogger,
+		events:          make(chan socketmode.Event, 50),
+		Output:          os.Stdout,
+		Input:           os.Stdin,
+		logger:          stdioLogger,
+		thinkingMessage: opts.ThinkingMessage,
+		script:          opts.Script,
+		replyTimeout:    replyTimeout,
+		now:             time.Now,
+		handled:         make(chan struct{}, 1),
+		userID:          stdioDefaultUserID,
+		userNames:       make(map[string]string),
+		channelID:       stdioDefaultChannelID,
+		threads:         make(map[string][]slack.Message),
+	}
+}
+
+// isTerminal reports whether the writer is a file attached to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
-func (client StdioClient) GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error) {
+
+func (client *StdioClient) GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error) {
 	return nil, nil
 }
-func (client StdioClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
+
+func (client *StdioClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
 	return "", "", nil
 }
-func (client StdioClient) Run() error {
+
+// Run reads lines from Input until EOF or /quit. Plain lines are sent to the bot as messages
+// in the current thread, lines starting with "/" are commands, and in script mode lines
+// starting with "#" are comments. Each message waits for the bot's answer before the next line is read.
+func (client *StdioClient) Run() error {
+	client.mu.Lock()
+	client.color = !client.script && isTerminal(client.Output) && os.Getenv("NO_COLOR") == ""
+	client.mu.Unlock()
+
+	if !client.script {
+		client.write(client.style(ansiDim, "Local REPL — type /help for commands") + "\n")
+	}
+
 	scanner := bufio.NewScanner(client.Input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	client.prompt()
 	for scanner.Scan() {
-		e := socketmode.Event{
-			Type: socketmode.EventTypeEventsAPI,
-			Data: slackevents.EventsAPIEvent{
-				Token:        "",
-				TeamID:       "",
-				Type:         slackevents.CallbackEvent,
-				APIAppID:     "",
-				EnterpriseID: "",
-				Data:         nil,
-				InnerEvent: slackevents.EventsAPIInnerEvent{
-					Type: "",
-					Data: &slackevents.AppMentionEvent{
-						User:      "xxx",
-						Text:      scanner.Text(),
-						TimeStamp: "",
-						Channel:   "xxx",
-					},
-				},
-			},
-			Request: &socketmode.Request{},
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case client.script && strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "/"):
+			quit, err := client.handleCommand(line)
+			if err != nil {
+				client.notice(err.Error())
+			}
+			if quit {
+				return nil
+			}
+		default:
+			client.sendUserMessage(line)
+			client.waitForReply()
 		}
-		client.events <- e
+		client.prompt()
 	}
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading stdin: %w", err)
+		return fmt.Errorf("error reading input: %w", err)
 	}
 	return nil
 }
-func (client StdioClient) Ack(req socketmode.Request, payload ...interface{}) {
 
+// handleCommand executes a REPL command and reports whether the REPL should exit
+func (client *StdioClient) handleCommand(line string) (bool, error) {
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	switch command {
+	case "/quit", "/exit":
+		return true, nil
+	case "/help":
+		client.writeLocked(stdioHelp + "\n")
+	case "/user":
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: /user <id> [name]")
+		}
+		client.userID = args[0]
+		if len(args) > 1 {
+			client.userNames[client.userID] = strings.Join(args[1:], " ")
+		}
+		client.noticeLocked(fmt.Sprintf("now speaking as %s", client.displayNameLocked(client.userID)))
+	case "/channel":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: /channel <id>")
+		}
+		client.channelID = strings.TrimPrefix(args[0], "#")
+		client.threadTS = ""
+		client.noticeLocked(fmt.Sprintf("switched to channel %s", client.channelID))
+	case "/thread":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: /thread <ts>")
+		}
+		if _, ok := client.threads[historyKey(client.channelID, args[0])]; !ok {
+			return false, fmt.Errorf("no thread %s in channel %s (see /threads)", args[0], client.channelID)
+		}
+		client.threadTS = args[0]
+		client.noticeLocked(fmt.Sprintf("continuing thread %s", client.threadTS))
+	case "/threads":
+		client.listThreadsLocked()
+	case "/new":
+		client.threadTS = ""
+		client.noticeLocked("the next message starts a new thread")
+	default:
+		return false, fmt.Errorf("unknown command %s (see /help)", command)
+	}
+	return false, nil
 }
-func (client StdioClient) GetEventChannel() chan socketmode.Event {
+
+// listThreadsLocked prints the threads of the current channel, oldest first
+func (client *StdioClient) listThreadsLocked() {
+	prefix := historyKey(client.channelID, "")
+	var roots []string
+	for key := range client.threads {
+		if strings.HasPrefix(key, prefix) {
+			roots = append(roots, strings.TrimPrefix(key, prefix))
+		}
+	}
+	if len(roots) == 0 {
+		client.noticeLocked(fmt.Sprintf("no threads in channel %s", client.channelID))
+		return
+	}
+	sort.Strings(roots)
+
+	var sb strings.Builder
+	for _, root := range roots {
+		messages := client.threads[historyKey(client.channelID, root)]
+		marker := " "
+		if root == client.threadTS {
+			marker = "*"
+		}
+		preview := messages[0].Text
+		if len([]rune(preview)) > 60 {
+			preview = string([]rune(preview)[:60]) + "…"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s  (%d messages)  %s\n", marker, root, len(messages), preview))
+	}
+	client.writeLocked(sb.String())
+}
+
+// sendUserMessage records a message from the current user and emits the matching Slack event
+func (client *StdioClient) sendUserMessage(text string) {
+	client.mu.Lock()
+	ts := client.nextTimestampLocked()
+	threadTS := client.threadTS
+	root := threadTS
+	if root == "" {
+		root = ts
+		client.threadTS = ts
+	}
+	userID, channelID := client.userID, client.channelID
+
+	key := historyKey(channelID, root)
+	client.threads[key] = append(client.threads[key], slack.Message{Msg: slack.Msg{
+		Type:            "message",
+		Channel:         channelID,
+		User:            userID,
+		Text:            text,
+		Timestamp:       ts,
+		ThreadTimestamp: root,
+	}})
+
+	if client.script {
+		client.writeLocked(fmt.Sprintf("**%s** (`%s`, thread `%s`):\n\n%s\n\n",
+			client.displayNameLocked(userID), channelID, root, quoteMarkdown(text)))
+	}
+	client.mu.Unlock()
+
+	// Drop a completion signal left over from a prompt that timed out
+	select {
+	case <-client.handled:
+	default:
+	}
+
+	var inner interface{}
+	innerType := string(slackevents.AppMention)
+	if strings.HasPrefix(channelID, "D") {
+		innerType = string(slackevents.Message)
+		inner = &slackevents.MessageEvent{
+			Type:            innerType,
+			User:            userID,
+			Text:            text,
+			TimeStamp:       ts,
+			ThreadTimeStamp: threadTS,
+			Channel:         channelID,
+			ChannelType:     "im",
+		}
+	} else {
+		inner = &slackevents.AppMentionEvent{
+			Type:            innerType,
+			User:            userID,
+			Text:            text,
+			TimeStamp:       ts,
+			ThreadTimeStamp: threadTS,
+			Channel:         channelID,
+		}
+	}
+
+	client.events <- socketmode.Event{
+		Type: socketmode.EventTypeEventsAPI,
+		Data: slackevents.EventsAPIEvent{
+			Type: slackevents.CallbackEvent,
+			InnerEvent: slackevents.EventsAPIInnerEvent{
+				Type: innerType,
+				Data: inner,
+			},
+		},
+		Request: &socketmode.Request{},
+	}
+}
+
+// waitForReply blocks until the bot reports the prompt as handled or the reply timeout expires
+func (client *StdioClient) waitForReply() {
+	select {
+	case <-client.handled:
+	case <-time.After(client.replyTimeout):
+		client.logger.WarnKV("Timed out waiting for bot reply", "timeout", client.replyTimeout)
+		client.notice(fmt.Sprintf("no reply within %s", client.replyTimeout))
+	}
+}
+
+// PromptHandled is called by the bot once it has finished answering a prompt
+func (client *StdioClient) PromptHandled(channelID, threadTS string) {
+	select {
+	case client.handled <- struct{}{}:
+	default:
+	}
+}
+
+// nextTimestampLocked returns a unique, increasing Slack-style timestamp
+func (client *StdioClient) nextTimestampLocked() string {
+	now := client.now().Truncate(time.Microsecond)
+	if !now.After(client.lastTS) {
+		now = client.lastTS.Add(time.Microsecond)
+	}
+	client.lastTS = now
+	return fmt.Sprintf("%d.%06d", now.Unix(), now.Nanosecond()/int(time.Microsecond))
+}
+
+func (client *StdioClient) Ack(req socketmode.Request, payload ...interface{}) {
+}
+
+func (client *StdioClient) GetEventChannel() chan socketmode.Event {
 	return client.events
 }
 
-func (client StdioClient) RemoveBotMention(msg string) string {
+func (client *StdioClient) RemoveBotMention(msg string) string {
 	return msg
 }
 
-func (client StdioClient) GetLogger() *logging.Logger {
+func (client *StdioClient) GetLogger() *logging.Logger {
 	return client.logger
 }
 
-func (client StdioClient) IsValidUser(userID string) bool {
-	return true
+func (client *StdioClient) IsValidUser(userID string) bool {
+	return userID != "" && !client.IsBotUser(userID)
 }
 
-func (client StdioClient) IsBotUser(userID string) bool {
-	return false
+func (client *StdioClient) IsBotUser(userID string) bool {
+	return userID == stdioBotUserID
 }
 
-func (client StdioClient) GetThreadReplies(channelID, threadTS string) ([]slack.Message, error) {
-	return []slack.Message{}, nil
+// GetThreadReplies returns a copy of all messages recorded in the thread, including bot replies
+func (client *StdioClient) GetThreadReplies(channelID, threadTS string) ([]slack.Message, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	messages := client.threads[historyKey(channelID, threadTS)]
+	replies := make([]slack.Message, len(messages))
+	copy(replies, messages)
+	return replies, nil
 }
 
-func (client StdioClient) GetUserInfo(userID string) (*UserProfile, error) {
-	currentUser, err := user.Current()
-	if err != nil {
-		return nil, fmt.Errorf("while getting current user: %w", err)
-	}
+func (client *StdioClient) GetUserInfo(userID string) (*UserProfile, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
 	return &UserProfile{
 		userId:   userID,
-		realName: currentUser.Name,
+		realName: client.displayNameLocked(userID),
 		email:    "",
 	}, nil
 }
 
-func (client StdioClient) SendMessage(channelID, threadTS, text string) {
-	messages := []string{
-		"----- SEND MESSAGE -----\n",
-		text, "\n",
-		"----- END MESSAGE -----\n",
+// displayNameLocked returns the name set with /user, the OS user's name for the default user, or the ID
+func (client *StdioClient) displayNameLocked(userID string) string {
+	if name, ok := client.userNames[userID]; ok {
+		return name
 	}
-	for _, msg := range messages {
-		_, err := client.Output.Write([]byte(msg))
-		if err != nil {
-			client.logger.ErrorKV("While writing message to output", "error", err)
+	if userID == stdioDefaultUserID {
+		if currentUser, err := user.Current(); err == nil {
+			if currentUser.Name != "" {
+				return currentUser.Name
+			}
+			return currentUser.Username
+		}
+	}
+	return userID
+}
+
+// SendMessage records a bot message in the thread and prints it
+func (client *StdioClient) SendMessage(channelID, threadTS, text string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.thinkingMessage != "" && text == client.thinkingMessage {
+		if !client.script {
+			client.writeLocked(client.style(ansiDim, text) + "\n")
 		}
+		return
+	}
+
+	key := historyKey(channelID, threadTS)
+	client.threads[key] = append(client.threads[key], slack.Message{Msg: slack.Msg{
+		Type:            "message",
+		Channel:         channelID,
+		User:            stdioBotUserID,
+		BotID:           stdioBotID,
+		Text:            text,
+		Timestamp:       client.nextTimestampLocked(),
+		ThreadTimestamp: threadTS,
+	}})
+
+	if client.script {
+		client.writeLocked(fmt.Sprintf("**%s**:\n\n%s\n\n", stdioBotName, strings.TrimSpace(text)))
+		return
+	}
+
+	body := text
+	if messageType := formatter.DetectMessageType(text); messageType == formatter.MarkdownText || messageType == formatter.PlainText {
+		body = terminalRenderer{color: client.color}.Render(text)
+	}
+	client.writeLocked(client.style(ansiBold+ansiGreen, stdioBotName+":") + "\n" + body + "\n\n")
+}
+
+// prompt prints the input prompt showing the current user, channel and thread
+func (client *StdioClient) prompt() {
+	if client.script {
+		return
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	thread := "new thread"
+	if client.threadTS != "" {
+		thread = "thread " + client.threadTS
+	}
+	client.writeLocked(client.style(ansiCyan, fmt.Sprintf("%s@%s (%s)> ", client.displayNameLocked(client.userID), client.channelID, thread)))
+}
+
+func (client *StdioClient) notice(msg string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.noticeLocked(msg)
+}
+
+// noticeLocked prints a status line; in script mode it becomes an italic line in the transcript
+func (client *StdioClient) noticeLocked(msg string) {
+	if client.script {
+		client.writeLocked(fmt.Sprintf("_%s_\n\n", msg))
+		return
+	}
+	client.writeLocked(client.style(ansiDim, "» "+msg) + "\n")
+}
+
+func (client *StdioClient) style(code, text string) string {
+	return terminalRenderer{color: client.color}.style(code, text)
+}
+
+func (client *StdioClient) write(text string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.writeLocked(text)
+}
+
+func (client *StdioClient) writeLocked(text string) {
+	if _, err := io.WriteString(client.Output, text); err != nil {
+		client.logger.ErrorKV("While writing message to output", "error", err)
+	}
+}
+
+// quoteMarkdown turns text into a markdown block quote
+func quoteMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
 	}
+	return strings.Join(lines, "\n")
 }
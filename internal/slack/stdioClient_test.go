@@ -0,0 +1,143 @@
+package slackbot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/tuannvm/slack-mcp-client/internal/common/logging"
+)
+
+// echoBot answers every event on the client's event channel the way the real bot would:
+// thinking message first, then a reply in the thread, then the completion signal.
+func echoBot(t *testing.T, client *StdioClient, seen chan<- slackevents.EventsAPIInnerEvent) {
+	for evt := range client.GetEventChannel() {
+		inner := evt.Data.(slackevents.EventsAPIEvent).InnerEvent
+		seen <- inner
+
+		var channel, threadTS, ts, text string
+		switch ev := inner.Data.(type) {
+		case *slackevents.AppMentionEvent:
+			channel, threadTS, ts, text = ev.Channel, ev.ThreadTimeStamp, ev.TimeStamp, ev.Text
+		case *slackevents.MessageEvent:
+			channel, threadTS, ts, text = ev.Channel, ev.ThreadTimeStamp, ev.TimeStamp, ev.Text
+		default:
+			t.Errorf("unexpected inner event %T", inner.Data)
+			continue
+		}
+		if threadTS == "" {
+			threadTS = ts
+		}
+
+		replies, _ := client.GetThreadReplies(channel, threadTS)
+		client.SendMessage(channel, threadTS, "Thinking...")
+		client.SendMessage(channel, threadTS, fmt.Sprintf("echo %s (%d in thread)", text, len(replies)))
+		client.PromptHandled(channel, threadTS)
+	}
+}
+
+func TestStdioClient_ScriptReplay(t *testing.T) {
+	script := strings.Join([]string{
+		"# comments and blank lines are ignored",
+		"",
+		"/user U1 Alice",
+		"first question",
+		"follow up",
+		"/new",
+		"second thread",
+		"/channel D42",
+		"direct message",
+	}, "\n")
+
+	client := NewStdioClient(logging.New("test", logging.LevelError), StdioOptions{
+		ThinkingMessage: "Thinking...",
+		Script:          true,
+		ReplyTimeout:    5 * time.Second,
+	})
+	var out bytes.Buffer
+	client.Input = strings.NewReader(script)
+	client.Output = &out
+	fixed := time.Unix(1700000000, 0)
+	client.now = func() time.Time { return fixed }
+
+	seen := make(chan slackevents.EventsAPIInnerEvent, 10)
+	go echoBot(t, client, seen)
+
+	if err := client.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	close(seen)
+
+	var events []slackevents.EventsAPIInnerEvent
+	for evt := range seen {
+		events = append(events, evt)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+
+	first := events[0].Data.(*slackevents.AppMentionEvent)
+	followUp := events[1].Data.(*slackevents.AppMentionEvent)
+	second := events[2].Data.(*slackevents.AppMentionEvent)
+	if first.User != "U1" {
+		t.Errorf("first message user = %q, want U1", first.User)
+	}
+	if first.ThreadTimeStamp != "" {
+		t.Errorf("first message should start a thread, got thread_ts %q", first.ThreadTimeStamp)
+	}
+	if followUp.ThreadTimeStamp != first.TimeStamp {
+		t.Errorf("follow up thread_ts = %q, want %q", followUp.ThreadTimeStamp, first.TimeStamp)
+	}
+	if second.ThreadTimeStamp != "" || second.TimeStamp <= followUp.TimeStamp {
+		t.Errorf("/new should start a new thread with a later timestamp, got ts %q thread_ts %q", second.TimeStamp, second.ThreadTimeStamp)
+	}
+	if dm, ok := events[3].Data.(*slackevents.MessageEvent); !ok || dm.Channel != "D42" {
+		t.Errorf("message in D channel should be a direct message event, got %+v", events[3].Data)
+	}
+
+	transcript := out.String()
+	for _, want := range []string{
+		"**Alice** (`CLOCAL`, thread `1700000000.000000`):\n\n> first question",
+		// The follow up sees the first question, the bot's reply and itself
+		"echo follow up (3 in thread)",
+		"echo second thread (1 in thread)",
+		"_switched to channel D42_",
+	} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript missing %q:\n%s", want, transcript)
+		}
+	}
+	if strings.Contains(transcript, "Thinking...") {
+		t.Errorf("transcript should not contain the thinking message:\n%s", transcript)
+	}
+}
+
+func TestTerminalRenderer_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"heading", "## Results", "Results"},
+		{"bold and italic", "This is **bold** and *italic*", "This is bold and italic"},
+		{"bullets", "- one\n  * two", "• one\n  • two"},
+		{"inline code keeps markers", "run `a **b**` now", "run a **b** now"},
+		{"markdown link", "see [docs](https://example.com)", "see docs (https://example.com)"},
+		{"slack link", "see <https://example.com|docs>", "see docs (https://example.com)"},
+		{"code block", "```go\nx := *y*\n```", "    x := *y*"},
+		{"quote", "> quoted", "│ quoted"},
+	}
+
+	renderer := terminalRenderer{color: false}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderer.Render(tt.markdown); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
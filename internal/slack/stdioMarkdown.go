@@ -0,0 +1,110 @@
+package slackbot
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ANSI escape codes used by the terminal renderer
+const (
+	ansiReset     = "\033[0m"
+	ansiBold      = "\033[1m"
+	ansiDim       = "\033[2m"
+	ansiItalic    = "\033[3m"
+	ansiUnderline = "\033[4m"
+	ansiGreen     = "\033[32m"
+	ansiCyan      = "\033[36m"
+)
+
+var (
+	mdHeadingRgx    = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	mdBulletRgx     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdRuleRgx       = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdInlineCodeRgx = regexp.MustCompile("`([^`]+)`")
+	mdLinkRgx       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	slackLinkRgx    = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+	mdBoldRgx       = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalicRgx     = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_]+)_\b`)
+)
+
+// terminalRenderer renders markdown for display in a terminal.
+// Without color, markup is removed and structure (bullets, quotes, code indentation) is kept.
+type terminalRenderer struct {
+	color bool
+}
+
+func (r terminalRenderer) style(code, text string) string {
+	if !r.color || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// Render converts markdown text into terminal output
+func (r terminalRenderer) Render(markdown string) string {
+	lines := strings.Split(strings.TrimRight(markdown, "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	inCode := false
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, "    "+r.style(ansiCyan, line))
+			continue
+		}
+
+		switch {
+		case mdHeadingRgx.MatchString(line):
+			heading := mdHeadingRgx.FindStringSubmatch(line)[1]
+			out = append(out, r.style(ansiBold+ansiUnderline, r.renderInline(heading)))
+		case mdRuleRgx.MatchString(line):
+			out = append(out, r.style(ansiDim, strings.Repeat("─", 40)))
+		case mdBulletRgx.MatchString(line):
+			match := mdBulletRgx.FindStringSubmatch(line)
+			out = append(out, match[1]+"• "+r.renderInline(match[2]))
+		case strings.HasPrefix(line, ">"):
+			quoted := strings.TrimPrefix(strings.TrimPrefix(line, ">"), " ")
+			out = append(out, r.style(ansiDim, "│ ")+r.renderInline(quoted))
+		default:
+			out = append(out, r.renderInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderInline applies inline styles, leaving the contents of code spans untouched
+func (r terminalRenderer) renderInline(line string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range mdInlineCodeRgx.FindAllStringSubmatchIndex(line, -1) {
+		sb.WriteString(r.renderSpans(line[last:loc[0]]))
+		sb.WriteString(r.style(ansiCyan, line[loc[2]:loc[3]]))
+		last = loc[1]
+	}
+	sb.WriteString(r.renderSpans(line[last:]))
+	return sb.String()
+}
+
+// renderSpans renders links, bold and italic text
+func (r terminalRenderer) renderSpans(text string) string {
+	text = mdLinkRgx.ReplaceAllStringFunc(text, func(m string) string {
+		match := mdLinkRgx.FindStringSubmatch(m)
+		return match[1] + " (" + r.style(ansiUnderline, match[2]) + ")"
+	})
+	text = slackLinkRgx.ReplaceAllStringFunc(text, func(m string) string {
+		match := slackLinkRgx.FindStringSubmatch(m)
+		return match[2] + " (" + r.style(ansiUnderline, match[1]) + ")"
+	})
+	text = mdBoldRgx.ReplaceAllStringFunc(text, func(m string) string {
+		match := mdBoldRgx.FindStringSubmatch(m)
+		return r.style(ansiBold, match[1]+match[2])
+	})
+	text = mdItalicRgx.ReplaceAllStringFunc(text, func(m string) string {
+		match := mdItalicRgx.FindStringSubmatch(m)
+		return r.style(ansiItalic, match[1]+match[2])
+	})
+	return text
+}
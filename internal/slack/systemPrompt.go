@@ -0,0 +1,48 @@
+package slackbot
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tuannvm/slack-mcp-client/internal/llm"
+	"github.com/tuannvm/slack-mcp-client/internal/prompt"
+)
+
+// renderSystemPrompt renders the custom prompt for a request, with the user's name, title and time zone,
+// the channel, the available tools and the model the request is routed to
+func (c *Client) renderSystemPrompt(channelID string, profile *UserProfile, route llm.RouteDecision) string {
+	if c.systemPrompt == nil {
+		return ""
+	}
+	var timeZone string
+	if frontend, ok := c.userFrontend.(timeZoneFrontend); ok && profile.userId != "" {
+		tz, err := frontend.GetUserTimeZone(profile.userId)
+		if err != nil {
+			c.logger.WarnKV("Failed to get user time zone", "user", profile.userId, "error", err)
+		}
+		timeZone = tz
+	}
+
+	data := prompt.NewData(time.Now(), timeZone)
+	data.UserName = profile.realName
+	data.UserTitle = profile.title
+	data.ChannelID = channelID
+	if frontend, ok := c.userFrontend.(directoryFrontend); ok && channelID != "" {
+		name, err := frontend.GetChannelName(channelID)
+		if err != nil {
+			c.logger.DebugKV("Failed to get channel name for the system prompt", "channel", channelID, "error", err)
+		}
+		data.ChannelName = name
+	}
+	for name := range c.discoveredTools {
+		data.Tools = append(data.Tools, name)
+	}
+	sort.Strings(data.Tools)
+	data.Provider = route.Provider
+	if data.Provider == "" {
+		data.Provider = c.cfg.LLM.Provider
+	}
+	data.Model = c.routeModel(route)
+	data.Extras = c.cfg.LLM.ChannelExtras(channelID)
+	return c.systemPrompt.Render(data)
+}
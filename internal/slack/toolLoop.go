@@ -0,0 +1,184 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/tuannvm/slack-mcp-client/internal/handlers"
+	"github.com/tuannvm/slack-mcp-client/internal/redact"
+)
+
+// runToolLoop runs the tool calls in the model's response and sends the results back, round after round,
+// until the model answers without calling tools or the configured iteration or token budget is used up.
+// It returns the answer for the user and the number of rounds run; on error the answer describes the error.
+func (c *Client) runToolLoop(ctx context.Context, request handlers.LLMRequest, response *llms.ContentChoice, extraArgs map[string]interface{},
+	channelID, threadTS string, reader recipient, redaction *redact.Session, mentions *mentionResolver) (string, int, error) {
+	traceID := traceIDFromContext(ctx)
+	limits := c.cfg.LLM.ToolLoop
+	usedTokens := getIntFromMap(response.GenerationInfo, "TotalTokens")
+
+	for step := 1; ; step++ {
+		toolCalls, err := c.llmMCPBridge.ExtractToolCalls(response)
+		if err != nil {
+			c.logger.ErrorKV("Failed to extract tool call", "error", err)
+			return c.message(reader, msgToolCallError, reader.errorData(err, traceID)), step - 1, err
+		}
+		if len(toolCalls) == 0 {
+			c.logger.DebugKV("No tool call detected, using LLM response as answer", "steps", step-1)
+			return mentions.Restore(redaction.Restore(response.Content)), step - 1, nil
+		}
+		if request.FinalAnswer {
+			c.logger.WarnKV("Model requested tools after the tool budget was used up", "steps", step-1, "tools", len(toolCalls))
+			return mentions.Restore(redaction.Restore(response.Content)), step - 1, nil
+		}
+
+		toolNames := make([]string, 0, len(toolCalls))
+		for _, toolCall := range toolCalls {
+			toolNames = append(toolNames, toolCall.Tool)
+		}
+		c.logger.InfoKV("Tool calls detected", "step", step, "tools", strings.Join(toolNames, ","))
+		stepCtx, stepSpan := c.tracingHandler.StartSpan(ctx, fmt.Sprintf("tool-step-%d", step), "span", strings.Join(toolNames, ", "), map[string]string{
+			"step":       fmt.Sprintf("%d", step),
+			"tool_count": fmt.Sprintf("%d", len(toolCalls)),
+			"tools":      strings.Join(toolNames, ","),
+		})
+
+		results := c.executeToolCalls(stepCtx, toolCalls, response.Content, extraArgs, channelID, threadTS, redaction)
+		request.Steps = append(request.Steps, handlers.ToolStep{Response: response.Content, Results: results})
+		request.FinalAnswer = step >= limits.MaxIterations || (limits.MaxTokens > 0 && usedTokens >= limits.MaxTokens)
+		if request.FinalAnswer {
+			c.logger.InfoKV("Tool budget used up, asking the model for a final answer",
+				"steps", step, "max_iterations", limits.MaxIterations, "tokens", usedTokens, "max_tokens", limits.MaxTokens)
+		}
+
+		response, err = c.callLLMWithToolResults(stepCtx, step, request, results)
+		if err != nil {
+			c.tracingHandler.RecordError(stepSpan, err, "ERROR")
+			stepSpan.End()
+			// Fallback: show the last tool result, or its error, with the LLM error
+			last := results[len(results)-1]
+			data := reader.errorData(err, traceID)
+			if last.IsError {
+				data.Tool = last.Call.Tool
+				return c.message(reader, msgToolError, data), step, err
+			}
+			data.ToolResult = last.Content
+			return c.message(reader, msgRepromptError, data), step, err
+		}
+		usedTokens += getIntFromMap(response.GenerationInfo, "TotalTokens")
+		c.tracingHandler.SetOutput(stepSpan, response.Content)
+		c.tracingHandler.RecordSuccess(stepSpan, "Tool step completed")
+		stepSpan.End()
+	}
+}
+
+// executeToolCalls runs the tool calls of one step in order and records them in the thread history.
+// Failed calls are returned as error results, so the model can recover or explain the failure.
+func (c *Client) executeToolCalls(ctx context.Context, toolCalls []*handlers.ToolCall, responseContent string, extraArgs map[string]interface{},
+	channelID, threadTS string, redaction *redact.Session) []handlers.ToolResult {
+	traceID := traceIDFromContext(ctx)
+	results := make([]handlers.ToolResult, 0, len(toolCalls))
+
+	for i, toolCall := range toolCalls {
+		// The LLM only saw placeholders; tools need the real values
+		toolCall.Args = redaction.RestoreArgs(toolCall.Args)
+		argsJSON, _ := json.Marshal(toolCall.Args)
+
+		// Original LLM response (tool call), recorded once per step
+		var content string
+		if i == 0 {
+			content = redaction.Restore(responseContent)
+		}
+		callID := toolCall.ID
+		if callID == "" {
+			callID = c.newHistoryID("call")
+		}
+		c.appendHistory(channelID, threadTS, Message{
+			Role:       "assistant",
+			Content:    content,
+			ToolName:   toolCall.Tool,
+			ToolArgs:   string(argsJSON),
+			ToolCallID: callID,
+			TraceID:    traceID,
+		})
+
+		// IMPORTANT: Use the returned context so child spans (embedding, retriever) are properly nested
+		toolExecCtx, toolExecSpan := c.tracingHandler.StartSpan(ctx, "tool-execution", "tool", string(argsJSON), map[string]string{
+			"bridge_available": "true",
+			"response_type":    "processing",
+			"tool_name":        toolCall.Tool,
+		})
+		toolCtx, cancel := context.WithTimeout(toolExecCtx, c.cfg.Timeouts.ToolProcessing())
+		startTime := time.Now()
+		output, err := c.llmMCPBridge.ExecuteToolCall(toolCtx, toolCall, extraArgs)
+		cancel()
+		c.tracingHandler.SetDuration(toolExecSpan, time.Since(startTime))
+
+		if err != nil {
+			result := handlers.ToolResult{Call: toolCall, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+			results = append(results, result)
+			c.tracingHandler.RecordError(toolExecSpan, err, "ERROR")
+			c.appendHistory(channelID, threadTS, Message{Role: "tool", Content: result.Content, ToolName: toolCall.Tool, ToolCallID: callID, TraceID: traceID})
+		} else {
+			results = append(results, handlers.ToolResult{Call: toolCall, Content: output})
+			c.appendHistory(channelID, threadTS, Message{Role: "tool", Content: output, ToolName: toolCall.Tool, ToolCallID: callID, TraceID: traceID})
+			c.tracingHandler.SetOutput(toolExecSpan, output)
+			c.tracingHandler.RecordSuccess(toolExecSpan, "Tool executed successfully")
+		}
+		toolExecSpan.End()
+	}
+	return results
+}
+
+// callLLMWithToolResults sends the request with its tool results back to the model, tracing the call
+func (c *Client) callLLMWithToolResults(ctx context.Context, step int, request handlers.LLMRequest, results []handlers.ToolResult) (*llms.ContentChoice, error) {
+	var toolNames []string
+	var estimatedTokens int
+	var input strings.Builder
+	for _, result := range results {
+		toolNames = append(toolNames, result.Call.Tool)
+		estimatedTokens += c.estimateToolTokenUsage(result.Call.Tool, request.Prompt, result.Content)
+		fmt.Fprintf(&input, "%s: %s\n", result.Call.Tool, result.Content)
+	}
+
+	llmCtx, llmSpan := c.tracingHandler.StartLLMSpan(ctx, "llm-tool-followup",
+		c.routeModel(request.Route),
+		input.String(),
+		map[string]interface{}{
+			"is_reprompt":           true,
+			"step":                  step,
+			"final_answer":          request.FinalAnswer,
+			"tool_name":             strings.Join(toolNames, ","),
+			"tool_estimated_tokens": estimatedTokens,
+		})
+	defer llmSpan.End()
+
+	startTime := time.Now()
+	response, report, err := c.llmMCPBridge.CallLLMWithRequest(llmCtx, request)
+	c.tracingHandler.SetDuration(llmSpan, time.Since(startTime))
+	c.recordUsage(request.UserID, request.ChannelID, report)
+	c.recordContextReport(llmCtx, report)
+	c.recordProviderAttempts(llmCtx, llmSpan, report)
+	if err != nil {
+		c.logger.ErrorKV("Error sending tool results to LLM", "step", step, "error", err)
+		c.tracingHandler.RecordError(llmSpan, err, "ERROR")
+		return nil, err
+	}
+
+	if totalTokens := getIntFromMap(response.GenerationInfo, "TotalTokens"); totalTokens > 0 {
+		c.tracingHandler.SetTokenUsage(llmSpan,
+			getIntFromMap(response.GenerationInfo, "PromptTokens"),
+			getIntFromMap(response.GenerationInfo, "CompletionTokens"),
+			getIntFromMap(response.GenerationInfo, "ReasoningTokens"),
+			totalTokens)
+	}
+	c.recordCacheUsage(llmSpan, response.GenerationInfo)
+	c.tracingHandler.SetOutput(llmSpan, response.Content)
+	c.tracingHandler.RecordSuccess(llmSpan, "LLM tool follow-up successful")
+	return response, nil
+}
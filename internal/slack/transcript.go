@@ -0,0 +1,225 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transcriptFormat is the file format of an exported thread transcript
+type transcriptFormat string
+
+const (
+	transcriptMarkdown transcriptFormat = "markdown"
+	transcriptJSON     transcriptFormat = "json"
+)
+
+// Kinds of transcript entries, derived from the history role and tool fields
+const (
+	transcriptUserPrompt  = "user_prompt"
+	transcriptToolCall    = "tool_call"
+	transcriptToolResult  = "tool_result"
+	transcriptModelAnswer = "model_answer"
+)
+
+// parseTranscriptCommand recognizes "export transcript [markdown|md|json]".
+// Any other text, including longer sentences starting with the same words, is a regular prompt.
+func parseTranscriptCommand(text string) (transcriptFormat, bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) < 2 || len(fields) > 3 || fields[0] != "export" || fields[1] != "transcript" {
+		return "", false
+	}
+	if len(fields) == 2 {
+		return transcriptMarkdown, true
+	}
+	switch fields[2] {
+	case "markdown", "md":
+		return transcriptMarkdown, true
+	case "json":
+		return transcriptJSON, true
+	default:
+		return "", false
+	}
+}
+
+// traceIDFromContext returns the trace ID of the span in ctx, or "" when tracing is disabled
+func traceIDFromContext(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// transcriptEntry is one history message in a JSON transcript.
+// Emails are left out so exports can be attached to bug reports without leaking contact details.
+type transcriptEntry struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`
+	Role           string          `json:"role"`
+	Timestamp      time.Time       `json:"timestamp"`
+	SlackTimestamp string          `json:"slack_ts,omitempty"`
+	UserID         string          `json:"user_id,omitempty"`
+	UserName       string          `json:"user_name,omitempty"`
+	ToolName       string          `json:"tool_name,omitempty"`
+	ToolArgs       json.RawMessage `json:"tool_args,omitempty"`
+	ToolCallID     string          `json:"tool_call_id,omitempty"`
+	TraceID        string          `json:"trace_id,omitempty"`
+	Content        string          `json:"content"`
+}
+
+// transcript is a thread's stored history prepared for export
+type transcript struct {
+	ChannelID  string            `json:"channel_id"`
+	ThreadTS   string            `json:"thread_ts"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Messages   []transcriptEntry `json:"messages"`
+}
+
+func newTranscript(channelID, threadTS string, history []Message, exportedAt time.Time) transcript {
+	t := transcript{ChannelID: channelID, ThreadTS: threadTS, ExportedAt: exportedAt.UTC(), Messages: []transcriptEntry{}}
+	for _, msg := range history {
+		entry := transcriptEntry{
+			ID:             msg.ID,
+			Role:           msg.Role,
+			Timestamp:      msg.Timestamp.UTC(),
+			SlackTimestamp: msg.SlackTimestamp,
+			UserID:         msg.UserID,
+			UserName:       msg.RealName,
+			ToolName:       msg.ToolName,
+			ToolCallID:     msg.ToolCallID,
+			TraceID:        msg.TraceID,
+			Content:        msg.Content,
+		}
+		if msg.ToolArgs != "" && json.Valid([]byte(msg.ToolArgs)) {
+			entry.ToolArgs = json.RawMessage(msg.ToolArgs)
+		}
+		switch {
+		case msg.Role == "tool":
+			entry.Kind = transcriptToolResult
+		case msg.Role == "assistant" && msg.ToolName != "":
+			entry.Kind = transcriptToolCall
+		case msg.Role == "assistant":
+			entry.Kind = transcriptModelAnswer
+		default:
+			entry.Kind = transcriptUserPrompt
+		}
+		t.Messages = append(t.Messages, entry)
+	}
+	return t
+}
+
+// render returns the file name and content of the transcript in the given format
+func (t transcript) render(format transcriptFormat) (string, string, error) {
+	base := fmt.Sprintf("transcript-%s-%s", t.ChannelID, strings.ReplaceAll(t.ThreadTS, ".", "_"))
+	if format == transcriptJSON {
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to marshal transcript: %w", err)
+		}
+		return base + ".json", string(data) + "\n", nil
+	}
+	return base + ".md", t.markdown(), nil
+}
+
+func (t transcript) markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Thread transcript\n\n")
+	sb.WriteString(fmt.Sprintf("- Channel: `%s`\n", t.ChannelID))
+	sb.WriteString(fmt.Sprintf("- Thread: `%s`\n", t.ThreadTS))
+	sb.WriteString(fmt.Sprintf("- Exported: %s\n", t.ExportedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("- Messages: %d\n", len(t.Messages)))
+
+	for _, entry := range t.Messages {
+		var heading string
+		switch entry.Kind {
+		case transcriptUserPrompt:
+			heading = "User"
+			if entry.UserName != "" {
+				heading += " — " + entry.UserName
+			}
+			if entry.UserID != "" {
+				heading += fmt.Sprintf(" (`%s`)", entry.UserID)
+			}
+		case transcriptToolCall:
+			heading = fmt.Sprintf("Tool call: `%s`", entry.ToolName)
+		case transcriptToolResult:
+			heading = "Tool result"
+			if entry.ToolName != "" {
+				heading += fmt.Sprintf(": `%s`", entry.ToolName)
+			}
+		default:
+			heading = "Assistant"
+		}
+		sb.WriteString(fmt.Sprintf("\n## %s · %s\n\n", entry.Timestamp.Format(time.RFC3339), heading))
+
+		var details []string
+		if entry.SlackTimestamp != "" {
+			details = append(details, fmt.Sprintf("Slack ts `%s`", entry.SlackTimestamp))
+		}
+		if entry.TraceID != "" {
+			details = append(details, fmt.Sprintf("trace `%s`", entry.TraceID))
+		}
+		if len(details) > 0 {
+			sb.WriteString("_" + strings.Join(details, ", ") + "_\n\n")
+		}
+
+		switch entry.Kind {
+		case transcriptToolCall:
+			args := "{}"
+			if len(entry.ToolArgs) > 0 {
+				if indented, err := json.MarshalIndent(entry.ToolArgs, "", "  "); err == nil {
+					args = string(indented)
+				}
+			}
+			sb.WriteString("Arguments:\n\n" + fenced(args, "json") + "\n")
+		case transcriptToolResult:
+			sb.WriteString(fenced(entry.Content, "") + "\n")
+		default:
+			sb.WriteString(strings.TrimSpace(entry.Content) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// fenced wraps content in a code fence longer than any backtick run it contains
+func fenced(content, lang string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimRight(content, "\n") + "\n" + fence + "\n"
+}
+
+// exportTranscript uploads the thread's stored history to the thread.
+// Frontends that cannot upload files get the transcript as a message instead.
+func (c *Client) exportTranscript(channelID, threadTS string, format transcriptFormat, reader recipient) {
+	history := append([]Message(nil), c.messageHistory[historyKey(channelID, threadTS)]...)
+	if len(history) == 0 {
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgTranscriptEmpty, messageData{}))
+		return
+	}
+
+	filename, content, err := newTranscript(channelID, threadTS, history, time.Now()).render(format)
+	if err != nil {
+		c.logger.ErrorKV("Failed to render transcript", "channel", channelID, "thread_ts", threadTS, "error", err)
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgTranscriptFailed, reader.errorData(err, "")))
+		return
+	}
+	c.logger.InfoKV("Exporting thread transcript", "channel", channelID, "thread_ts", threadTS, "format", format, "messages", len(history))
+
+	uploader, ok := c.userFrontend.(fileUploader)
+	if !ok {
+		c.userFrontend.SendMessage(channelID, threadTS, fenced(content, ""))
+		return
+	}
+	comment := fmt.Sprintf("Transcript of this thread (%d messages)", len(history))
+	if err := uploader.UploadFile(channelID, threadTS, filename, "Thread transcript", comment, content); err != nil {
+		c.logger.ErrorKV("Failed to upload transcript", "channel", channelID, "thread_ts", threadTS, "error", err)
+		c.userFrontend.SendMessage(channelID, threadTS, c.message(reader, msgTranscriptFailed, reader.errorData(err, "")))
+	}
+}
@@ -0,0 +1,107 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTranscriptCommand(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantFormat transcriptFormat
+		wantOK     bool
+	}{
+		{"export transcript", transcriptMarkdown, true},
+		{"  Export Transcript  ", transcriptMarkdown, true},
+		{"export transcript md", transcriptMarkdown, true},
+		{"export transcript markdown", transcriptMarkdown, true},
+		{"export transcript JSON", transcriptJSON, true},
+		{"export transcript pdf", "", false},
+		{"export transcript of yesterday's meeting", "", false},
+		{"export", "", false},
+		{"please export transcript", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			format, ok := parseTranscriptCommand(tt.text)
+			if format != tt.wantFormat || ok != tt.wantOK {
+				t.Errorf("parseTranscriptCommand(%q) = %q, %v, want %q, %v", tt.text, format, ok, tt.wantFormat, tt.wantOK)
+			}
+		})
+	}
+}
+
+func transcriptHistory() []Message {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []Message{
+		{Role: "user", Content: "why is db-1 down?", Timestamp: at, SlackTimestamp: "1700000000.000100", UserID: "U1", RealName: "Alice", Email: "alice@example.com", TraceID: "trace-1"},
+		{Role: "assistant", Content: `{"tool": "db_status", "args": {"host": "db-1"}}`, Timestamp: at.Add(time.Second), ToolName: "db_status", ToolArgs: `{"host":"db-1"}`, TraceID: "trace-1"},
+		{Role: "tool", Content: "disk full\n```\ndf output\n```", Timestamp: at.Add(2 * time.Second), ToolName: "db_status", TraceID: "trace-1"},
+		{Role: "assistant", Content: "The disk is full.", Timestamp: at.Add(3 * time.Second), TraceID: "trace-1"},
+	}
+}
+
+func TestTranscript_Markdown(t *testing.T) {
+	exportedAt := time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)
+	filename, content, err := newTranscript("C1", "1700000000.000100", transcriptHistory(), exportedAt).render(transcriptMarkdown)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if filename != "transcript-C1-1700000000_000100.md" {
+		t.Errorf("filename = %q", filename)
+	}
+	for _, want := range []string{
+		"- Messages: 4",
+		"## 2026-03-01T10:00:00Z · User — Alice (`U1`)",
+		"_Slack ts `1700000000.000100`, trace `trace-1`_",
+		"## 2026-03-01T10:00:01Z · Tool call: `db_status`",
+		"```json\n{\n  \"host\": \"db-1\"\n}\n```",
+		"## 2026-03-01T10:00:02Z · Tool result: `db_status`",
+		// The tool result contains a code fence, so it is wrapped in a longer one
+		"````\ndisk full\n```\ndf output\n```\n````",
+		"## 2026-03-01T10:00:03Z · Assistant\n\n_trace `trace-1`_\n\nThe disk is full.",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("markdown missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "alice@example.com") {
+		t.Errorf("markdown should not contain emails:\n%s", content)
+	}
+}
+
+func TestTranscript_JSON(t *testing.T) {
+	filename, content, err := newTranscript("C1", "1700000000.000100", transcriptHistory(), time.Now()).render(transcriptJSON)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if !strings.HasSuffix(filename, ".json") {
+		t.Errorf("filename = %q, want a .json file", filename)
+	}
+
+	var decoded transcript
+	if err := json.Unmarshal([]byte(content), &decoded); err != nil {
+		t.Fatalf("transcript is not valid JSON: %v", err)
+	}
+	wantKinds := []string{transcriptUserPrompt, transcriptToolCall, transcriptToolResult, transcriptModelAnswer}
+	if len(decoded.Messages) != len(wantKinds) {
+		t.Fatalf("got %d messages, want %d", len(decoded.Messages), len(wantKinds))
+	}
+	for i, kind := range wantKinds {
+		if decoded.Messages[i].Kind != kind {
+			t.Errorf("messages[%d].kind = %q, want %q", i, decoded.Messages[i].Kind, kind)
+		}
+		if decoded.Messages[i].TraceID != "trace-1" {
+			t.Errorf("messages[%d].trace_id = %q, want trace-1", i, decoded.Messages[i].TraceID)
+		}
+	}
+	var args map[string]string
+	if err := json.Unmarshal(decoded.Messages[1].ToolArgs, &args); err != nil || args["host"] != "db-1" {
+		t.Errorf("tool_args = %s, want the tool call arguments", decoded.Messages[1].ToolArgs)
+	}
+	if strings.Contains(content, "alice@example.com") {
+		t.Errorf("JSON should not contain emails:\n%s", content)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/socketmode"
@@ -27,6 +29,62 @@ type UserFrontend interface {
 	GetUserInfo(userID string) (*UserProfile, error)
 }
 
+// promptHandledNotifier is implemented by frontends that need to know when a prompt has been fully answered,
+// such as the local REPL which waits for the bot before reading the next line
+type promptHandledNotifier interface {
+	PromptHandled(channelID, threadTS string)
+}
+
+// interactiveFrontend is implemented by frontends that can open modals, used by the message shortcut
+type interactiveFrontend interface {
+	OpenModal(triggerID string, view slack.ModalViewRequest) error
+	GetMessagePermalink(channelID, messageTS string) (string, error)
+}
+
+// fileUploader is implemented by frontends that can share files in a thread, used by transcript exports
+type fileUploader interface {
+	UploadFile(channelID, threadTS, filename, title, comment, content string) error
+}
+
+// directoryFrontend is implemented by frontends that can look up channel and user group names,
+// used to turn Slack mention tokens into readable text for the LLM
+type directoryFrontend interface {
+	GetChannelName(channelID string) (string, error)
+	GetUserGroupHandle(groupID string) (string, error)
+}
+
+// membershipFrontend is implemented by frontends that can tell who may read a conversation,
+// used to only expand message links the asker is allowed to see
+type membershipFrontend interface {
+	IsPublicChannel(channelID string) (bool, error)
+	IsSharedChannel(channelID string) (bool, error)
+	IsConversationMember(channelID, userID string) (bool, error)
+	IsGuest(userID string) (bool, error)
+}
+
+// channelHistoryFrontend is implemented by frontends that can read a channel's recent messages,
+// used to give top-level mentions the context of the channel
+type channelHistoryFrontend interface {
+	GetChannelHistory(channelID string, oldest time.Time, limit int, keep func(slack.Message) bool) ([]slack.Message, error)
+}
+
+// blockMessageFrontend is implemented by frontends that can post and update Block Kit messages,
+// used for the agent trace that updates as the agent runs
+type blockMessageFrontend interface {
+	PostBlocks(channelID, threadTS, text string, blocks []slack.Block) (string, error)
+	UpdateBlocks(channelID, ts, text string, blocks []slack.Block) error
+}
+
+// localeFrontend is implemented by frontends that know the user's locale, used to localize messages
+type localeFrontend interface {
+	GetUserLocale(userID string) (string, error)
+}
+
+// timeZoneFrontend is implemented by frontends that know the user's time zone, used for dates in the system prompt
+type timeZoneFrontend interface {
+	GetUserTimeZone(userID string) (string, error)
+}
+
 func getLogLevel(stdLogger *logging.Logger) logging.LogLevel {
 	// Determine log level from environment variable
 	logLevel := logging.LevelInfo // Default to INFO
@@ -37,7 +95,9 @@ func getLogLevel(stdLogger *logging.Logger) logging.LogLevel {
 	return logLevel
 }
 
-func GetSlackClient(botToken, appToken string, stdLogger *logging.Logger, thinkingMessage string) (*SlackClient, error) {
+// GetSlackClient authenticates with Slack and creates a Socket Mode frontend.
+// Extra options are passed to the Web API client (e.g. slack.OptionAPIURL to target a fake server in tests).
+func GetSlackClient(botToken, appToken string, stdLogger *logging.Logger, thinkingMessage string, opts ...slack.Option) (*SlackClient, error) {
 	if botToken == "" {
 		return nil, fmt.Errorf("SLACK_BOT_TOKEN must be set")
 	}
@@ -54,12 +114,12 @@ func GetSlackClient(botToken, appToken string, stdLogger *logging.Logger, thinki
 	slackLogger := logging.New("slack-client", logLevel)
 
 	// Initialize the API client
-	api := slack.New(
-		botToken,
+	apiOptions := []slack.Option{
 		slack.OptionAppLevelToken(appToken),
 		// Still using standard logger for Slack API as it expects a standard logger
 		slack.OptionLog(slackLogger.StdLogger()),
-	)
+	}
+	api := slack.New(botToken, append(apiOptions, opts...)...)
 
 	// Authenticate with Slack
 	authTest, err := api.AuthTestContext(context.Background())
@@ -84,13 +144,25 @@ func GetSlackClient(botToken, appToken string, stdLogger *logging.Logger, thinki
 		logger:          slackLogger,
 		thinkingMessage: thinkingMessage,
 		userCache:       make(map[string]*UserProfile),
+		userInfoCache:   make(map[string]*slack.User),
+		channels:        make(map[string]cachedChannel),
 	}, nil
 }
 
 type UserProfile struct {
-	userId   string
-	realName string
-	email    string
+	userId      string
+	realName    string
+	displayName string // Name shown in Slack, may be empty
+	email       string
+	title       string // Job title from the Slack profile, may be empty
+}
+
+// mentionName returns the name the user is mentioned by in Slack
+func (p *UserProfile) mentionName() string {
+	if p.displayName != "" {
+		return p.displayName
+	}
+	return p.realName
 }
 
 type SlackClient struct {
@@ -99,7 +171,13 @@ type SlackClient struct {
 	botUserID       string
 	logger          *logging.Logger
 	thinkingMessage string
-	userCache       map[string]*UserProfile
+
+	// Guards the caches below, which prompts handled concurrently read and fill
+	directoryMu   sync.Mutex
+	userCache     map[string]*UserProfile
+	userInfoCache map[string]*slack.User   // users.info results, for locales and time zones
+	channels      map[string]cachedChannel // Conversation info by channel ID
+	groupHandles  map[string]string        // By user group ID, loaded on first use
 }
 
 func (slackClient *SlackClient) GetEventChannel() chan socketmode.Event {
@@ -140,7 +218,10 @@ func (slackClient *SlackClient) GetUserInfo(userID string) (*UserProfile, error)
 	if userID == "" {
 		return nil, fmt.Errorf("userID must be provided")
 	}
-	if profile, ok := slackClient.userCache[userID]; ok {
+	slackClient.directoryMu.Lock()
+	profile, ok := slackClient.userCache[userID]
+	slackClient.directoryMu.Unlock()
+	if ok {
 		return profile, nil
 	}
 	slackProfile, err := slackClient.GetUserProfile(&slack.GetUserProfileParameters{
@@ -149,15 +230,250 @@ func (slackClient *SlackClient) GetUserInfo(userID string) (*UserProfile, error)
 	if err != nil {
 		return nil, customErrors.WrapSlackError(err, "fetch_user_profile_failed", "Failed to fetch user profile")
 	}
-	profile := &UserProfile{
-		userId:   userID,
-		realName: slackProfile.RealName,
-		email:    slackProfile.Email,
+	profile = &UserProfile{
+		userId:      userID,
+		realName:    slackProfile.RealName,
+		displayName: slackProfile.DisplayName,
+		email:       slackProfile.Email,
+		title:       slackProfile.Title,
 	}
+	slackClient.directoryMu.Lock()
 	slackClient.userCache[userID] = profile
+	slackClient.directoryMu.Unlock()
 	return profile, nil
 }
 
+// GetUserLocale returns the user's Slack locale (e.g. "en-US")
+func (slackClient *SlackClient) GetUserLocale(userID string) (string, error) {
+	user, err := slackClient.userInfo(userID)
+	if err != nil {
+		return "", err
+	}
+	return user.Locale, nil
+}
+
+// GetUserTimeZone returns the user's time zone (e.g. "Europe/Berlin")
+func (slackClient *SlackClient) GetUserTimeZone(userID string) (string, error) {
+	user, err := slackClient.userInfo(userID)
+	if err != nil {
+		return "", err
+	}
+	return user.TZ, nil
+}
+
+// userInfo returns the cached users.info result of a user, fetching it on first use
+func (slackClient *SlackClient) userInfo(userID string) (*slack.User, error) {
+	slackClient.directoryMu.Lock()
+	user, ok := slackClient.userInfoCache[userID]
+	slackClient.directoryMu.Unlock()
+	if ok {
+		return user, nil
+	}
+	user, err := slackClient.Client.GetUserInfo(userID)
+	if err != nil {
+		return nil, customErrors.WrapSlackError(err, "fetch_user_info_failed", "Failed to fetch user info")
+	}
+	slackClient.directoryMu.Lock()
+	slackClient.userInfoCache[userID] = user
+	slackClient.directoryMu.Unlock()
+	return user, nil
+}
+
+// channelInfoTTL bounds how long conversation info is cached, so renamed channels and channels
+// made private or shared are picked up
+const channelInfoTTL = 10 * time.Minute
+
+// cachedChannel is conversation info with the time it was fetched
+type cachedChannel struct {
+	channel   *slack.Channel
+	fetchedAt time.Time
+}
+
+// channelInfo returns the cached conversation info of a channel, fetching it when missing or expired
+func (slackClient *SlackClient) channelInfo(channelID string) (*slack.Channel, error) {
+	slackClient.directoryMu.Lock()
+	cached, ok := slackClient.channels[channelID]
+	slackClient.directoryMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < channelInfoTTL {
+		return cached.channel, nil
+	}
+	channel, err := slackClient.GetConversationInfo(&slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		return nil, customErrors.WrapSlackError(err, "fetch_channel_info_failed", "Failed to fetch channel info")
+	}
+	slackClient.directoryMu.Lock()
+	slackClient.channels[channelID] = cachedChannel{channel: channel, fetchedAt: time.Now()}
+	slackClient.directoryMu.Unlock()
+	return channel, nil
+}
+
+// GetChannelName returns the name of a channel, without the leading "#"
+func (slackClient *SlackClient) GetChannelName(channelID string) (string, error) {
+	channel, err := slackClient.channelInfo(channelID)
+	if err != nil {
+		return "", err
+	}
+	return channel.Name, nil
+}
+
+// IsPublicChannel reports whether a conversation is a public channel that every workspace member can read
+func (slackClient *SlackClient) IsPublicChannel(channelID string) (bool, error) {
+	channel, err := slackClient.channelInfo(channelID)
+	if err != nil {
+		return false, err
+	}
+	return channel.IsChannel && !channel.IsPrivate && !channel.IsIM && !channel.IsMpIM, nil
+}
+
+// IsSharedChannel reports whether a conversation is shared with other workspaces through Slack Connect
+func (slackClient *SlackClient) IsSharedChannel(channelID string) (bool, error) {
+	channel, err := slackClient.channelInfo(channelID)
+	if err != nil {
+		return false, err
+	}
+	return channel.IsExtShared || channel.IsPendingExtShared, nil
+}
+
+// IsGuest reports whether a user is a guest or from another workspace, and so cannot read every public channel
+func (slackClient *SlackClient) IsGuest(userID string) (bool, error) {
+	user, err := slackClient.userInfo(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsRestricted || user.IsUltraRestricted || user.IsStranger, nil
+}
+
+// IsConversationMember reports whether a user is a member of a conversation.
+// Membership is not cached, so users who leave a channel lose access right away.
+func (slackClient *SlackClient) IsConversationMember(channelID, userID string) (bool, error) {
+	params := &slack.GetUsersInConversationParameters{ChannelID: channelID, Limit: 1000}
+	for {
+		members, cursor, err := slackClient.GetUsersInConversation(params)
+		if err != nil {
+			return false, customErrors.WrapSlackError(err, "fetch_conversation_members_failed", "Failed to fetch conversation members")
+		}
+		for _, member := range members {
+			if member == userID {
+				return true, nil
+			}
+		}
+		if cursor == "" {
+			return false, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// GetUserGroupHandle returns the handle of a user group, without the leading "@".
+// All groups are fetched with one call and cached; the list is refreshed when an unknown group is requested.
+func (slackClient *SlackClient) GetUserGroupHandle(groupID string) (string, error) {
+	slackClient.directoryMu.Lock()
+	handle, ok := slackClient.groupHandles[groupID]
+	slackClient.directoryMu.Unlock()
+	if ok {
+		return handle, nil
+	}
+	groups, err := slackClient.GetUserGroups()
+	if err != nil {
+		return "", customErrors.WrapSlackError(err, "fetch_user_groups_failed", "Failed to fetch user groups")
+	}
+	handles := make(map[string]string, len(groups)+1)
+	for _, group := range groups {
+		handles[group.ID] = group.Handle
+	}
+	if _, ok := handles[groupID]; !ok {
+		handles[groupID] = "" // Don't refetch the list for a deleted group
+	}
+	slackClient.directoryMu.Lock()
+	slackClient.groupHandles = handles
+	slackClient.directoryMu.Unlock()
+	return handles[groupID], nil
+}
+
+// GetChannelHistory returns up to limit top-level messages posted after oldest for which keep returns true,
+// newest first, following conversations.history pagination
+func (slackClient *SlackClient) GetChannelHistory(channelID string, oldest time.Time, limit int, keep func(slack.Message) bool) ([]slack.Message, error) {
+	params := &slack.GetConversationHistoryParameters{ChannelID: channelID, Limit: 200} // Maximum page size recommended by Slack
+	if !oldest.IsZero() {
+		params.Oldest = fmt.Sprintf("%d.000000", oldest.Unix())
+	}
+	var messages []slack.Message
+	for limit <= 0 || len(messages) < limit {
+		resp, err := slackClient.GetConversationHistory(params)
+		if err != nil {
+			return nil, customErrors.WrapSlackError(err, "fetch_channel_history_failed", "Failed to fetch channel history")
+		}
+		for _, msg := range resp.Messages {
+			if keep == nil || keep(msg) {
+				messages = append(messages, msg)
+			}
+		}
+		if !resp.HasMore || resp.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = resp.ResponseMetaData.NextCursor
+	}
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+// OpenModal opens a modal view in response to an interaction
+func (slackClient *SlackClient) OpenModal(triggerID string, view slack.ModalViewRequest) error {
+	if _, err := slackClient.OpenView(triggerID, view); err != nil {
+		return customErrors.WrapSlackError(err, "open_view_failed", "Failed to open modal")
+	}
+	return nil
+}
+
+// GetMessagePermalink returns the permanent link to a message
+func (slackClient *SlackClient) GetMessagePermalink(channelID, messageTS string) (string, error) {
+	permalink, err := slackClient.GetPermalink(&slack.PermalinkParameters{Channel: channelID, Ts: messageTS})
+	if err != nil {
+		return "", customErrors.WrapSlackError(err, "get_permalink_failed", "Failed to get message permalink")
+	}
+	return permalink, nil
+}
+
+// UploadFile shares a text file in a channel, in a thread if threadTS is provided
+func (slackClient *SlackClient) UploadFile(channelID, threadTS, filename, title, comment, content string) error {
+	_, err := slackClient.UploadFileV2(slack.UploadFileV2Parameters{
+		Channel:         channelID,
+		ThreadTimestamp: threadTS,
+		Filename:        filename,
+		Title:           title,
+		InitialComment:  comment,
+		Content:         content,
+		FileSize:        len(content),
+	})
+	if err != nil {
+		return customErrors.WrapSlackError(err, "upload_file_failed", "Failed to upload file")
+	}
+	return nil
+}
+
+// PostBlocks posts a Block Kit message with fallback text, in a thread if threadTS is provided, and returns its timestamp
+func (slackClient *SlackClient) PostBlocks(channelID, threadTS, text string, blocks []slack.Block) (string, error) {
+	options := []slack.MsgOption{slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...)}
+	if threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
+	}
+	_, ts, err := slackClient.PostMessage(channelID, options...)
+	if err != nil {
+		return "", customErrors.WrapSlackError(err, "post_message_failed", "Failed to post message")
+	}
+	return ts, nil
+}
+
+// UpdateBlocks replaces the text and blocks of a message
+func (slackClient *SlackClient) UpdateBlocks(channelID, ts, text string, blocks []slack.Block) error {
+	if _, _, _, err := slackClient.UpdateMessage(channelID, ts, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...)); err != nil {
+		return customErrors.WrapSlackError(err, "update_message_failed", "Failed to update message")
+	}
+	return nil
+}
+
 // SendMessage sends a message back to Slack, replying in a thread if threadTS is provided.
 func (slackClient *SlackClient) SendMessage(channelID, threadTS, text string) {
 	if text == "" {
@@ -43,16 +43,19 @@ BASE_CONFIG='{
     "provider": "openai",
     "providers": {
       "openai": {
+        "type": "openai",
         "model": "gpt-4o",
         "apiKey": "${OPENAI_API_KEY}",
         "temperature": 0.7
       },
       "anthropic": {
+        "type": "anthropic",
         "model": "claude-3-5-sonnet-20241022",
         "apiKey": "${ANTHROPIC_API_KEY}",
         "temperature": 0.7
       },
       "ollama": {
+        "type": "ollama",
         "model": "llama3",
         "baseUrl": "http://localhost:11434",
         "temperature": 0.7
@@ -225,8 +228,8 @@ elif jq -e '.servers' "$LEGACY_CONFIG" > /dev/null 2>&1; then
             echo "Migrating LLM provider: $provider_name"
             provider_config=$(jq ".llm_providers[\"$provider_name\"]" "$LEGACY_CONFIG")
             
-            # Create new provider config
-            new_provider='{}'
+            # Create new provider config; legacy providers are named after their type
+            new_provider=$(jq -n --arg type "$provider_name" '{type: $type}')
             
             for field in model api_key base_url temperature max_tokens; do
                 if jq -e ".$field" <<< "$provider_config" > /dev/null 2>&1; then